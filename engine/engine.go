@@ -0,0 +1,340 @@
+// Package engine fait dialoguer le jeu avec des moteurs de Puissance 4
+// par un protocole texte inspiré d'UCI, sur l'entrée et la sortie standard
+// du moteur : Serve sert un moteur alpha-bêta du paquet game par ce
+// protocole et External fait jouer un exécutable externe qui le parle.
+//
+// Chaque commande et chaque réponse tient sur une ligne ; les coups sont
+// écrits en notation compacte (game.FormatMoves) : la colonne numérotée à
+// partir de 1, précédée de 'p' pour un retrait (par exemple 4 ou p4).
+//
+// Commandes reçues par le moteur :
+//
+//	c4e                         présentation : le moteur répond "id name <nom>" puis "c4eok"
+//	isready                     le moteur répond "readyok"
+//	newgame                     une nouvelle partie commence : le moteur peut oublier ce qu'il a appris
+//	position [size L H N] [popout] [moves <coups>]
+//	                            position atteinte par les coups, sur un plateau de L colonnes et H
+//	                            rangées où il faut aligner N jetons (7 6 4 par défaut), aux règles
+//	                            PopOut si demandé ; les coups s'écrivent à la suite (4453p4)
+//	go [depth N] [movetime MS]  cherche le coup du joueur au trait, à la profondeur N ou pendant MS
+//	                            millisecondes ; sans l'un ni l'autre, jusqu'à "stop"
+//	stop                        arrête la recherche, qui répond aussitôt par "bestmove"
+//	quit                        arrête le moteur
+//
+// Réponses du moteur :
+//
+//	info depth D score (cp S | win N | loss N) nodes K pv <coup> <coup> ...
+//	                            itération terminée : valeur heuristique S pour le joueur au trait, ou
+//	                            victoire / défaite forcée en N demi-coups, variante principale
+//	info string <texte>         message libre, par exemple une erreur
+//	bestmove <coup>             fin de la recherche ; "bestmove none" si la partie est terminée
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// FormatMove écrit un coup en notation compacte.
+func FormatMove(m game.Move) string {
+	return game.FormatMoves([]game.Move{m})
+}
+
+// ParseMove lit un coup en notation compacte.
+func ParseMove(s string) (game.Move, error) {
+	moves, err := game.ParseMoveString(s)
+	if err != nil {
+		return game.Move{}, err
+	}
+	if len(moves) != 1 {
+		return game.Move{}, fmt.Errorf("expected a single move, got %q", s)
+	}
+	return moves[0], nil
+}
+
+// FormatInfo écrit la ligne "info" décrivant une itération de la
+// recherche.
+func FormatInfo(i game.SearchInfo) string {
+	score := "cp " + strconv.Itoa(i.Score)
+	switch {
+	case i.Mate > 0:
+		score = "win " + strconv.Itoa(i.Mate)
+	case i.Mate < 0:
+		score = "loss " + strconv.Itoa(-i.Mate)
+	}
+	pv := make([]string, len(i.PV))
+	for j, m := range i.PV {
+		pv[j] = FormatMove(m)
+	}
+	return fmt.Sprintf("info depth %d score %s nodes %d pv %s", i.Depth, score, i.Nodes, strings.Join(pv, " "))
+}
+
+// ParseInfo lit une ligne "info" autre que "info string". Les mots
+// inconnus sont ignorés, pour accepter les moteurs qui en envoient
+// davantage.
+func ParseInfo(line string) (game.SearchInfo, error) {
+	var info game.SearchInfo
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return info, fmt.Errorf("not an info line: %q", line)
+	}
+	number := func(i int) (int, error) {
+		if i >= len(fields) {
+			return 0, fmt.Errorf("missing value after %q", fields[i-1])
+		}
+		return strconv.Atoi(fields[i])
+	}
+	for i := 1; i < len(fields); i++ {
+		var err error
+		switch fields[i] {
+		case "depth":
+			i++
+			info.Depth, err = number(i)
+		case "nodes":
+			i++
+			var n int
+			n, err = number(i)
+			info.Nodes = uint64(n)
+		case "score":
+			i += 2
+			if i >= len(fields) {
+				return info, fmt.Errorf("incomplete score in %q", line)
+			}
+			var v int
+			v, err = number(i)
+			switch fields[i-1] {
+			case "cp":
+				info.Score = v
+			case "win":
+				info.Mate = v
+			case "loss":
+				info.Mate = -v
+			default:
+				return info, fmt.Errorf("unknown score %q", fields[i-1])
+			}
+		case "pv":
+			// la variante s'arrête au premier mot qui n'est pas un coup
+			for ; i+1 < len(fields); i++ {
+				m, err := ParseMove(fields[i+1])
+				if err != nil {
+					break
+				}
+				info.PV = append(info.PV, m)
+			}
+		}
+		if err != nil {
+			return info, fmt.Errorf("invalid info line %q: %v", line, err)
+		}
+	}
+	return info, nil
+}
+
+// position est une position décrite par la commande "position".
+type position struct {
+	width, height, connect int
+	variant                game.Variant
+	moves                  string
+}
+
+// parsePosition lit les arguments de la commande "position".
+func parsePosition(args []string) (position, error) {
+	p := position{width: game.DefaultWidth, height: game.DefaultHeight, connect: game.DefaultConnect}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "startpos":
+		case "size":
+			if i+3 >= len(args) {
+				return p, errors.New("size expects a width, a height and a connect length")
+			}
+			var dims [3]int
+			for j := range dims {
+				n, err := strconv.Atoi(args[i+1+j])
+				if err != nil {
+					return p, fmt.Errorf("invalid size %q", args[i+1+j])
+				}
+				dims[j] = n
+			}
+			p.width, p.height, p.connect = dims[0], dims[1], dims[2]
+			i += 3
+		case "popout":
+			p.variant = game.PopOut
+		case "moves":
+			p.moves = strings.Join(args[i+1:], "")
+			i = len(args)
+		default:
+			return p, fmt.Errorf("unknown position argument %q", args[i])
+		}
+	}
+	if err := game.CheckGeometry(p.width, p.height, p.connect); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// String écrit la position comme arguments de la commande "position".
+func (p position) String() string {
+	s := fmt.Sprintf("size %d %d %d", p.width, p.height, p.connect)
+	if p.variant == game.PopOut {
+		s += " popout"
+	}
+	if p.moves != "" {
+		s += " moves " + p.moves
+	}
+	return s
+}
+
+// game rejoue les coups de la position et renvoie la partie obtenue.
+func (p position) game() (*game.GameManager, error) {
+	gm := game.NewGameManager(false, 0, game.WithBoardSize(p.width, p.height, p.connect), game.WithVariant(p.variant))
+	if err := gm.PlayMoves(p.moves); err != nil {
+		return nil, err
+	}
+	return gm, nil
+}
+
+// toMove renvoie le joueur au trait après n coups.
+func toMove(n int) string {
+	if n%2 == 0 {
+		return game.PlayerOneColor
+	}
+	return game.PlayerTwoColor
+}
+
+// servedEngine est l'état d'un moteur servi par Serve.
+type servedEngine struct {
+	base *game.AlphaBeta
+	pos  position
+
+	mu     sync.Mutex // Protège w
+	w      *bufio.Writer
+	cancel context.CancelFunc // Arrête la recherche en cours (nil sans recherche)
+	done   chan struct{}      // Fermé à la fin de la recherche en cours
+}
+
+// Serve sert le moteur a par le protocole : les commandes sont lues sur r
+// et les réponses écrites sur w jusqu'à "quit" ou la fin de r. La
+// profondeur et le temps de réflexion de a sont remplacés par ceux de
+// chaque commande "go" ; ses autres réglages (table, solveur, workers...)
+// sont conservés.
+func Serve(r io.Reader, w io.Writer, name string, a *game.AlphaBeta) error {
+	e := &servedEngine{base: a, w: bufio.NewWriter(w)}
+	e.pos, _ = parsePosition(nil)
+	defer e.wait()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "c4e":
+			e.println("id name " + name)
+			e.println("c4eok")
+		case "isready":
+			e.println("readyok")
+		case "newgame":
+			e.wait()
+			if a.Table != nil {
+				a.Table.Clear()
+			}
+		case "position":
+			e.wait()
+			pos, err := parsePosition(fields[1:])
+			if err == nil {
+				_, err = pos.game()
+			}
+			if err != nil {
+				e.println("info string " + err.Error())
+				continue
+			}
+			e.pos = pos
+		case "go":
+			e.search(fields[1:])
+		case "stop":
+			e.stop()
+		case "quit":
+			return nil
+		default:
+			e.println("info string unknown command " + fields[0])
+		}
+	}
+	return sc.Err()
+}
+
+// println écrit une réponse.
+func (e *servedEngine) println(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.WriteString(line + "\n")
+	e.w.Flush()
+}
+
+// search démarre la recherche demandée par "go".
+func (e *servedEngine) search(args []string) {
+	if e.done != nil {
+		select {
+		case <-e.done:
+		default:
+			e.println("info string already searching")
+			return
+		}
+	}
+	a := *e.base
+	a.Depth, a.ThinkTime = 0, 0
+	for i := 0; i+1 < len(args); i += 2 {
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n <= 0 {
+			e.println("info string invalid value " + args[i+1])
+			return
+		}
+		switch args[i] {
+		case "depth":
+			a.Depth = n
+		case "movetime":
+			a.ThinkTime = time.Duration(n) * time.Millisecond
+		default:
+			e.println("info string unknown go argument " + args[i])
+			return
+		}
+	}
+	a.Info = func(i game.SearchInfo) { e.println(FormatInfo(i)) }
+
+	gm, _ := e.pos.game()
+	b, player := gm.Board(), toMove(len(gm.History()))
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel, e.done = cancel, make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		defer cancel()
+		m, err := a.BestMove(ctx, b, player)
+		if err != nil {
+			e.println("bestmove none")
+			return
+		}
+		e.println("bestmove " + FormatMove(m))
+	}(e.done)
+}
+
+// stop arrête la recherche en cours, qui répond par "bestmove".
+func (e *servedEngine) stop() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// wait arrête la recherche en cours et attend sa réponse.
+func (e *servedEngine) wait() {
+	e.stop()
+	if e.done != nil {
+		<-e.done
+	}
+}
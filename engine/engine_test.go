@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// TestMain fait du binaire de test un moteur servi sur l'entrée et la
+// sortie standard quand il est lancé par TestExternal, ou un moteur lent à
+// s'arrêter pour TestExternalStopTimeout.
+func TestMain(m *testing.M) {
+	switch os.Getenv("C4_ENGINE_HELPER") {
+	case "1":
+		if err := Serve(os.Stdin, os.Stdout, "helper", game.NewAlphaBeta(4)); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "slow":
+		slowEngine()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// slowEngine se présente puis ne répond à stop qu'au bout d'une seconde.
+func slowEngine() {
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		switch sc.Text() {
+		case "c4e":
+			fmt.Println("id name slow")
+			fmt.Println("c4eok")
+		case "stop":
+			time.Sleep(time.Second)
+			fmt.Println("bestmove 1")
+		}
+	}
+}
+
+// session dialogue avec un moteur servi par Serve dans une goroutine.
+type session struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+}
+
+func serve(t *testing.T) *session {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(inR, outW, "test", game.NewAlphaBeta(4))
+		outW.Close()
+	}()
+	s := &session{t: t, in: inW, lines: make(chan string, 256)}
+	go func() {
+		defer close(s.lines)
+		sc := bufio.NewScanner(outR)
+		for sc.Scan() {
+			s.lines <- sc.Text()
+		}
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return s
+}
+
+func (s *session) send(line string) {
+	s.t.Helper()
+	if _, err := io.WriteString(s.in, line+"\n"); err != nil {
+		s.t.Fatalf("send %q: %v", line, err)
+	}
+}
+
+// until lit les réponses jusqu'à celle qui commence par prefix et renvoie
+// toutes les lignes lues.
+func (s *session) until(prefix string) []string {
+	s.t.Helper()
+	var lines []string
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.t.Fatalf("engine output closed before %q, got %q", prefix, lines)
+			}
+			lines = append(lines, line)
+			if strings.HasPrefix(line, prefix) {
+				return lines
+			}
+		case <-timeout:
+			s.t.Fatalf("no %q from the engine, got %q", prefix, lines)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := serve(t)
+	s.send("c4e")
+	if got := s.until("c4eok"); got[0] != "id name test" {
+		t.Fatalf("unexpected handshake %q", got)
+	}
+	s.send("isready")
+	s.until("readyok")
+
+	// X gagne en jouant dans la colonne 4
+	s.send("position moves 112233")
+	s.send("go depth 3")
+	lines := s.until("bestmove")
+	if last := lines[len(lines)-1]; last != "bestmove 4" {
+		t.Fatalf("expected the winning move, got %q", last)
+	}
+	info, err := ParseInfo(lines[len(lines)-2])
+	if err != nil || info.Mate != 1 || len(info.PV) != 1 || info.PV[0].Column != 3 {
+		t.Fatalf("unexpected info %q (%v)", lines[len(lines)-2], err)
+	}
+
+	// une recherche sans limite s'arrête sur "stop"
+	s.send("newgame")
+	s.send("position size 5 4 3 popout moves 3p3")
+	s.send("go")
+	s.send("stop")
+	if last := s.until("bestmove"); last[len(last)-1] == "bestmove none" {
+		t.Fatalf("expected a move after stop, got %q", last)
+	}
+
+	s.send("position moves 8")
+	if got := s.until("info string"); len(got) != 1 {
+		t.Fatalf("expected an error for an invalid position, got %q", got)
+	}
+	s.send("position moves 1212121")
+	s.send("go depth 2")
+	s.until("bestmove none")
+	s.send("quit")
+}
+
+func TestInfoRoundTrip(t *testing.T) {
+	for _, info := range []game.SearchInfo{
+		{Depth: 7, Score: -12, Nodes: 4242, PV: []game.Move{{Column: 3}, {Column: 2, Kind: game.MovePop}}},
+		{Depth: 3, Mate: 3, Nodes: 10, PV: []game.Move{{Column: 0}}},
+		{Depth: 4, Mate: -2, Nodes: 10, PV: []game.Move{{Column: 6}}},
+	} {
+		line := FormatInfo(info)
+		got, err := ParseInfo(line + " hashfull 12")
+		if err != nil {
+			t.Fatalf("ParseInfo(%q): %v", line, err)
+		}
+		if FormatInfo(got) != line {
+			t.Fatalf("round trip of %q gave %q", line, FormatInfo(got))
+		}
+	}
+}
+
+func TestExternal(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("no test executable: %v", err)
+	}
+	t.Setenv("C4_ENGINE_HELPER", "1")
+	e, err := Start(exe)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer e.Close()
+	if e.Name() != "helper" {
+		t.Fatalf("unexpected engine name %q", e.Name())
+	}
+	e.Depth = 4
+
+	// le moteur externe joue les coups de l'IA d'une partie
+	var infos int
+	e.Info = func(game.SearchInfo) { infos++ }
+	gm := game.Settings{AI: true, Opponent: e}.NewGameManager(game.WithVariant(game.PopOut))
+	for _, c := range []int{0, 0, 1} {
+		gm.MakePlayerTurn(c)
+		if _, err := gm.MakeOpponentTurnContext(context.Background(), -1); err != nil {
+			t.Fatalf("opponent move: %v", err)
+		}
+	}
+	if len(gm.History()) != 6 || infos == 0 {
+		t.Fatalf("unexpected game %q with %d info lines", gm.MoveString(), infos)
+	}
+
+	// une recherche interrompue renvoie le meilleur coup provisoire
+	e.Depth, e.MoveTime = 0, time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	gm.MakePlayerTurn(3)
+	if _, err := gm.MakeOpponentTurnContext(ctx, -1); err != nil {
+		t.Fatalf("interrupted opponent move: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("the search was not stopped")
+	}
+
+	// un plateau entamé sans ses coups est refusé
+	if _, err := e.BestMove(context.Background(), gm.Board(), game.PlayerOneColor); err == nil {
+		t.Fatalf("expected BestMove to refuse a position without its moves")
+	}
+}
+
+func TestExternalStopTimeout(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("no test executable: %v", err)
+	}
+	defer func(d time.Duration) { handshakeTimeout = d }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+	t.Setenv("C4_ENGINE_HELPER", "slow")
+	e, err := Start(exe)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer e.Close()
+
+	b := game.NewBoard(game.DefaultWidth, game.DefaultHeight, game.DefaultConnect)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := e.BestMove(ctx, b, game.PlayerOneColor); err == nil {
+		t.Fatalf("expected an error when the engine does not answer stop")
+	}
+	// le coup en retard ne doit pas servir de réponse à la recherche suivante
+	time.Sleep(2 * handshakeTimeout)
+	if m, err := e.BestMove(context.Background(), b, game.PlayerOneColor); err == nil {
+		t.Fatalf("expected the engine to be unusable, got move %+v", m)
+	}
+}
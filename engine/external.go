@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// DefaultMoveTime est le temps de réflexion demandé à un moteur externe
+// sans profondeur ni temps fixés.
+const DefaultMoveTime = time.Second
+
+// handshakeTimeout est le temps laissé à un moteur externe pour se
+// présenter, et à la fin d'une recherche interrompue pour arriver. C'est
+// une variable pour que les tests puissent le raccourcir.
+var handshakeTimeout = 10 * time.Second
+
+// ErrEngineExited est renvoyée quand le moteur externe s'est arrêté.
+var ErrEngineExited = errors.New("engine exited")
+
+// External est un moteur externe : un exécutable parlant le protocole du
+// paquet sur son entrée et sa sortie standard. Il implémente
+// game.HistoryEngine et peut donc jouer contre un GameManager (voir
+// game.WithEngine). Ses recherches ne doivent pas être concurrentes.
+type External struct {
+	Depth    int           // Profondeur demandée au moteur (0 : limitée par MoveTime)
+	MoveTime time.Duration // Temps de réflexion par coup (0 avec Depth à 0 : DefaultMoveTime)
+	Info     func(game.SearchInfo)
+
+	name  string
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	lines chan string // Lignes écrites par le moteur, fermé à la fin de sa sortie
+	mu    sync.Mutex  // Protège in
+	err   error       // Raison pour laquelle le moteur est devenu inutilisable
+}
+
+// Start lance l'exécutable path avec les arguments args et attend qu'il se
+// présente.
+func Start(path string, args ...string) (*External, error) {
+	cmd := exec.Command(path, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	e := &External{cmd: cmd, in: in, lines: make(chan string, 64)}
+	go func() {
+		defer close(e.lines)
+		sc := bufio.NewScanner(out)
+		for sc.Scan() {
+			e.lines <- sc.Text()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := e.handshake(ctx); err != nil {
+		e.Close()
+		return nil, fmt.Errorf("engine %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// handshake présente le client au moteur et lit son nom.
+func (e *External) handshake(ctx context.Context) error {
+	if err := e.send("c4e"); err != nil {
+		return err
+	}
+	for {
+		line, err := e.readLine(ctx)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "c4eok":
+			return nil
+		case strings.HasPrefix(line, "id name "):
+			e.name = strings.TrimPrefix(line, "id name ")
+		}
+	}
+}
+
+// Name renvoie le nom sous lequel le moteur s'est présenté.
+func (e *External) Name() string {
+	return e.name
+}
+
+// send écrit une commande au moteur.
+func (e *External) send(line string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := io.WriteString(e.in, line+"\n")
+	return err
+}
+
+// readLine attend la prochaine ligne écrite par le moteur.
+func (e *External) readLine(ctx context.Context) (string, error) {
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return "", ErrEngineExited
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// BestMove cherche le coup de player sur le plateau vide b : le protocole
+// décrit les positions par leurs coups, que seul BestMoveAfter connaît
+// pour un plateau déjà entamé.
+func (e *External) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	return e.BestMoveAfter(ctx, b, nil, player)
+}
+
+// BestMoveAfter envoie au moteur la position atteinte par les coups
+// history et renvoie le coup qu'il choisit. Si ctx se termine avant, la
+// recherche est arrêtée et son meilleur coup provisoire est renvoyé. Un
+// moteur qui ne répond pas à l'arrêt est tué : son coup arriverait sinon
+// en réponse à la recherche suivante, et il renvoie ensuite toujours une
+// erreur.
+func (e *External) BestMoveAfter(ctx context.Context, b *game.Board, history []game.Move, player string) (game.Move, error) {
+	if e.err != nil {
+		return game.Move{Column: -1}, e.err
+	}
+	pos := position{width: b.Width(), height: b.Height(), connect: b.ConnectN(), variant: b.Variant(), moves: game.FormatMoves(history)}
+	if gm, err := pos.game(); err != nil || gm.Board().String() != b.String() {
+		return game.Move{Column: -1}, errors.New("the moves given to the engine do not lead to the position")
+	}
+	if toMove(len(history)) != player {
+		return game.Move{Column: -1}, fmt.Errorf("%s is not to move after %d moves", player, len(history))
+	}
+	goCmd := "go"
+	if e.Depth > 0 {
+		goCmd += fmt.Sprintf(" depth %d", e.Depth)
+	}
+	if moveTime := e.MoveTime; moveTime > 0 || e.Depth <= 0 {
+		if moveTime <= 0 {
+			moveTime = DefaultMoveTime
+		}
+		goCmd += fmt.Sprintf(" movetime %d", moveTime.Milliseconds())
+	}
+	if err := e.send("position " + pos.String()); err != nil {
+		return game.Move{Column: -1}, err
+	}
+	if err := e.send(goCmd); err != nil {
+		return game.Move{Column: -1}, err
+	}
+
+	stopped := false
+	stopCtx := ctx
+	for {
+		line, err := e.readLine(stopCtx)
+		if err != nil {
+			if stopped {
+				e.err = fmt.Errorf("engine did not answer stop: %w", err)
+				e.cmd.Process.Kill()
+				return game.Move{Column: -1}, e.err
+			}
+			if !errors.Is(err, ctx.Err()) {
+				return game.Move{Column: -1}, err
+			}
+			// arrête la recherche et attend son dernier coup
+			stopped = true
+			if err := e.send("stop"); err != nil {
+				return game.Move{Column: -1}, err
+			}
+			var cancel context.CancelFunc
+			stopCtx, cancel = context.WithTimeout(context.Background(), handshakeTimeout)
+			defer cancel()
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "info":
+			if e.Info == nil || len(fields) > 1 && fields[1] == "string" {
+				continue
+			}
+			if info, err := ParseInfo(line); err == nil {
+				e.Info(info)
+			}
+		case "bestmove":
+			if len(fields) < 2 || fields[1] == "none" {
+				return game.Move{Column: -1}, game.ErrGameOver
+			}
+			m, err := ParseMove(fields[1])
+			if err != nil {
+				return game.Move{Column: -1}, fmt.Errorf("engine move: %w", err)
+			}
+			m.Player = player
+			return m, nil
+		}
+	}
+}
+
+// Close arrête le moteur, en le tuant s'il ne s'arrête pas de lui-même.
+func (e *External) Close() error {
+	e.send("quit")
+	e.in.Close()
+	exited := make(chan error, 1)
+	go func() {
+		// vide la sortie pour que le moteur ne reste pas bloqué en écriture
+		for range e.lines {
+		}
+		exited <- e.cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(handshakeTimeout):
+		e.cmd.Process.Kill()
+		return <-exited
+	}
+}
@@ -1,19 +1,73 @@
 package game
 
 import (
+	"context"
 	"math/rand"
-	"time"
+	"sync"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 const (
 	big   = 100000
 	small = -big
+	// winThreshold sépare les scores de victoire/défaite (big - profondeur)
+	// des autres évaluations.
+	winThreshold = big - 1000
 )
 
+// zobristMaximizer est combinée à la clé Zobrist du plateau lorsque c'est
+// au joueur maximisant de jouer : une même position n'a pas la même valeur
+// selon le joueur au trait.
+const zobristMaximizer uint64 = 0x7f4a7c159e3779b9
+
+// abortCheckInterval est le nombre de nœuds visités entre deux
+// vérifications de l'échéance de la recherche.
+const abortCheckInterval = 1024
+
+// searchConfig regroupe les paramètres d'une recherche alpha-bêta.
+// tt peut être nil, auquel cas aucune position n'est mémorisée ; eval peut
+// être nil, auquel cas defaultEvaluator est utilisé à l'horizon. Avec
+// workers > 1, la recherche est répartie entre autant de goroutines
+// (Lazy SMP) ; rng, s'il n'est pas nil, fournit l'ordre aléatoire des
+// colonnes et la graine de chaque goroutine. info, si elle n'est pas nil,
+// est appelée après chaque itération de la goroutine principale.
+type searchConfig struct {
+	tt      *TranspositionTable
+	eval    Evaluator
+	workers int
+	rng     *rand.Rand
+	info    func(SearchInfo)
+}
+
+// searcher regroupe l'état partagé par les nœuds d'une recherche
+// alpha-bêta. ctx peut être nil, auquel cas la recherche n'est jamais
+// interrompue ; rng peut être nil, auquel cas le générateur global de
+// math/rand est utilisé et les coups ne sont pas reproductibles.
+type searcher struct {
+	searchConfig
+	ctx     context.Context
+	nodes   uint64
+	aborted bool
+	ttStats TTStats // Accès à tt pas encore reportés dans ses compteurs (voir flushStats)
+}
+
+// flushStats reporte dans les compteurs de la table les accès de la
+// recherche.
+func (s *searcher) flushStats() {
+	if s.tt != nil {
+		s.tt.addStats(&s.ttStats)
+	}
+}
+
+// searchResult est le résultat d'une recherche par approfondissement
+// itératif : le coup et le score (du point de vue du joueur ayant le trait)
+// de la dernière itération terminée.
+type searchResult struct {
+	Move  int
+	Score int
+	Depth int
+	Nodes uint64
+}
+
 //getAiMove returns the best move for the given board position based on the strength of the AI
 func getAiMove(b *Board, strength int) int {
 	copy := b.copyOfBoard()
@@ -21,27 +75,202 @@ func getAiMove(b *Board, strength int) int {
 	return move
 }
 
+//searchIterative runs alphabeta for player with increasing depths until maxDepth (or the end of
+//the game when maxDepth <= 0) is reached, a forced result is found or ctx is done. It returns the
+//best move of the deepest completed iteration; the first iteration always completes so a move is
+//always found. When cfg.workers > 1 the search is run by that many goroutines sharing cfg.tt.
+func searchIterative(ctx context.Context, b *Board, maxDepth int, player string, cfg searchConfig) searchResult {
+	if cfg.workers > 1 {
+		return searchParallel(ctx, b, maxDepth, player, cfg)
+	}
+	return searchWorker(ctx, b, maxDepth, player, cfg)
+}
+
+//searchWorker is the sequential iterative deepening loop run by searchIterative
+func searchWorker(ctx context.Context, b *Board, maxDepth int, player string, cfg searchConfig) searchResult {
+	board := b.copyOfBoard()
+	remaining := board.geo.cells() - board.movesMade
+	if board.variant == PopOut {
+		// les retraits permettent des parties sans fin : la profondeur est
+		// limitée arbitrairement au nombre de cases
+		remaining = board.geo.cells()
+	}
+	if maxDepth <= 0 || maxDepth > remaining {
+		maxDepth = remaining
+	}
+	result := searchResult{Move: -1}
+	for depth := 1; depth <= maxDepth; depth++ {
+		s := &searcher{searchConfig: cfg, ctx: ctx}
+		if depth == 1 {
+			s.ctx = nil
+		} else if ctx.Err() != nil {
+			break
+		}
+		score, move := s.alphabeta(board, player == PlayerTwoColor, 0, small, big, depth)
+		s.flushStats()
+		result.Nodes += s.nodes
+		if s.aborted {
+			break
+		}
+		if player != PlayerTwoColor {
+			score = -score
+		}
+		result.Move, result.Score, result.Depth = move, score, depth
+		if cfg.info != nil {
+			cfg.info(result.info(board, player, cfg.tt))
+		}
+		if score > winThreshold || score < -winThreshold {
+			break
+		}
+	}
+	return result
+}
+
+//searchParallel is a Lazy SMP search: cfg.workers goroutines run the same iterative deepening,
+//each with its own column ordering, and share what they find through the transposition table.
+//The helpers are stopped as soon as the main worker is done, and the move of the deepest
+//completed iteration (the main worker's on ties) is returned.
+func searchParallel(ctx context.Context, b *Board, maxDepth int, player string, cfg searchConfig) searchResult {
+	helperCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]searchResult, cfg.workers)
+	var wg sync.WaitGroup
+	for i := 1; i < cfg.workers; i++ {
+		helper := cfg
+		helper.workers, helper.info = 1, nil
+		if cfg.rng != nil {
+			helper.rng = rand.New(rand.NewSource(cfg.rng.Int63()))
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = searchWorker(helperCtx, b, maxDepth, player, helper)
+		}(i)
+	}
+	primary := cfg
+	primary.workers = 1
+	results[0] = searchWorker(ctx, b, maxDepth, player, primary)
+	cancel()
+	wg.Wait()
+
+	best := results[0]
+	for _, r := range results[1:] {
+		best.Nodes += r.Nodes
+		if r.Depth > best.Depth {
+			best.Move, best.Score, best.Depth = r.Move, r.Score, r.Depth
+		}
+	}
+	return best
+}
+
+//info describes the iteration of r for the SearchInfo callback; the principal variation is the
+//best move followed by the moves stored in tt for the positions it leads to
+func (r searchResult) info(b *Board, player string, tt *TranspositionTable) SearchInfo {
+	info := SearchInfo{Depth: r.Depth, Score: r.Score, Nodes: r.Nodes}
+	switch {
+	case r.Score > winThreshold:
+		info.Mate = big - r.Score
+	case r.Score < -winThreshold:
+		info.Mate = -(big + r.Score)
+	}
+	board := b.copyOfBoard()
+	move := r.Move
+	for len(info.PV) < r.Depth && move >= 0 && board.playMove(move, player) {
+		info.PV = append(info.PV, board.searchMove(move, player))
+		player = opponent(player)
+		if tt == nil || board.winner(opponent(player)) != "" {
+			break
+		}
+		key := board.hash
+		if player == PlayerTwoColor {
+			key ^= zobristMaximizer
+		}
+		var ok bool
+		if _, move, _, _, ok = tt.probe(key); !ok {
+			break
+		}
+	}
+	return info
+}
+
 //alphabeta implements the alphabeta algorithm and returns the score of the given board position
 //and the best move for the given board position
 func alphabeta(b *Board, maximizer bool, depth, alpha, beta, max_depth int) (int, int) {
-	if depth == max_depth {
+	s := &searcher{searchConfig: searchConfig{eval: defaultEvaluator}}
+	return s.alphabeta(b, maximizer, depth, alpha, beta, max_depth)
+}
+
+//alphabeta is the transposition-table aware version of the package level alphabeta. The moves
+//are the indices of Board.playMove, so that PopOut boards are searched with their pops; the
+//repetition rule is not taken into account
+func (s *searcher) alphabeta(b *Board, maximizer bool, depth, alpha, beta, max_depth int) (int, int) {
+	s.nodes++
+	if s.ctx != nil && s.nodes%abortCheckInterval == 0 && s.ctx.Err() != nil {
+		s.aborted = true
+	}
+	if s.aborted {
 		return 0, -1
 	}
-	if b.areFourConnected(PlayerTwoColor) {
+	// le dernier coup a été joué par le joueur qui n'a pas le trait ; il
+	// gagne aussi lorsqu'un retrait aligne les jetons des deux joueurs
+	mover := PlayerOneColor
+	if !maximizer {
+		mover = PlayerTwoColor
+	}
+	switch b.winner(mover) {
+	case PlayerTwoColor:
 		return big - depth, -1
-	} else if b.areFourConnected(PlayerOneColor) {
+	case PlayerOneColor:
 		return small + depth, -1
 	}
+	if b.movesMade == b.geo.cells() && b.variant == Classic {
+		return 0, -1
+	}
+	if depth == max_depth {
+		return s.evaluate(b), -1
+	}
+
+	key := b.hash
+	if maximizer {
+		key ^= zobristMaximizer
+	}
+	ttMove := -1
+	if s.tt != nil {
+		if v, m, d, bound, ok := s.tt.probeCounted(key, &s.ttStats); ok {
+			ttMove = m
+			// à la racine on veut toujours un coup joué par cette recherche
+			if depth > 0 && d >= max_depth-depth {
+				v = scoreFromTable(v, depth)
+				switch bound {
+				case BoundExact:
+					return v, m
+				case BoundLower:
+					alpha = max(alpha, v)
+				case BoundUpper:
+					beta = min(beta, v)
+				}
+				if alpha >= beta {
+					return v, m
+				}
+			}
+		}
+	}
+	alphaOrig, betaOrig := alpha, beta
+
 	var value int
-	var bestMove int
-	shuffledColumns := rand.Perm(7)
+	bestMove := -1
+	columns := orderColumns(s.perm(b.searchMoves()), ttMove)
 
 	if maximizer {
 		value = small
-		for _, column := range shuffledColumns {
-			if b.Drop(column, PlayerTwoColor) {
-				new_score, _ := alphabeta(b, false, depth+1, alpha, beta, max_depth)
-				b.undoDrop(column)
+		for _, column := range columns {
+			if b.playMove(column, PlayerTwoColor) {
+				new_score, _ := s.alphabeta(b, false, depth+1, alpha, beta, max_depth)
+				b.undoMove(column, PlayerTwoColor)
+				if s.aborted {
+					return 0, -1
+				}
 
 				if value < new_score {
 					bestMove = column
@@ -55,10 +284,13 @@ func alphabeta(b *Board, maximizer bool, depth, alpha, beta, max_depth int) (int
 		}
 	} else {
 		value = big
-		for _, column := range shuffledColumns {
-			if b.Drop(column, PlayerOneColor) {
-				new_score, _ := alphabeta(b, true, depth+1, alpha, beta, max_depth)
-				b.undoDrop(column)
+		for _, column := range columns {
+			if b.playMove(column, PlayerOneColor) {
+				new_score, _ := s.alphabeta(b, true, depth+1, alpha, beta, max_depth)
+				b.undoMove(column, PlayerOneColor)
+				if s.aborted {
+					return 0, -1
+				}
 
 				if value > new_score {
 					bestMove = column
@@ -71,9 +303,80 @@ func alphabeta(b *Board, maximizer bool, depth, alpha, beta, max_depth int) (int
 			}
 		}
 	}
+
+	if bestMove < 0 {
+		// en PopOut, un joueur sans coup possible fait partie nulle
+		value = 0
+	}
+
+	if s.tt != nil {
+		bound := BoundExact
+		if value <= alphaOrig {
+			bound = BoundUpper
+		} else if value >= betaOrig {
+			bound = BoundLower
+		}
+		s.tt.storeCounted(key, scoreToTable(value, depth), bestMove, max_depth-depth, bound, &s.ttStats)
+	}
 	return value, bestMove
 }
 
+//perm returns the n columns in a random order, drawn from the searcher's own source if it has one
+func (s *searcher) perm(n int) []int {
+	if s.rng != nil {
+		return s.rng.Perm(n)
+	}
+	return rand.Perm(n)
+}
+
+//evaluate scores a position at the search horizon from the maximizer's point of view, keeping
+//the heuristic strictly between the loss and win scores
+func (s *searcher) evaluate(b *Board) int {
+	eval := s.eval
+	if eval == nil {
+		eval = defaultEvaluator
+	}
+	return max(-winThreshold, min(winThreshold, eval.Evaluate(b, PlayerTwoColor)))
+}
+
+//orderColumns moves the column suggested by the transposition table (if any) to the front
+func orderColumns(columns []int, first int) []int {
+	if first < 0 {
+		return columns
+	}
+	for i, c := range columns {
+		if c == first {
+			copy(columns[1:i+1], columns[:i])
+			columns[0] = first
+			break
+		}
+	}
+	return columns
+}
+
+//scoreToTable converts a win/loss score relative to the root into a score relative to the
+//node at the given depth, so that it stays valid when the position is reached at another depth
+func scoreToTable(value, depth int) int {
+	if value > winThreshold {
+		return value + depth
+	}
+	if value < -winThreshold {
+		return value - depth
+	}
+	return value
+}
+
+//scoreFromTable is the inverse of scoreToTable
+func scoreFromTable(value, depth int) int {
+	if value > winThreshold {
+		return value - depth
+	}
+	if value < -winThreshold {
+		return value + depth
+	}
+	return value
+}
+
 func min(a, b int) int {
 	if a > b {
 		return b
@@ -86,4 +389,4 @@ func max(a, b int) int {
 		return b
 	}
 	return a
-}
\ No newline at end of file
+}
@@ -1,7 +1,11 @@
 package game
 
 import (
+	"context"
+	"math/rand"
+	"slices"
 	"testing"
+	"time"
 )
 
 var _ = func() bool {
@@ -10,7 +14,7 @@ var _ = func() bool {
 }()
 
 func TestGetAiMoveOneMoveFromLose(t *testing.T) {
-	board := NewBoard()
+	board := newStandardBoard()
 	board.Drop(5, PlayerOneColor)
 	board.Drop(5, PlayerOneColor)
 	board.Drop(5, PlayerOneColor)
@@ -23,7 +27,7 @@ func TestGetAiMoveOneMoveFromLose(t *testing.T) {
 }
 
 func TestGetAiMoveTwoMoveFromLose(t *testing.T) {
-	board := NewBoard()
+	board := newStandardBoard()
 	board.Drop(3, PlayerOneColor)
 	board.Drop(4, PlayerOneColor)
 
@@ -32,4 +36,149 @@ func TestGetAiMoveTwoMoveFromLose(t *testing.T) {
 	if bestMove != 2 && bestMove != 5 {
 		t.Errorf("AI did not made expected move, expected %d, got %d", 2, bestMove)
 	}
-}
\ No newline at end of file
+}
+func TestSearchIterativeRespectsDeadline(t *testing.T) {
+	board := newStandardBoard()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := searchIterative(ctx, board, 0, PlayerOneColor, searchConfig{tt: NewTranspositionTable(1<<16, ReplaceDepthPreferred)})
+	elapsed := time.Since(start)
+
+	if elapsed > 500*time.Millisecond {
+		t.Fatalf("search ignored its deadline, took %v", elapsed)
+	}
+	if result.Move < 0 || result.Move >= boardWidth {
+		t.Fatalf("expected a legal move, got %d", result.Move)
+	}
+	if result.Depth < 1 {
+		t.Fatalf("expected at least one completed iteration, got depth %d", result.Depth)
+	}
+}
+
+func TestSearchIterativeCancelledStillMoves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := searchIterative(ctx, newStandardBoard(), 0, PlayerOneColor, searchConfig{})
+	if result.Move < 0 || result.Move >= boardWidth || result.Depth != 1 {
+		t.Fatalf("expected the first iteration to complete, got move=%d depth=%d", result.Move, result.Depth)
+	}
+}
+
+func TestSearchIterativeStopsOnForcedWin(t *testing.T) {
+	board := newStandardBoard()
+	board.Drop(5, PlayerTwoColor)
+	board.Drop(5, PlayerTwoColor)
+	board.Drop(5, PlayerTwoColor)
+
+	result := searchIterative(context.Background(), board, 9, PlayerTwoColor, searchConfig{})
+	if result.Move != 5 {
+		t.Fatalf("expected winning move 5, got %d", result.Move)
+	}
+	if result.Depth != 1 || result.Score != big-1 {
+		t.Fatalf("expected the search to stop at depth 1 with score %d, got depth=%d score=%d", big-1, result.Depth, result.Score)
+	}
+}
+
+func TestGameManagerWithThinkTime(t *testing.T) {
+	gm := NewGameManager(true, 0, WithThinkTime(30*time.Millisecond))
+	if _, err := gm.MakePlayerTurn(3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	col, err := gm.MakeOpponentTurn(-1)
+	if err != nil {
+		t.Fatalf("unexpected error from AI opponent: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("AI exceeded its think time")
+	}
+	if col < 0 || col >= boardWidth {
+		t.Fatalf("AI returned out-of-range column %d", col)
+	}
+}
+
+func TestAIPlaysForTheSideToMove(t *testing.T) {
+	// l'IA commence la partie (après une victoire du joueur) : elle joue
+	// alors avec PlayerOneColor et doit chercher sa propre victoire.
+	gm := NewGameManager(true, 4)
+	for _, c := range []int{0, 1, 0, 1, 0, 1} {
+		player := PlayerOneColor
+		if gm.board.movesMade%2 == 1 {
+			player = PlayerTwoColor
+		}
+		gm.board.Drop(c, player)
+	}
+	gm.turn = 6
+	column, err := gm.MakeOpponentTurn(-1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if column != 0 || gm.GetState() != Lose {
+		t.Fatalf("expected the AI to win with column 0, got %d (state %v)", column, gm.GetState())
+	}
+}
+
+func TestSearchParallelFindsForcedWin(t *testing.T) {
+	board := newStandardBoard()
+	board.Drop(5, PlayerTwoColor)
+	board.Drop(5, PlayerTwoColor)
+	board.Drop(5, PlayerTwoColor)
+
+	cfg := searchConfig{tt: NewTranspositionTable(1<<16, ReplaceDepthPreferred), workers: 4}
+	result := searchIterative(context.Background(), board, 9, PlayerTwoColor, cfg)
+	if result.Move != 5 || result.Score != big-1 {
+		t.Fatalf("expected winning move 5 with score %d, got move=%d score=%d", big-1, result.Move, result.Score)
+	}
+}
+
+func TestSearchParallelRespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	cfg := searchConfig{tt: NewTranspositionTable(1<<16, ReplaceDepthPreferred), workers: 4}
+	result := searchIterative(ctx, newStandardBoard(), 0, PlayerOneColor, cfg)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("parallel search ignored its deadline, took %v", elapsed)
+	}
+	if result.Move < 0 || result.Move >= boardWidth || result.Depth < 1 {
+		t.Fatalf("expected a legal move from a completed iteration, got move=%d depth=%d", result.Move, result.Depth)
+	}
+}
+
+func TestSearchParallelMatchesSequentialScore(t *testing.T) {
+	board := newStandardBoard()
+	playMoves(t, board, 3, 3, 2, 4, 2)
+	sequential := searchIterative(context.Background(), board, 7, PlayerTwoColor, searchConfig{})
+	cfg := searchConfig{tt: NewTranspositionTable(1<<16, ReplaceDepthPreferred), workers: 4}
+	parallel := searchIterative(context.Background(), board, 7, PlayerTwoColor, cfg)
+	if parallel.Depth != 7 || parallel.Score != sequential.Score {
+		t.Fatalf("expected depth 7 and score %d, got depth=%d score=%d", sequential.Score, parallel.Depth, parallel.Score)
+	}
+}
+
+func TestAlphaBetaSeededSingleWorkerIsDeterministic(t *testing.T) {
+	play := func() []int {
+		engine := NewAlphaBeta(5)
+		engine.Workers = 1
+		engine.Rand = rand.New(rand.NewSource(7))
+		b := newStandardBoard()
+		var moves []int
+		for player := PlayerOneColor; !b.gameOver(); player = opponent(player) {
+			move, err := engine.BestMove(context.Background(), b, player)
+			if err != nil {
+				t.Fatalf("BestMove: %v", err)
+			}
+			b.Drop(move.Column, player)
+			moves = append(moves, move.Column)
+		}
+		return moves
+	}
+	first, second := play(), play()
+	if !slices.Equal(first, second) {
+		t.Fatalf("seeded games differ:\n%v\n%v", first, second)
+	}
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// MoveEval est la valeur d'un coup pour le joueur qui le joue, calculée
+// par Analyzer.
+type MoveEval struct {
+	Move     Move
+	Exact    bool    // Le résultat du coup est forcé : Outcome et Distance sont renseignés
+	Outcome  Outcome // Victoire, défaite ou nul forcés (avec Exact)
+	Distance int     // Demi-coups jusqu'à la fin de la partie, ce coup compris (avec Exact, sauf nul)
+	Score    int     // Évaluation heuristique sans Exact, positive si le coup est favorable
+}
+
+// String décrit la valeur du coup : "win in 3", "loss in 2", "draw" ou
+// l'évaluation heuristique signée ("+12").
+func (e MoveEval) String() string {
+	switch {
+	case !e.Exact:
+		if e.Score >= 0 {
+			return "+" + strconv.Itoa(e.Score)
+		}
+		return strconv.Itoa(e.Score)
+	case e.Outcome == OutcomeDraw:
+		return "draw"
+	}
+	return e.Outcome.String() + " in " + strconv.Itoa(e.Distance)
+}
+
+// Value renvoie la valeur du coup sur une échelle unique : les victoires
+// (d'autant plus grandes qu'elles sont rapides), puis les évaluations
+// heuristiques, le nul valant 0, puis les défaites (d'autant plus petites
+// qu'elles sont rapides).
+func (e MoveEval) Value() int {
+	switch {
+	case !e.Exact:
+		return min(max(e.Score, -winThreshold+1), winThreshold-1)
+	case e.Outcome == OutcomeWin:
+		return big - e.Distance
+	case e.Outcome == OutcomeLoss:
+		return small + e.Distance
+	}
+	return 0
+}
+
+// BestEval renvoie l'indice du meilleur coup de evals (voir Value), le
+// premier en cas d'égalité, ou -1 si evals est vide.
+func BestEval(evals []MoveEval) int {
+	best := -1
+	for i, e := range evals {
+		if best < 0 || e.Value() > evals[best].Value() {
+			best = i
+		}
+	}
+	return best
+}
+
+// Analyzer évalue chacun des coups possibles d'une position. Sur le
+// plateau standard aux règles classiques, le solveur donne la valeur
+// exacte des coups ; sinon, ou s'il n'aboutit pas à temps, chaque coup
+// est évalué par une recherche alpha-bêta de profondeur Depth, qui ne
+// trouve que les fins de partie à sa portée. Un Analyzer n'est pas
+// utilisable par plusieurs goroutines à la fois.
+type Analyzer struct {
+	Depth     int                 // Profondeur de la recherche après chaque coup (0 : jusqu'à la fin de la partie)
+	Solver    *Solver             // Solveur exact essayé en premier (nil : aucun)
+	SolveTime time.Duration       // Temps laissé au solveur (0 : jusqu'à l'échéance du contexte)
+	Table     *TranspositionTable // Table de transposition de la recherche (nil : aucune position mémorisée)
+	Evaluator Evaluator           // Évaluation à l'horizon (nil : PositionalEvaluator avec DefaultWeights)
+}
+
+// NewAnalyzer crée un analyseur dont la recherche a la profondeur depth,
+// avec un solveur et une table de transposition de tailles par défaut.
+func NewAnalyzer(depth int) *Analyzer {
+	return &Analyzer{
+		Depth:  depth,
+		Solver: NewSolver(DefaultSolverTableSize),
+		Table:  NewTranspositionTable(DefaultTableSize, ReplaceDepthPreferred),
+	}
+}
+
+// Analyze renvoie la valeur de chacun des coups que player, le joueur au
+// trait, peut jouer sur b, sans modifier b : les dépôts de gauche à
+// droite, puis les retraits en PopOut. À l'échéance de ctx, les coups
+// restants sont évalués par la recherche la plus courte. La règle de la
+// triple répétition du PopOut n'est pas prise en compte.
+func (a *Analyzer) Analyze(ctx context.Context, b *Board, player string) ([]MoveEval, error) {
+	if b.gameOver() || !b.hasMove(player) {
+		return nil, ErrGameOver
+	}
+	if a.Solver != nil {
+		solveCtx := ctx
+		if a.SolveTime > 0 {
+			var cancel context.CancelFunc
+			solveCtx, cancel = context.WithTimeout(ctx, a.SolveTime)
+			defer cancel()
+		}
+		if moves, err := a.Solver.AnalyzeContext(solveCtx, b); err == nil {
+			evals := make([]MoveEval, 0, len(moves))
+			for _, m := range moves {
+				evals = append(evals, MoveEval{Move: Move{Column: m.Column, Player: player}, Exact: true, Outcome: m.Outcome, Distance: m.Distance})
+			}
+			slices.SortFunc(evals, func(x, y MoveEval) int {
+				return x.Move.Column - y.Move.Column
+			})
+			return evals, nil
+		}
+	}
+
+	other := opponent(player)
+	cfg := searchConfig{tt: a.Table, eval: a.Evaluator}
+	var evals []MoveEval
+	for m := 0; m < b.searchMoves(); m++ {
+		if !b.canPlayMove(m, player) {
+			continue
+		}
+		e := MoveEval{Move: b.searchMove(m, player)}
+		child := b.copyOfBoard()
+		child.playMove(m, player)
+		switch winner := child.winner(player); {
+		case winner == player:
+			e.Exact, e.Outcome, e.Distance = true, OutcomeWin, 1
+		case winner == other:
+			e.Exact, e.Outcome, e.Distance = true, OutcomeLoss, 1
+		case !child.hasMove(other):
+			e.Exact, e.Outcome, e.Distance = true, OutcomeDraw, 1
+		default:
+			// le score de la recherche est du point de vue de l'adversaire
+			r := searchIterative(ctx, child, a.Depth, other, cfg)
+			switch {
+			case r.Score > winThreshold:
+				e.Exact, e.Outcome, e.Distance = true, OutcomeLoss, big-r.Score+1
+			case r.Score < -winThreshold:
+				e.Exact, e.Outcome, e.Distance = true, OutcomeWin, big+r.Score+1
+			default:
+				e.Score = -r.Score
+			}
+		}
+		evals = append(evals, e)
+	}
+	return evals, nil
+}
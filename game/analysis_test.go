@@ -0,0 +1,109 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMoveEvalString(t *testing.T) {
+	for _, c := range []struct {
+		e    MoveEval
+		want string
+	}{
+		{MoveEval{Exact: true, Outcome: OutcomeWin, Distance: 3}, "win in 3"},
+		{MoveEval{Exact: true, Outcome: OutcomeLoss, Distance: 2}, "loss in 2"},
+		{MoveEval{Exact: true, Outcome: OutcomeDraw, Distance: 9}, "draw"},
+		{MoveEval{Score: 12}, "+12"},
+		{MoveEval{Score: -5}, "-5"},
+	} {
+		if got := c.e.String(); got != c.want {
+			t.Fatalf("%+v: got %q, expected %q", c.e, got, c.want)
+		}
+	}
+	evals := []MoveEval{
+		{Exact: true, Outcome: OutcomeLoss, Distance: 4},
+		{Score: big},
+		{Exact: true, Outcome: OutcomeWin, Distance: 5},
+		{Exact: true, Outcome: OutcomeWin, Distance: 3},
+	}
+	if best := BestEval(evals); best != 3 {
+		t.Fatalf("expected the fastest win to be the best move, got %d", best)
+	}
+	if BestEval(nil) != -1 {
+		t.Fatalf("expected no best move without moves")
+	}
+}
+
+func TestAnalyzerSolver(t *testing.T) {
+	b := randomPosition(rand.New(rand.NewSource(1)), 24)
+	player := PlayerOneColor
+	a := NewAnalyzer(6)
+	evals, err := a.Analyze(context.Background(), b, player)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	want, _ := NewSolver(1 << 16).Analyze(b)
+	if len(evals) != len(want) {
+		t.Fatalf("expected %d moves, got %d", len(want), len(evals))
+	}
+	for _, w := range want {
+		i := slices.IndexFunc(evals, func(e MoveEval) bool { return e.Move.Column == w.Column })
+		if i < 0 || !evals[i].Exact || evals[i].Outcome != w.Outcome || evals[i].Distance != w.Distance {
+			t.Fatalf("column %d: got %+v, expected %+v", w.Column+1, evals, w)
+		}
+	}
+	for i := 1; i < len(evals); i++ {
+		if evals[i].Move.Column <= evals[i-1].Move.Column {
+			t.Fatalf("expected the moves from left to right: %v", evals)
+		}
+	}
+
+	// sans solveur, la recherche trouve la victoire immédiate
+	b = newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 2)
+	evals, err = (&Analyzer{Depth: 2}).Analyze(context.Background(), b, player)
+	if err != nil || len(evals) != boardWidth {
+		t.Fatalf("Analyze: %v (%d moves)", err, len(evals))
+	}
+	if e := evals[0]; !e.Exact || e.Outcome != OutcomeWin || e.Distance != 1 || BestEval(evals) != 0 {
+		t.Fatalf("expected column 1 to win at once, got %v", e)
+	}
+}
+
+func TestAnalyzerSearch(t *testing.T) {
+	// O menace de gagner dans la colonne 2 : X doit l'occuper
+	b := NewBoard(5, 4, 4)
+	playMoves(t, b, 0, 1, 0, 1, 4, 1)
+	a := &Analyzer{Depth: 4}
+	evals, err := a.Analyze(context.Background(), b, PlayerOneColor)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if len(evals) != 5 || BestEval(evals) != 1 {
+		t.Fatalf("expected the block to be the best of 5 moves: %v", evals)
+	}
+	if e := evals[2]; !e.Exact || e.Outcome != OutcomeLoss || e.Distance != 2 {
+		t.Fatalf("expected column 3 to lose in 2, got %v", e)
+	}
+	if evals[1].Exact && evals[1].Outcome == OutcomeLoss {
+		t.Fatalf("expected the block not to lose at once, got %v", evals[1])
+	}
+
+	// en PopOut, les retraits sont évalués après les dépôts
+	b = NewBoard(4, 4, 4)
+	b.variant = PopOut
+	playMoves(t, b, 0, 1)
+	evals, err = a.Analyze(context.Background(), b, PlayerOneColor)
+	if err != nil || len(evals) != 5 || evals[4].Move.Kind != MovePop || evals[4].Move.Column != 0 {
+		t.Fatalf("unexpected PopOut evaluations %v (%v)", evals, err)
+	}
+
+	b = newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 1, 0)
+	if _, err := a.Analyze(context.Background(), b, PlayerTwoColor); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected ErrGameOver, got %v", err)
+	}
+}
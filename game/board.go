@@ -2,163 +2,259 @@ package game
 
 import (
 	"fmt"
+	"math/bits"
 	"strings"
 )
 
-// Board représente l'état du plateau de Puissance 4.
+// Board représente l'état d'un plateau de Puissance 4 sous forme de
+// bitboard.
+//
+// Chaque colonne occupe height bits consécutifs : la case (ligne r depuis
+// le bas, colonne c) correspond au bit c*height + r. Le plateau entier
+// tient donc dans 64 bits (voir CheckGeometry).
 //
 // Champs :
-// - board : matrice (hauteur x largeur) contenant les symboles des cases.
+// - geo : dimensions du plateau et masques associés.
+// - players : un masque de 64 bits par joueur (PlayerOneColor, PlayerTwoColor).
 // - col : slice indiquant combien de jetons sont déjà placés par colonne.
-// - movesMade : nombre total de coups joués sur le plateau.
+// - movesMade : nombre de jetons sur le plateau (en PopOut, un retrait en enlève un).
+// - hash : clé Zobrist de la position, mise à jour à chaque coup.
+// - variant : règles de la partie (Classic ou PopOut).
 type Board struct {
-	board     [][]string
+	geo       *geometry
+	players   [2]uint64
 	col       []int
 	movesMade int
+	hash      uint64
+	variant   Variant
 }
 
-// Constantes de configuration du plateau : largeur, hauteur et symbole
-// utilisé pour représenter une case vide.
+// Constantes de configuration du plateau standard : largeur, hauteur,
+// nombre de jetons à aligner et symbole utilisé pour représenter une case
+// vide. Les plateaux d'autres dimensions sont décrits par leur geometry.
 const (
-	boardWidth  = 7
-	boardHeight = 6
-	emptySpot   = "∟"
+	boardWidth    = DefaultWidth
+	boardHeight   = DefaultHeight
+	connectLength = DefaultConnect
+	emptySpot     = "∟"
 )
 
+// zobristKeys associe une clé aléatoire à chaque couple (joueur, case) ;
+// la clé d'une position est le XOR des clés de ses jetons. Les clés sont
+// générées à partir d'une graine fixe pour être identiques d'une exécution
+// à l'autre.
+var zobristKeys = computeZobristKeys(0x9e3779b97f4a7c15)
+
+// computeZobristKeys génère les clés Zobrist avec un générateur splitmix64.
+func computeZobristKeys(seed uint64) [2][64]uint64 {
+	var keys [2][64]uint64
+	for p := range keys {
+		for i := range keys[p] {
+			seed += 0x9e3779b97f4a7c15
+			z := seed
+			z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+			z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+			keys[p][i] = z ^ (z >> 31)
+		}
+	}
+	return keys
+}
+
+// playerIndex renvoie l'indice du masque associé au symbole du joueur, ou
+// -1 si le symbole n'est pas celui d'un joueur.
+func playerIndex(player string) int {
+	switch player {
+	case PlayerOneColor:
+		return 0
+	case PlayerTwoColor:
+		return 1
+	}
+	return -1
+}
+
+// opponent renvoie le symbole de l'adversaire de player.
+func opponent(player string) string {
+	if player == PlayerOneColor {
+		return PlayerTwoColor
+	}
+	return PlayerOneColor
+}
+
 // gameOver retourne true si la partie est terminée :
 // - soit le nombre maximal de coups a été atteint (toutes les cases remplies),
-// - soit un joueur a quatre jetons connectés.
+// - soit un joueur a aligné connectN jetons.
+//
+// En PopOut, un plateau plein ne termine pas la partie tant que le joueur
+// au trait peut retirer un jeton (voir hasMove).
 func (b *Board) gameOver() bool {
-	return b.movesMade == 42 || b.areFourConnected(PlayerOneColor) || b.areFourConnected(PlayerTwoColor)
+	return (b.movesMade == b.geo.cells() && b.variant == Classic) || b.geo.hasAlignment(b.players[0]) || b.geo.hasAlignment(b.players[1])
+}
+
+// String renvoie le plateau ligne par ligne, de haut en bas : 'X' pour
+// PlayerOneColor, 'O' pour PlayerTwoColor et '.' pour une case vide.
+func (b *Board) String() string {
+	return strings.Join(b.boardRows(), "\n")
 }
 
 // copyOfBoard renvoie une copie profonde du plateau courant. La copie
 // est indépendante de l'original (modifications ultérieures n'affectent pas
 // l'instance source).
 func (b *Board) copyOfBoard() *Board {
-	boardCopy := NewBoard()
-	for i := 0; i < boardHeight; i++ {
-		for j := 0; j < boardWidth; j++ {
-			boardCopy.board[i][j] = b.board[i][j]
-		}
-	}
-	boardCopy.col = make([]int, boardWidth)
+	boardCopy := *b
+	boardCopy.col = make([]int, len(b.col))
 	copy(boardCopy.col, b.col)
-	return boardCopy
+	return &boardCopy
 }
 
-// NewBoard crée et retourne un nouveau plateau pour une partie de
-// Puissance 4. Toutes les cases sont initialisées avec le symbole
-// représentant une case vide et les compteurs de colonnes sont remis à zéro.
-func NewBoard() *Board {
+// NewBoard crée et retourne un nouveau plateau de width colonnes et height
+// rangées sur lequel il faut aligner connectN jetons pour gagner (7, 6 et 4
+// pour le Puissance 4 standard). Toutes les cases sont vides et les
+// compteurs de colonnes sont remis à zéro. NewBoard panique si les
+// dimensions sont refusées par CheckGeometry.
+func NewBoard(width, height, connectN int) *Board {
+	if err := CheckGeometry(width, height, connectN); err != nil {
+		panic(err)
+	}
 	b := new(Board)
+	b.geo = getGeometry(width, height, connectN)
 	b.movesMade = 0
-	b.col = make([]int, boardWidth)
-	// initialize the connect 4 board
-	for range boardHeight {
-		row := make([]string, boardWidth)
+	b.col = make([]int, width)
+	return b
+}
 
-		for i := range row {
-			row[i] = emptySpot
-		}
-		b.board = append(b.board, row)
+// newStandardBoard crée un plateau standard de Puissance 4.
+func newStandardBoard() *Board {
+	return NewBoard(DefaultWidth, DefaultHeight, DefaultConnect)
+}
+
+// Width renvoie le nombre de colonnes du plateau.
+func (b *Board) Width() int {
+	return b.geo.width
+}
+
+// Height renvoie le nombre de rangées du plateau.
+func (b *Board) Height() int {
+	return b.geo.height
+}
+
+// ConnectN renvoie le nombre de jetons à aligner pour gagner.
+func (b *Board) ConnectN() int {
+	return b.geo.connect
+}
+
+// canPlay indique si la colonne existe et n'est pas pleine.
+func (b *Board) canPlay(column int) bool {
+	return column >= 0 && column < b.geo.width && b.col[column] < b.geo.height
+}
+
+// cell renvoie le symbole de la case (row, column) : PlayerOneColor,
+// PlayerTwoColor ou emptySpot.
+func (b *Board) cell(row, column int) string {
+	bit := b.geo.cellBit(row, column)
+	switch {
+	case b.players[0]&bit != 0:
+		return PlayerOneColor
+	case b.players[1]&bit != 0:
+		return PlayerTwoColor
 	}
-	return b
+	return emptySpot
 }
 
 // printBoard affiche le plateau sur la sortie standard de façon lisible.
 func (b *Board) printBoard() {
 	space := strings.Repeat(" ", 20)
 	fmt.Print(space)
-	for i := 0; i < len(b.board[0]); i++ {
+	for i := 0; i < b.geo.width; i++ {
 		fmt.Printf("%d ", i)
 	}
 	fmt.Println()
-	for i := 0; i < len(b.board); i++ {
+	for i := 0; i < b.geo.height; i++ {
 		fmt.Print(space)
-		for j := 0; j < len(b.board[0]); j++ {
-			fmt.Print(b.board[i][j] + " ")
+		for j := 0; j < b.geo.width; j++ {
+			fmt.Print(b.cell(i, j) + " ")
 		}
 		fmt.Println()
 	}
 }
 
 // undoDrop annule le dernier dépôt dans la colonne spécifiée en
-// décrémentant le compteur de la colonne et réinitialisant la case
-// correspondante à la valeur vide.
+// décrémentant le compteur de la colonne et en vidant la case
+// correspondante.
 func (b *Board) undoDrop(column int) {
 	b.col[column]--
-	b.board[5-b.col[column]][column] = emptySpot
+	idx := column*b.geo.height + b.col[column]
+	bit := uint64(1) << idx
+	if b.players[0]&bit != 0 {
+		b.hash ^= zobristKeys[0][idx]
+	} else {
+		b.hash ^= zobristKeys[1][idx]
+	}
+	b.players[0] &^= bit
+	b.players[1] &^= bit
 	b.movesMade--
 }
 
 // Drop tente de placer le jeton du joueur dans la colonne indiquée.
 // Retourne true si le jeton a été placé avec succès, false sinon
-// (colonne invalide ou pleine).
+// (colonne invalide ou pleine, ou symbole de joueur inconnu).
 func (b *Board) Drop(column int, player string) bool {
-	if column < len(b.board[0]) && (column >= 0) && b.col[column] < len(b.board) {
-		b.board[5-b.col[column]][column] = player
-		b.col[column]++
-		b.movesMade++
-		return true
+	p := playerIndex(player)
+	if p < 0 || !b.canPlay(column) {
+		return false
 	}
-	return false
-}
-
-// WhereConnected recherche s'il existe quatre jetons consécutifs du
-// joueur fourni. Si trouvé, retourne true ainsi que deux tableaux de
-// 4 entiers représentant les indices de ligne et de colonne des
-// quatre positions; sinon retourne false et des tableaux remplis de -1.
-func (b *Board) WhereConnected(player string) (bool, [4]int, [4]int) {
-	for j := 0; j < len(b.board[0])-3; j++ {
-		for i := 0; i < len(b.board); i++ {
-			if b.board[i][j] == player &&
-				b.board[i][j+1] == player &&
-				b.board[i][j+2] == player &&
-				b.board[i][j+3] == player {
-				return true, [4]int{i, i, i, i}, [4]int{j, j + 1, j + 2, j + 3}
-			}
-		}
+	idx := column*b.geo.height + b.col[column]
+	b.players[p] |= uint64(1) << idx
+	b.hash ^= zobristKeys[p][idx]
+	b.col[column]++
+	b.movesMade++
+	return true
+}
+
+// WhereConnected recherche s'il existe connectN jetons consécutifs du
+// joueur fourni. Si trouvé, retourne true ainsi que deux slices de
+// connectN entiers représentant les indices de ligne et de colonne des
+// positions alignées ; sinon retourne false et des slices remplies de -1.
+//
+// Les directions sont examinées dans l'ordre horizontal, vertical,
+// diagonale montante puis descendante ; les coordonnées sont renvoyées
+// de gauche à droite, de haut en bas pour une verticale et depuis la case
+// la plus basse pour une diagonale.
+func (b *Board) WhereConnected(player string) (bool, []int, []int) {
+	g := b.geo
+	rows, cols := make([]int, g.connect), make([]int, g.connect)
+	for k := range rows {
+		rows[k], cols[k] = -1, -1
 	}
-	// verticalCheck
-	for i := 0; i < len(b.board)-3; i++ {
-		for j := 0; j < len(b.board[0]); j++ {
-			if b.board[i][j] == player &&
-				b.board[i+1][j] == player &&
-				b.board[i+2][j] == player &&
-				b.board[i+3][j] == player {
-				return true, [4]int{i, i + 1, i + 2, i + 3}, [4]int{j, j, j, j}
-			}
-		}
+	p := playerIndex(player)
+	if p < 0 {
+		return false, rows, cols
 	}
-	// ascendingDiagonalCheck
-	for i := 3; i < len(b.board); i++ {
-		for j := 0; j < len(b.board[0])-3; j++ {
-			if b.board[i][j] == player &&
-				b.board[i-1][j+1] == player &&
-				b.board[i-2][j+2] == player &&
-				b.board[i-3][j+3] == player {
-				return true, [4]int{i, i - 1, i - 2, i - 3}, [4]int{j, j + 1, j + 2, j + 3}
-			}
+	for dir, shift := range g.directions {
+		runs := g.alignments(b.players[p], dir)
+		if runs == 0 {
+			continue
 		}
-	}
-	// descendingDiagonalCheck
-	for i := 3; i < len(b.board); i++ {
-		for j := 3; j < len(b.board[0]); j++ {
-			if b.board[i][j] == player &&
-				b.board[i-1][j-1] == player &&
-				b.board[i-2][j-2] == player &&
-				b.board[i-3][j-3] == player {
-				return true, [4]int{i, i - 1, i - 2, i - 3}, [4]int{j, j - 1, j - 2, j - 3}
+		start := bits.TrailingZeros64(runs)
+		for k := 0; k < g.connect; k++ {
+			idx := k
+			// verticale et diagonale descendante : la case de départ est la
+			// plus basse (resp. la plus haute) ; on inverse l'ordre pour
+			// conserver celui attendu par l'interface.
+			if dir == 1 || dir == 3 {
+				idx = g.connect - 1 - k
 			}
+			bit := start + k*shift
+			rows[idx] = g.height - 1 - bit%g.height
+			cols[idx] = bit / g.height
 		}
+		return true, rows, cols
 	}
-	return false, [4]int{-1, -1, -1, -1}, [4]int{-1, -1, -1, -1}
+	return false, rows, cols
 }
 
-// areFourConnected retourne true si le joueur a quatre jetons
-// connectés sur le plateau (utilise WhereConnected pour la détection).
+// areFourConnected retourne true si le joueur a aligné connectN jetons
+// (quatre sur le plateau standard).
 func (b *Board) areFourConnected(player string) bool {
-	connected, _, _ := b.WhereConnected(player)
-	return connected
+	p := playerIndex(player)
+	return p >= 0 && b.geo.hasAlignment(b.players[p])
 }
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"context"
+	"testing"
+)
+
+// gridBoard reproduit l'ancienne représentation du plateau (matrice de
+// symboles parcourue entièrement à chaque détection de victoire). Elle
+// n'existe que pour comparer les performances avec le bitboard.
+type gridBoard struct {
+	board [][]string
+	col   []int
+}
+
+func newGridBoard() *gridBoard {
+	g := &gridBoard{col: make([]int, boardWidth)}
+	for range boardHeight {
+		row := make([]string, boardWidth)
+		for i := range row {
+			row[i] = emptySpot
+		}
+		g.board = append(g.board, row)
+	}
+	return g
+}
+
+func (g *gridBoard) drop(column int, player string) bool {
+	if column < 0 || column >= boardWidth || g.col[column] >= boardHeight {
+		return false
+	}
+	g.board[boardHeight-1-g.col[column]][column] = player
+	g.col[column]++
+	return true
+}
+
+func (g *gridBoard) undoDrop(column int) {
+	g.col[column]--
+	g.board[boardHeight-1-g.col[column]][column] = emptySpot
+}
+
+func (g *gridBoard) areFourConnected(player string) bool {
+	b := g.board
+	for i := 0; i < boardHeight; i++ {
+		for j := 0; j < boardWidth; j++ {
+			if b[i][j] != player {
+				continue
+			}
+			if j+3 < boardWidth && b[i][j+1] == player && b[i][j+2] == player && b[i][j+3] == player {
+				return true
+			}
+			if i+3 < boardHeight && b[i+1][j] == player && b[i+2][j] == player && b[i+3][j] == player {
+				return true
+			}
+			if i >= 3 && j+3 < boardWidth && b[i-1][j+1] == player && b[i-2][j+2] == player && b[i-3][j+3] == player {
+				return true
+			}
+			if i >= 3 && j >= 3 && b[i-1][j-1] == player && b[i-2][j-2] == player && b[i-3][j-3] == player {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// perftGrid et perftBitboard parcourent tout l'arbre de jeu jusqu'à la
+// profondeur demandée, en testant la victoire à chaque nœud comme le fait
+// alphabeta, et renvoient le nombre de nœuds visités.
+func perftGrid(g *gridBoard, player string, depth int) int {
+	if depth == 0 || g.areFourConnected(PlayerOneColor) || g.areFourConnected(PlayerTwoColor) {
+		return 1
+	}
+	next := PlayerOneColor
+	if player == PlayerOneColor {
+		next = PlayerTwoColor
+	}
+	nodes := 1
+	for column := 0; column < boardWidth; column++ {
+		if g.drop(column, player) {
+			nodes += perftGrid(g, next, depth-1)
+			g.undoDrop(column)
+		}
+	}
+	return nodes
+}
+
+func perftBitboard(b *Board, player string, depth int) int {
+	if depth == 0 || b.areFourConnected(PlayerOneColor) || b.areFourConnected(PlayerTwoColor) {
+		return 1
+	}
+	next := PlayerOneColor
+	if player == PlayerOneColor {
+		next = PlayerTwoColor
+	}
+	nodes := 1
+	for column := 0; column < boardWidth; column++ {
+		if b.Drop(column, player) {
+			nodes += perftBitboard(b, next, depth-1)
+			b.undoDrop(column)
+		}
+	}
+	return nodes
+}
+
+const perftDepth = 6
+
+func TestPerftRepresentationsAgree(t *testing.T) {
+	grid := perftGrid(newGridBoard(), PlayerOneColor, 5)
+	bitboard := perftBitboard(newStandardBoard(), PlayerOneColor, 5)
+	if grid != bitboard {
+		t.Fatalf("node counts differ: grid=%d bitboard=%d", grid, bitboard)
+	}
+}
+
+func BenchmarkPerftGrid(b *testing.B) {
+	nodes := 0
+	for i := 0; i < b.N; i++ {
+		nodes += perftGrid(newGridBoard(), PlayerOneColor, perftDepth)
+	}
+	b.ReportMetric(float64(nodes)/b.Elapsed().Seconds(), "nodes/s")
+}
+
+func BenchmarkPerftBitboard(b *testing.B) {
+	nodes := 0
+	for i := 0; i < b.N; i++ {
+		nodes += perftBitboard(newStandardBoard(), PlayerOneColor, perftDepth)
+	}
+	b.ReportMetric(float64(nodes)/b.Elapsed().Seconds(), "nodes/s")
+}
+
+func BenchmarkAlphabetaDepth9(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		alphabeta(newStandardBoard(), true, 0, small, big, 9)
+	}
+}
+
+func BenchmarkAlphabetaDepth9WithTable(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := &searcher{searchConfig: searchConfig{tt: NewTranspositionTable(1<<18, ReplaceDepthPreferred)}}
+		s.alphabeta(newStandardBoard(), true, 0, small, big, 9)
+	}
+}
+
+func benchmarkSearchWorkers(b *testing.B, workers int) {
+	for i := 0; i < b.N; i++ {
+		cfg := searchConfig{tt: NewTranspositionTable(1<<18, ReplaceDepthPreferred), workers: workers}
+		searchIterative(context.Background(), newStandardBoard(), 11, PlayerOneColor, cfg)
+	}
+}
+
+func BenchmarkSearchDepth11Workers1(b *testing.B) { benchmarkSearchWorkers(b, 1) }
+func BenchmarkSearchDepth11Workers4(b *testing.B) { benchmarkSearchWorkers(b, 4) }
@@ -3,6 +3,7 @@ package game
 import (
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -12,20 +13,26 @@ var _ = func() bool {
 	return true
 }()
 
+// setCell place directement le jeton du joueur dans la case (row, column),
+// sans tenir compte de la gravité ni des compteurs de colonnes.
+func setCell(b *Board, row, column int, player string) {
+	b.players[playerIndex(player)] |= b.geo.cellBit(row, column)
+}
+
 func TestBoardWhereConnectedHorizontal(t *testing.T) {
-	board := NewBoard()
-	board.Drop(5, "*")
-	board.Drop(4, "*")
-	board.Drop(3, "*")
-	board.Drop(2, "*")
-
-	areConnected, row, col := board.WhereConnected("*")
-	expectedCol := [4]int{2, 3, 4, 5}
-	if col != expectedCol {
+	board := newStandardBoard()
+	board.Drop(5, PlayerOneColor)
+	board.Drop(4, PlayerOneColor)
+	board.Drop(3, PlayerOneColor)
+	board.Drop(2, PlayerOneColor)
+
+	areConnected, row, col := board.WhereConnected(PlayerOneColor)
+	expectedCol := []int{2, 3, 4, 5}
+	if !slices.Equal(col, expectedCol) {
 		t.Errorf("columns are incorrect, expected %v got %v", expectedCol, col)
 	}
-	expectedRow := [4]int{5, 5, 5, 5}
-	if row != expectedRow {
+	expectedRow := []int{5, 5, 5, 5}
+	if !slices.Equal(row, expectedRow) {
 		t.Errorf("rows are incorrect, expected %v got %v", expectedRow, row)
 	}
 	if !areConnected {
@@ -34,19 +41,19 @@ func TestBoardWhereConnectedHorizontal(t *testing.T) {
 }
 
 func TestBoardWhereConnectedVertical(t *testing.T) {
-	board := NewBoard()
-	board.Drop(5, "*")
-	board.Drop(5, "*")
-	board.Drop(5, "*")
-	board.Drop(5, "*")
-
-	areConnected, row, col := board.WhereConnected("*")
-	expectedCol := [4]int{5, 5, 5, 5}
-	if col != expectedCol {
+	board := newStandardBoard()
+	board.Drop(5, PlayerOneColor)
+	board.Drop(5, PlayerOneColor)
+	board.Drop(5, PlayerOneColor)
+	board.Drop(5, PlayerOneColor)
+
+	areConnected, row, col := board.WhereConnected(PlayerOneColor)
+	expectedCol := []int{5, 5, 5, 5}
+	if !slices.Equal(col, expectedCol) {
 		t.Errorf("columns are incorrect, expected %v got %v", expectedCol, col)
 	}
-	expectedRow := [4]int{2, 3, 4, 5}
-	if row != expectedRow {
+	expectedRow := []int{2, 3, 4, 5}
+	if !slices.Equal(row, expectedRow) {
 		t.Errorf("rows are incorrect, expected %v got %v", expectedRow, row)
 	}
 	if !areConnected {
@@ -55,19 +62,19 @@ func TestBoardWhereConnectedVertical(t *testing.T) {
 }
 
 func TestBoardWhereConnectedAscendingDiagonal(t *testing.T) {
-	board := NewBoard()
-	board.board[5][0] = "*"
-	board.board[4][1] = "*"
-	board.board[3][2] = "*"
-	board.board[2][3] = "*"
-
-	areConnected, row, col := board.WhereConnected("*")
-	expectedCol := [4]int{0, 1, 2, 3}
-	if col != expectedCol {
+	board := newStandardBoard()
+	setCell(board, 5, 0, PlayerOneColor)
+	setCell(board, 4, 1, PlayerOneColor)
+	setCell(board, 3, 2, PlayerOneColor)
+	setCell(board, 2, 3, PlayerOneColor)
+
+	areConnected, row, col := board.WhereConnected(PlayerOneColor)
+	expectedCol := []int{0, 1, 2, 3}
+	if !slices.Equal(col, expectedCol) {
 		t.Errorf("columns are incorrect, expected %v got %v", expectedCol, col)
 	}
-	expectedRow := [4]int{5, 4, 3, 2}
-	if row != expectedRow {
+	expectedRow := []int{5, 4, 3, 2}
+	if !slices.Equal(row, expectedRow) {
 		t.Errorf("rows are incorrect, expected %v got %v", expectedRow, row)
 	}
 	if !areConnected {
@@ -76,20 +83,20 @@ func TestBoardWhereConnectedAscendingDiagonal(t *testing.T) {
 }
 
 func TestBoardWhereConnectedDescendingDiagonal(t *testing.T) {
-	board := NewBoard()
+	board := newStandardBoard()
 	// positions: [5][3], [4][2], [3][1], [2][0]
-	board.board[5][3] = "*"
-	board.board[4][2] = "*"
-	board.board[3][1] = "*"
-	board.board[2][0] = "*"
-
-	areConnected, row, col := board.WhereConnected("*")
-	expectedCol := [4]int{3, 2, 1, 0}
-	if col != expectedCol {
+	setCell(board, 5, 3, PlayerOneColor)
+	setCell(board, 4, 2, PlayerOneColor)
+	setCell(board, 3, 1, PlayerOneColor)
+	setCell(board, 2, 0, PlayerOneColor)
+
+	areConnected, row, col := board.WhereConnected(PlayerOneColor)
+	expectedCol := []int{3, 2, 1, 0}
+	if !slices.Equal(col, expectedCol) {
 		t.Errorf("columns are incorrect, expected %v got %v", expectedCol, col)
 	}
-	expectedRow := [4]int{5, 4, 3, 2}
-	if row != expectedRow {
+	expectedRow := []int{5, 4, 3, 2}
+	if !slices.Equal(row, expectedRow) {
 		t.Errorf("rows are incorrect, expected %v got %v", expectedRow, row)
 	}
 	if !areConnected {
@@ -98,36 +105,36 @@ func TestBoardWhereConnectedDescendingDiagonal(t *testing.T) {
 }
 
 func TestDropBoundaries(t *testing.T) {
-	b := NewBoard()
-	if b.Drop(-1, "*") {
+	b := newStandardBoard()
+	if b.Drop(-1, PlayerOneColor) {
 		t.Errorf("Drop should return false for negative column")
 	}
-	if b.Drop(len(b.board[0]), "*") {
+	if b.Drop(boardWidth, PlayerOneColor) {
 		t.Errorf("Drop should return false for column >= width")
 	}
 }
 
 func TestDropColumnFullAndMovesMade(t *testing.T) {
-	b := NewBoard()
+	b := newStandardBoard()
 	col := 0
 	// fill the column
-	for i := 0; i < len(b.board); i++ {
-		if !b.Drop(col, "*") {
+	for i := 0; i < boardHeight; i++ {
+		if !b.Drop(col, PlayerOneColor) {
 			t.Fatalf("expected Drop to succeed at iteration %d", i)
 		}
 	}
 	// now the column should be full
-	if b.Drop(col, "*") {
+	if b.Drop(col, PlayerOneColor) {
 		t.Errorf("Drop should fail when column is full")
 	}
-	if b.col[col] != len(b.board) {
-		t.Errorf("expected col[%d] == %d got %d", col, len(b.board), b.col[col])
+	if b.col[col] != boardHeight {
+		t.Errorf("expected col[%d] == %d got %d", col, boardHeight, b.col[col])
 	}
 }
 
 func TestUndoDrop(t *testing.T) {
-	b := NewBoard()
-	if !b.Drop(0, "*") {
+	b := newStandardBoard()
+	if !b.Drop(0, PlayerOneColor) {
 		t.Fatalf("Drop failed when it should succeed")
 	}
 	if b.col[0] != 1 || b.movesMade != 1 {
@@ -137,33 +144,33 @@ func TestUndoDrop(t *testing.T) {
 	if b.col[0] != 0 || b.movesMade != 0 {
 		t.Fatalf("unexpected state after undoDrop: col[0]=%d movesMade=%d", b.col[0], b.movesMade)
 	}
-	if b.board[5][0] != emptySpot {
-		t.Fatalf("expected bottom cell to be empty after undoDrop, got %q", b.board[5][0])
+	if b.cell(5, 0) != emptySpot {
+		t.Fatalf("expected bottom cell to be empty after undoDrop, got %q", b.cell(5, 0))
 	}
 }
 
 func TestCopyOfBoardDeepCopy(t *testing.T) {
-	b := NewBoard()
-	if !b.Drop(1, "*") {
+	b := newStandardBoard()
+	if !b.Drop(1, PlayerOneColor) {
 		t.Fatalf("initial Drop failed")
 	}
 	copy := b.copyOfBoard()
 	// modify the copy and ensure original is unchanged
-	if !copy.Drop(1, "o") {
+	if !copy.Drop(1, PlayerTwoColor) {
 		t.Fatalf("Drop on copy failed")
 	}
 	// original should still have only the first drop at row 5
-	if b.board[5][1] != "*" {
-		t.Fatalf("original board modified after mutating copy: expected '*' at [5][1], got %q", b.board[5][1])
+	if b.cell(5, 1) != PlayerOneColor {
+		t.Fatalf("original board modified after mutating copy: expected %q at [5][1], got %q", PlayerOneColor, b.cell(5, 1))
 	}
 	// the copy should have the new token somewhere above the original one
-	if copy.board[4][1] != "o" {
-		t.Fatalf("expected copy to have 'o' at [4][1], got %q", copy.board[4][1])
+	if copy.cell(4, 1) != PlayerTwoColor {
+		t.Fatalf("expected copy to have %q at [4][1], got %q", PlayerTwoColor, copy.cell(4, 1))
 	}
 }
 
 func TestNewBoardInitialization(t *testing.T) {
-	b := NewBoard()
+	b := newStandardBoard()
 	if b.movesMade != 0 {
 		t.Fatalf("expected movesMade == 0, got %d", b.movesMade)
 	}
@@ -172,17 +179,17 @@ func TestNewBoardInitialization(t *testing.T) {
 			t.Fatalf("expected col[%d] == 0, got %d", j, b.col[j])
 		}
 	}
-	for i := 0; i < len(b.board); i++ {
-		for j := 0; j < len(b.board[0]); j++ {
-			if b.board[i][j] != emptySpot {
-				t.Fatalf("expected board[%d][%d] == emptySpot, got %q", i, j, b.board[i][j])
+	for i := 0; i < boardHeight; i++ {
+		for j := 0; j < boardWidth; j++ {
+			if b.cell(i, j) != emptySpot {
+				t.Fatalf("expected cell(%d, %d) == emptySpot, got %q", i, j, b.cell(i, j))
 			}
 		}
 	}
 }
 
 func TestGameOverByMovesMade(t *testing.T) {
-	b := NewBoard()
+	b := newStandardBoard()
 	b.movesMade = 42
 	if !b.gameOver() {
 		t.Fatalf("expected gameOver to return true when movesMade == 42")
@@ -190,33 +197,33 @@ func TestGameOverByMovesMade(t *testing.T) {
 }
 
 func TestWhereConnectedSpecificToPlayer(t *testing.T) {
-	b := NewBoard()
-	b.board[5][0] = "*"
-	b.board[5][1] = "o"
-	b.board[5][2] = "*"
-	b.board[5][3] = "*"
-	b.board[5][4] = "*"
-	// there are not four contiguous '*' so this should be false
-	areConnected, _, _ := b.WhereConnected("*")
+	b := newStandardBoard()
+	setCell(b, 5, 0, PlayerOneColor)
+	setCell(b, 5, 1, PlayerTwoColor)
+	setCell(b, 5, 2, PlayerOneColor)
+	setCell(b, 5, 3, PlayerOneColor)
+	setCell(b, 5, 4, PlayerOneColor)
+	// there are not four contiguous PlayerOneColor tokens so this should be false
+	areConnected, _, _ := b.WhereConnected(PlayerOneColor)
 	if areConnected {
-		t.Fatalf("expected WhereConnected to be false for '*' since sequence is interrupted by 'o'")
+		t.Fatalf("expected WhereConnected to be false for PlayerOneColor since sequence is interrupted by PlayerTwoColor")
 	}
 }
 
 func TestAreFourConnectedDirect(t *testing.T) {
-	b := NewBoard()
-	if !b.Drop(0, "*") || !b.Drop(1, "*") || !b.Drop(2, "*") || !b.Drop(3, "*") {
+	b := newStandardBoard()
+	if !b.Drop(0, PlayerOneColor) || !b.Drop(1, PlayerOneColor) || !b.Drop(2, PlayerOneColor) || !b.Drop(3, PlayerOneColor) {
 		t.Fatalf("failed to place four tokens for AreFourConnected test")
 	}
-	if !b.areFourConnected("*") {
+	if !b.areFourConnected(PlayerOneColor) {
 		t.Fatalf("expected areFourConnected to return true for four in a row")
 	}
 }
 
 func TestPrintBoardOutput(t *testing.T) {
-	b := NewBoard()
+	b := newStandardBoard()
 	// place a token to ensure output contains a non-empty symbol
-	if !b.Drop(0, "*") {
+	if !b.Drop(0, PlayerOneColor) {
 		t.Fatalf("initial Drop failed")
 	}
 
@@ -242,10 +249,42 @@ func TestPrintBoardOutput(t *testing.T) {
 	if !strings.Contains(out, "0 1 2 3 4 5 6") {
 		t.Errorf("printBoard output missing column headers: %q", out)
 	}
-	if !strings.Contains(out, "*") {
-		t.Errorf("printBoard output missing placed token '*' : %q", out)
+	if !strings.Contains(out, PlayerOneColor) {
+		t.Errorf("printBoard output missing placed token %q : %q", PlayerOneColor, out)
 	}
 	if !strings.Contains(out, emptySpot) {
 		t.Errorf("printBoard output missing empty spot symbol %q: %q", emptySpot, out)
 	}
 }
+
+func TestDropUnknownPlayer(t *testing.T) {
+	b := newStandardBoard()
+	if b.Drop(0, "*") {
+		t.Fatalf("Drop should return false for an unknown player symbol")
+	}
+	if b.col[0] != 0 || b.movesMade != 0 {
+		t.Fatalf("unexpected state after rejected Drop: col[0]=%d movesMade=%d", b.col[0], b.movesMade)
+	}
+}
+
+func TestNoAlignmentAcrossColumns(t *testing.T) {
+	b := newStandardBoard()
+	// trois jetons en haut de la colonne 0 et un en bas de la colonne 1 :
+	// les bits sont consécutifs mais ne forment pas un alignement.
+	setCell(b, 0, 0, PlayerOneColor)
+	setCell(b, 1, 0, PlayerOneColor)
+	setCell(b, 2, 0, PlayerOneColor)
+	setCell(b, 5, 1, PlayerOneColor)
+	if b.areFourConnected(PlayerOneColor) {
+		t.Fatalf("expected no alignment wrapping from one column to the next")
+	}
+	// même chose pour la diagonale descendante qui longe le bord
+	b = newStandardBoard()
+	setCell(b, 3, 0, PlayerOneColor)
+	setCell(b, 4, 1, PlayerOneColor)
+	setCell(b, 5, 2, PlayerOneColor)
+	setCell(b, 0, 2, PlayerOneColor)
+	if b.areFourConnected(PlayerOneColor) {
+		t.Fatalf("expected no diagonal alignment wrapping across the board edge")
+	}
+}
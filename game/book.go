@@ -0,0 +1,242 @@
+package game
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// bookMagic et bookVersion identifient le format binaire d'une
+// bibliothèque d'ouvertures.
+const (
+	bookMagic   = "C4BK"
+	bookVersion = 1
+)
+
+// ErrInvalidBook est renvoyée lorsqu'un fichier n'est pas une bibliothèque
+// d'ouvertures valide.
+var ErrInvalidBook = errors.New("invalid opening book")
+
+// bookEntry associe la clé canonique d'une position au meilleur coup
+// (exprimé dans l'orientation canonique) et à son score.
+type bookEntry struct {
+	key   uint64
+	move  int
+	score int
+}
+
+// OpeningBook est une bibliothèque d'ouvertures : le meilleur coup connu
+// pour chaque position des premiers demi-coups d'une partie. Une position
+// et sa symétrique (colonnes inversées) partagent la même entrée.
+type OpeningBook struct {
+	plies   int
+	entries []bookEntry // triées par clé croissante
+}
+
+// BookOptions configure la construction d'une bibliothèque d'ouvertures.
+type BookOptions struct {
+	Plies       int                   // Les positions de moins de Plies coups sont couvertes
+	SearchDepth int                   // Profondeur de la recherche alpha-bêta pour chaque position (au moins 1)
+	Progress    func(done, total int) // Appelée après chaque position évaluée (peut être nil)
+	Seed        int64                 // Graine de l'ordre des coups : une même graine donne la même bibliothèque
+}
+
+// Plies renvoie le nombre de demi-coups couverts par la bibliothèque.
+func (bk *OpeningBook) Plies() int {
+	return bk.plies
+}
+
+// Len renvoie le nombre de positions de la bibliothèque.
+func (bk *OpeningBook) Len() int {
+	return len(bk.entries)
+}
+
+// mirrorColumns renvoie x (dans la disposition du solveur) avec l'ordre
+// des colonnes inversé.
+func mirrorColumns(x uint64) uint64 {
+	var m uint64
+	for c := 0; c < boardWidth; c++ {
+		col := (x >> (c * solverStride)) & (uint64(1)<<solverStride - 1)
+		m |= col << ((boardWidth - 1 - c) * solverStride)
+	}
+	return m
+}
+
+// canonicalKey renvoie la plus petite des clés de p et de sa symétrique,
+// et indique si c'est la symétrique qui a été retenue.
+func canonicalKey(p position) (key uint64, mirrored bool) {
+	key = p.key()
+	mirror := position{current: mirrorColumns(p.current), mask: mirrorColumns(p.mask)}
+	if mk := mirror.key(); mk < key {
+		return mk, true
+	}
+	return key, false
+}
+
+// Lookup renvoie le coup de la bibliothèque pour la position du plateau,
+// ok valant false si la position n'y figure pas.
+func (bk *OpeningBook) Lookup(b *Board) (column int, ok bool) {
+	p, err := positionFromBoard(b)
+	if err != nil || p.moves >= bk.plies {
+		return -1, false
+	}
+	key, mirrored := canonicalKey(p)
+	i := sort.Search(len(bk.entries), func(i int) bool { return bk.entries[i].key >= key })
+	if i == len(bk.entries) || bk.entries[i].key != key {
+		return -1, false
+	}
+	column = bk.entries[i].move
+	if mirrored {
+		column = boardWidth - 1 - column
+	}
+	return column, true
+}
+
+// BuildOpeningBook construit une bibliothèque couvrant toutes les positions
+// de moins de opts.Plies demi-coups, le meilleur coup de chacune étant
+// choisi par une recherche alpha-bêta de profondeur opts.SearchDepth (au
+// moins 1). La construction s'arrête, avec l'erreur de ctx, dès que ctx se
+// termine.
+func BuildOpeningBook(ctx context.Context, opts BookOptions) (*OpeningBook, error) {
+	if opts.Plies < 0 || opts.Plies > boardWidth*boardHeight {
+		return nil, fmt.Errorf("plies %d out of range", opts.Plies)
+	}
+	if opts.SearchDepth < 1 {
+		return nil, fmt.Errorf("search depth %d must be at least 1", opts.SearchDepth)
+	}
+	positions := map[uint64]*Board{}
+	collectBookPositions(newStandardBoard(), opts.Plies, positions)
+
+	keys := make([]uint64, 0, len(positions))
+	for key := range positions {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	cfg := searchConfig{
+		tt:  NewTranspositionTable(DefaultTableSize, ReplaceDepthPreferred),
+		rng: rand.New(rand.NewSource(opts.Seed)),
+	}
+	bk := &OpeningBook{plies: opts.Plies, entries: make([]bookEntry, 0, len(keys))}
+	for i, key := range keys {
+		b := positions[key]
+		player := PlayerOneColor
+		if b.movesMade%2 == 1 {
+			player = PlayerTwoColor
+		}
+		result := searchIterative(ctx, b, opts.SearchDepth, player, cfg)
+		// une recherche interrompue n'a pas atteint la profondeur demandée
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p, _ := positionFromBoard(b)
+		move := result.Move
+		if _, mirrored := canonicalKey(p); mirrored {
+			move = boardWidth - 1 - move
+		}
+		bk.entries = append(bk.entries, bookEntry{key: key, move: move, score: result.Score})
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(keys))
+		}
+	}
+	return bk, nil
+}
+
+// collectBookPositions ajoute à positions toutes les positions non
+// terminées de moins de plies coups atteignables depuis b, indexées par
+// leur clé canonique.
+func collectBookPositions(b *Board, plies int, positions map[uint64]*Board) {
+	if b.movesMade >= plies {
+		return
+	}
+	p, err := positionFromBoard(b)
+	if err != nil {
+		return
+	}
+	key, _ := canonicalKey(p)
+	if _, seen := positions[key]; seen {
+		return
+	}
+	positions[key] = b.copyOfBoard()
+	player := PlayerOneColor
+	if b.movesMade%2 == 1 {
+		player = PlayerTwoColor
+	}
+	for c := 0; c < boardWidth; c++ {
+		if b.Drop(c, player) {
+			collectBookPositions(b, plies, positions)
+			b.undoDrop(c)
+		}
+	}
+}
+
+// WriteTo écrit la bibliothèque au format binaire :
+//
+//	"C4BK" | version | largeur | hauteur | demi-coups | nombre d'entrées (uvarint)
+//	puis pour chaque entrée, par clé croissante :
+//	écart avec la clé précédente (uvarint) | coup (1 octet) | score (varint)
+func (bk *OpeningBook) WriteTo(w io.Writer) (int64, error) {
+	buf := []byte(bookMagic)
+	buf = append(buf, bookVersion, boardWidth, boardHeight, byte(bk.plies))
+	buf = binary.AppendUvarint(buf, uint64(len(bk.entries)))
+	var prev uint64
+	for _, e := range bk.entries {
+		buf = binary.AppendUvarint(buf, e.key-prev)
+		buf = append(buf, byte(e.move))
+		buf = binary.AppendVarint(buf, int64(e.score))
+		prev = e.key
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadOpeningBook lit une bibliothèque écrite par WriteTo.
+func ReadOpeningBook(r io.Reader) (*OpeningBook, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(bookMagic)+4)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBook, err)
+	}
+	if string(header[:len(bookMagic)]) != bookMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidBook)
+	}
+	version, width, height, plies := header[4], header[5], header[6], header[7]
+	if version != bookVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBook, version)
+	}
+	if width != boardWidth || height != boardHeight {
+		return nil, fmt.Errorf("%w: book is for a %dx%d board", ErrInvalidBook, width, height)
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBook, err)
+	}
+	bk := &OpeningBook{plies: int(plies)}
+	var key uint64
+	for i := uint64(0); i < count; i++ {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidBook, i, err)
+		}
+		move, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidBook, i, err)
+		}
+		score, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidBook, i, err)
+		}
+		if int(move) >= boardWidth || (i > 0 && delta == 0) {
+			return nil, fmt.Errorf("%w: entry %d is corrupted", ErrInvalidBook, i)
+		}
+		key += delta
+		bk.entries = append(bk.entries, bookEntry{key: key, move: int(move), score: int(score)})
+	}
+	return bk, nil
+}
@@ -0,0 +1,169 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// buildTestBook construit une petite bibliothèque pour les tests.
+func buildTestBook(t *testing.T) *OpeningBook {
+	t.Helper()
+	book, err := BuildOpeningBook(context.Background(), BookOptions{Plies: 4, SearchDepth: 4})
+	if err != nil {
+		t.Fatalf("BuildOpeningBook failed: %v", err)
+	}
+	return book
+}
+
+func TestOpeningBookCoversAllPositions(t *testing.T) {
+	book := buildTestBook(t)
+	if book.Plies() != 4 || book.Len() == 0 {
+		t.Fatalf("unexpected book: plies=%d len=%d", book.Plies(), book.Len())
+	}
+	var walk func(b *Board)
+	walk = func(b *Board) {
+		if b.movesMade >= 4 || b.gameOver() {
+			if _, ok := book.Lookup(b); ok {
+				t.Fatalf("position with %d moves should not be in the book", b.movesMade)
+			}
+			return
+		}
+		column, ok := book.Lookup(b)
+		if !ok {
+			t.Fatalf("position with %d moves missing from the book", b.movesMade)
+		}
+		if column < 0 || column >= boardWidth || b.col[column] >= boardHeight {
+			t.Fatalf("book move %d is not playable", column)
+		}
+		player := PlayerOneColor
+		if b.movesMade%2 == 1 {
+			player = PlayerTwoColor
+		}
+		for c := 0; c < boardWidth; c++ {
+			if b.Drop(c, player) {
+				walk(b)
+				b.undoDrop(c)
+			}
+		}
+	}
+	walk(newStandardBoard())
+}
+
+func TestOpeningBookMirroredPositionsShareEntries(t *testing.T) {
+	book := buildTestBook(t)
+	left, right := newStandardBoard(), newStandardBoard()
+	playMoves(t, left, 0, 1)
+	playMoves(t, right, boardWidth-1, boardWidth-2)
+
+	pl, _ := positionFromBoard(left)
+	pr, _ := positionFromBoard(right)
+	kl, _ := canonicalKey(pl)
+	kr, _ := canonicalKey(pr)
+	if kl != kr {
+		t.Fatalf("mirrored positions should share the same canonical key")
+	}
+	ml, _ := book.Lookup(left)
+	mr, _ := book.Lookup(right)
+	if ml != boardWidth-1-mr {
+		t.Fatalf("expected mirrored book moves, got %d and %d", ml, mr)
+	}
+}
+
+func TestOpeningBookRoundTrip(t *testing.T) {
+	book := buildTestBook(t)
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	// format compact : quelques octets par position
+	if buf.Len() > 8*book.Len()+16 {
+		t.Fatalf("book file is too large: %d bytes for %d entries", buf.Len(), book.Len())
+	}
+	read, err := ReadOpeningBook(&buf)
+	if err != nil {
+		t.Fatalf("ReadOpeningBook failed: %v", err)
+	}
+	if read.Plies() != book.Plies() || read.Len() != book.Len() {
+		t.Fatalf("book changed after round trip: plies %d/%d len %d/%d", read.Plies(), book.Plies(), read.Len(), book.Len())
+	}
+	for i := range book.entries {
+		if read.entries[i] != book.entries[i] {
+			t.Fatalf("entry %d differs after round trip: %+v vs %+v", i, read.entries[i], book.entries[i])
+		}
+	}
+}
+
+func TestReadOpeningBookRejectsGarbage(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("nope"),
+		[]byte("XXXX\x01\x07\x06\x04\x00"),
+		[]byte("C4BK\x02\x07\x06\x04\x00"),
+		[]byte("C4BK\x01\x08\x07\x04\x00"),
+		[]byte("C4BK\x01\x07\x06\x04\x02\x05\x01"),
+	} {
+		if _, err := ReadOpeningBook(bytes.NewReader(data)); !errors.Is(err, ErrInvalidBook) {
+			t.Errorf("expected ErrInvalidBook for %q, got %v", data, err)
+		}
+	}
+}
+
+func TestGameManagerPlaysBookMoves(t *testing.T) {
+	book := buildTestBook(t)
+	gm := NewGameManager(true, 9, WithOpeningBook(book))
+	if _, err := gm.MakePlayerTurn(3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, _ := book.Lookup(&gm.board)
+	column, err := gm.MakeOpponentTurn(-1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if column != want {
+		t.Fatalf("expected book move %d, got %d", want, column)
+	}
+	if gm.TTStats().Probes != 0 {
+		t.Fatalf("expected no search when the book has the position")
+	}
+}
+
+func TestSolverBeforeBook(t *testing.T) {
+	// le premier joueur gagne dans la colonne 0, la bibliothèque propose
+	// la colonne 6
+	b := newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 1)
+	p, _ := positionFromBoard(b)
+	key, mirrored := canonicalKey(p)
+	move := 6
+	if mirrored {
+		move = boardWidth - 1 - move
+	}
+	book := &OpeningBook{plies: 8, entries: []bookEntry{{key: key, move: move}}}
+
+	a := &AlphaBeta{Depth: 4, Book: book}
+	if m, err := a.BestMove(context.Background(), b, PlayerOneColor); err != nil || m.Column != 6 {
+		t.Fatalf("expected the book move without a solver, got %d (%v)", m.Column, err)
+	}
+	a.Solver = NewSolver(1 << 10)
+	if m, err := a.BestMove(context.Background(), b, PlayerOneColor); err != nil || m.Column != 0 {
+		t.Fatalf("expected the solver to override the book, got %d (%v)", m.Column, err)
+	}
+}
+
+func TestBuildOpeningBookErrors(t *testing.T) {
+	if _, err := BuildOpeningBook(context.Background(), BookOptions{Plies: 2, SearchDepth: 0}); err == nil {
+		t.Fatalf("expected a search depth of 0 to be refused")
+	}
+	// une construction annulée s'arrête pendant la recherche d'une position
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := BuildOpeningBook(ctx, BookOptions{Plies: 1, SearchDepth: 40}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("expected the search to stop with its context")
+	}
+}
@@ -0,0 +1,188 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeControl est la cadence d'une partie. Une cadence nulle ne limite pas
+// le temps de réflexion ; sinon, PerMove borne chaque coup et Base le temps
+// total de chaque joueur (« mort subite »), augmenté de Increment après
+// chacun de ses coups (cadence Fischer). Les deux limites peuvent se
+// cumuler.
+type TimeControl struct {
+	PerMove   time.Duration // Temps maximal par coup (0 : pas de limite par coup)
+	Base      time.Duration // Temps de chaque joueur pour la partie (0 : pas de limite)
+	Increment time.Duration // Temps ajouté à un joueur après chacun de ses coups (avec Base)
+}
+
+// ParseTimeControl lit une cadence : "none" (ou "") sans limite, "30s/move"
+// pour un temps par coup, "5m" pour une mort subite et "3m+2s" pour une
+// cadence Fischer. Une limite par coup peut s'ajouter au temps de partie,
+// par exemple "5m+2s,30s/move".
+func ParseTimeControl(s string) (TimeControl, error) {
+	var tc TimeControl
+	if s == "" || s == "none" {
+		return tc, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		var err error
+		if perMove, ok := strings.CutSuffix(part, "/move"); ok {
+			tc.PerMove, err = time.ParseDuration(perMove)
+		} else if base, inc, ok := strings.Cut(part, "+"); ok {
+			if tc.Base, err = time.ParseDuration(base); err == nil {
+				tc.Increment, err = time.ParseDuration(inc)
+			}
+		} else {
+			tc.Base, err = time.ParseDuration(part)
+		}
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: %v", s, err)
+		}
+	}
+	if err := tc.Check(); err != nil {
+		return TimeControl{}, err
+	}
+	return tc, nil
+}
+
+// String renvoie la cadence dans la notation de ParseTimeControl.
+func (tc TimeControl) String() string {
+	var parts []string
+	if tc.Base > 0 {
+		part := tc.Base.String()
+		if tc.Increment > 0 {
+			part += "+" + tc.Increment.String()
+		}
+		parts = append(parts, part)
+	}
+	if tc.PerMove > 0 {
+		parts = append(parts, tc.PerMove.String()+"/move")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ",")
+}
+
+// Unlimited indique si la cadence ne limite pas le temps de réflexion.
+func (tc TimeControl) Unlimited() bool {
+	return tc.PerMove <= 0 && tc.Base <= 0
+}
+
+// Check vérifie que les durées de la cadence sont positives et qu'un
+// incrément accompagne un temps de partie.
+func (tc TimeControl) Check() error {
+	if tc.PerMove < 0 || tc.Base < 0 || tc.Increment < 0 {
+		return fmt.Errorf("negative duration in time control %v", tc)
+	}
+	if tc.Increment > 0 && tc.Base == 0 {
+		return fmt.Errorf("time control %v: an increment needs a base time", tc)
+	}
+	return nil
+}
+
+// Clock est la pendule d'une partie à la cadence fournie à NewClock. Elle
+// n'a pas d'horloge propre : le temps avance par Advance, ce qui permet de
+// l'arrêter pendant les animations et de la faire tourner sur un temps
+// virtuel. Les joueurs sont numérotés 0 (premier joueur) et 1.
+type Clock struct {
+	tc     TimeControl
+	left   [2]time.Duration // Temps de partie restant à chaque joueur, hors coup en cours
+	spent  time.Duration    // Temps passé sur le coup en cours
+	toMove int              // Joueur au trait
+	paused bool
+}
+
+// NewClock crée une pendule à la cadence tc, en marche, le premier joueur
+// ayant le trait.
+func NewClock(tc TimeControl) *Clock {
+	c := &Clock{tc: tc}
+	c.Reset()
+	return c
+}
+
+// Reset remet la pendule dans son état de début de partie.
+func (c *Clock) Reset() {
+	c.left = [2]time.Duration{c.tc.Base, c.tc.Base}
+	c.spent, c.toMove, c.paused = 0, 0, false
+}
+
+// Control renvoie la cadence de la pendule.
+func (c *Clock) Control() TimeControl {
+	return c.tc
+}
+
+// Advance décompte d au joueur au trait, sauf si la pendule est arrêtée,
+// et indique si son temps est écoulé.
+func (c *Clock) Advance(d time.Duration) bool {
+	if !c.paused {
+		c.spent += d
+	}
+	return c.Expired()
+}
+
+// Pause arrête la pendule.
+func (c *Clock) Pause() {
+	c.paused = true
+}
+
+// Resume remet la pendule en marche.
+func (c *Clock) Resume() {
+	c.paused = false
+}
+
+// Paused indique si la pendule est arrêtée.
+func (c *Clock) Paused() bool {
+	return c.paused
+}
+
+// Press termine le coup du joueur au trait : le temps passé est retiré de
+// son temps de partie, l'incrément lui est ajouté et l'autre joueur prend
+// le trait.
+func (c *Clock) Press() {
+	if c.tc.Base > 0 {
+		c.left[c.toMove] += c.tc.Increment - c.spent
+	}
+	c.spent = 0
+	c.toMove ^= 1
+}
+
+// SetToMove donne le trait au joueur player sans décompter le coup en
+// cours, par exemple après une annulation.
+func (c *Clock) SetToMove(player int) {
+	c.spent = 0
+	c.toMove = player
+}
+
+// ToMove renvoie le joueur au trait.
+func (c *Clock) ToMove() int {
+	return c.toMove
+}
+
+// Left renvoie le temps restant au joueur player : son temps de partie,
+// borné pour le joueur au trait par le temps restant pour son coup. Sans
+// limite (TimeControl.Unlimited), Left renvoie 0.
+func (c *Clock) Left(player int) time.Duration {
+	if c.tc.Unlimited() {
+		return 0
+	}
+	var spent time.Duration
+	if player == c.toMove {
+		spent = c.spent
+	}
+	left := c.left[player] - spent
+	if c.tc.PerMove > 0 && (c.tc.Base == 0 || c.tc.PerMove-spent < left) {
+		left = c.tc.PerMove - spent
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Expired indique si le temps du joueur au trait est écoulé.
+func (c *Clock) Expired() bool {
+	return !c.tc.Unlimited() && c.Left(c.toMove) <= 0
+}
@@ -0,0 +1,137 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want TimeControl
+	}{
+		{"", TimeControl{}},
+		{"none", TimeControl{}},
+		{"59s/move", TimeControl{PerMove: 59 * time.Second}},
+		{"5m", TimeControl{Base: 5 * time.Minute}},
+		{"3m+2s", TimeControl{Base: 3 * time.Minute, Increment: 2 * time.Second}},
+		{"5m+2s,30s/move", TimeControl{PerMove: 30 * time.Second, Base: 5 * time.Minute, Increment: 2 * time.Second}},
+	} {
+		tc, err := ParseTimeControl(c.s)
+		if err != nil || tc != c.want {
+			t.Fatalf("ParseTimeControl(%q) = %+v, %v; expected %+v", c.s, tc, err, c.want)
+		}
+		if again, _ := ParseTimeControl(tc.String()); again != tc {
+			t.Fatalf("String of %+v does not round-trip: %q", tc, tc.String())
+		}
+	}
+	for _, s := range []string{"5", "x/move", "3m+", "-1s", "+2s", "5m,-1s/move"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Fatalf("expected ParseTimeControl(%q) to fail", s)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	// temps par coup : chaque coup repart de la limite
+	c := NewClock(TimeControl{PerMove: 10 * time.Second})
+	if c.Advance(9*time.Second) || c.Left(0) != time.Second || c.Left(1) != 10*time.Second {
+		t.Fatalf("unexpected per-move clock: %v %v", c.Left(0), c.Left(1))
+	}
+	c.Press()
+	if c.ToMove() != 1 || c.Left(0) != 10*time.Second {
+		t.Fatalf("expected the second player to move with a fresh limit")
+	}
+	c.Pause()
+	if c.Advance(time.Hour) || c.Left(1) != 10*time.Second {
+		t.Fatalf("expected a paused clock not to run")
+	}
+	c.Resume()
+	if !c.Advance(10 * time.Second) {
+		t.Fatalf("expected the per-move limit to expire")
+	}
+
+	// cadence Fischer : le temps passé est retiré, l'incrément ajouté
+	c = NewClock(TimeControl{Base: time.Minute, Increment: 5 * time.Second})
+	c.Advance(20 * time.Second)
+	c.Press()
+	if c.Left(0) != 45*time.Second || c.Left(1) != time.Minute {
+		t.Fatalf("unexpected Fischer times %v %v", c.Left(0), c.Left(1))
+	}
+	c.SetToMove(0)
+	if c.Advance(44*time.Second) || !c.Advance(time.Second) {
+		t.Fatalf("expected the first player to lose after 45s")
+	}
+	c.Reset()
+	if c.ToMove() != 0 || c.Left(0) != time.Minute || c.Expired() {
+		t.Fatalf("expected Reset to restore the base time")
+	}
+
+	// mort subite avec limite par coup : la plus courte l'emporte
+	c = NewClock(TimeControl{Base: 15 * time.Second, PerMove: 10 * time.Second})
+	c.Advance(8 * time.Second)
+	c.Press()
+	c.Press()
+	if c.Left(0) != 7*time.Second {
+		t.Fatalf("expected the base time to bound the move, got %v", c.Left(0))
+	}
+
+	if c := NewClock(TimeControl{}); c.Advance(time.Hour) || c.Expired() {
+		t.Fatalf("expected an unlimited clock never to expire")
+	}
+}
+
+func TestTimeOut(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	gm.MakePlayerTurn(3)
+	events, cancel := gm.Subscribe()
+	defer cancel()
+	if !gm.TimeOut(false) || gm.GetState() != Lose || !gm.TimedOut() || gm.GetLostGames() != 1 {
+		t.Fatalf("expected a loss on time, got %v", gm.GetState())
+	}
+	if gm.Winner() != PlayerOneColor {
+		t.Fatalf("expected the player not to move to win, got %q", gm.Winner())
+	}
+	if ev := <-events; ev.Kind != GameWon || ev.Player != PlayerOneColor || ev.State != Lose {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if gm.TimeOut(false) {
+		t.Fatalf("expected a finished game not to time out again")
+	}
+	gm.ResetGame()
+	if gm.TimedOut() || gm.Winner() != "" || gm.GetLostGames() != 1 {
+		t.Fatalf("expected ResetGame to clear the timeout but keep the score")
+	}
+
+	// les coups annulés ne sont pas rejoués dans une partie perdue au temps
+	gm = NewGameManager(false, 0)
+	for _, c := range []int{0, 1, 0, 1, 0, 1, 0} {
+		gm.MakePlayerTurn(c)
+	}
+	gm.Undo()
+	gm.TimeOut(false)
+	if gm.Redo() || len(gm.History()) != 6 || gm.GetWonGames() != 0 || gm.GetLostGames() != 1 {
+		t.Fatalf("expected no redo after a loss on time, got %q (%d:%d)", gm.MoveString(), gm.GetWonGames(), gm.GetLostGames())
+	}
+
+	// l'IA perd au temps pendant sa réflexion : son coup n'est pas joué
+	e := blockingEngine{make(chan struct{}), make(chan struct{})}
+	gm = NewGameManager(true, 1, WithEngine(e))
+	gm.MakePlayerTurn(3)
+	errc := make(chan error)
+	go func() {
+		_, err := gm.MakeOpponentTurnContext(context.Background(), -1)
+		errc <- err
+	}()
+	<-e.started
+	gm.TimeOut(true)
+	close(e.release)
+	if err := <-errc; !errors.Is(err, ErrPositionChanged) {
+		t.Fatalf("expected ErrPositionChanged, got %v", err)
+	}
+	if gm.GetState() != Win || gm.GetWonGames() != 1 || len(gm.History()) != 1 {
+		t.Fatalf("expected the player to win on time, got %v after %q", gm.GetState(), gm.MoveString())
+	}
+}
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Engine choisit les coups de l'IA. Le GameManager utilise AlphaBeta par
+// défaut ; WithEngine permet de le remplacer, par exemple par MCTS.
+type Engine interface {
+	// BestMove renvoie le coup que player doit jouer sur b, sans modifier
+	// b : un dépôt, ou un retrait si b suit les règles PopOut. Seuls les
+	// champs Column, Kind et Player du coup sont renseignés. À l'échéance
+	// de ctx, le moteur renvoie le meilleur coup trouvé jusque-là lorsqu'il
+	// en a un.
+	BestMove(ctx context.Context, b *Board, player string) (Move, error)
+}
+
+// HistoryEngine est implémenté par les moteurs qui ont besoin des coups
+// menant à la position, comme un moteur externe auquel la position est
+// transmise par sa liste de coups : le GameManager leur fournit alors son
+// historique.
+type HistoryEngine interface {
+	Engine
+	// BestMoveAfter est BestMove pour la position b atteinte par les coups
+	// history, du premier au dernier.
+	BestMoveAfter(ctx context.Context, b *Board, history []Move, player string) (Move, error)
+}
+
+// SearchInfo décrit une itération terminée de la recherche alpha-bêta
+// (voir AlphaBeta.Info).
+type SearchInfo struct {
+	Depth int    // Profondeur de l'itération, en demi-coups
+	Score int    // Valeur de la position pour le joueur au trait (heuristique sans fin de partie forcée)
+	Mate  int    // Victoire forcée en Mate demi-coups si positif, défaite en -Mate demi-coups si négatif, 0 sinon
+	Nodes uint64 // Nombre de positions visitées depuis le début de la recherche
+	PV    []Move // Variante principale : les meilleurs coups des deux joueurs à partir de la position
+}
+
+// seededEngine est implémenté par les moteurs du paquet dont les coups
+// dépendent d'une source aléatoire : le GameManager leur fournit la sienne,
+// initialisée avec la graine de la partie.
+type seededEngine interface {
+	setRand(r *rand.Rand)
+}
+
+// AlphaBeta est le moteur historique de l'IA : une recherche alpha-bêta
+// par approfondissement itératif, précédée de la bibliothèque d'ouvertures
+// et du solveur exact lorsqu'ils sont configurés.
+//
+// Avec Workers > 1, les goroutines partagent Table et Evaluator, qui doit
+// alors pouvoir être appelé de façon concurrente. Une recherche d'un seul
+// worker dont Rand a une graine fixe joue toujours les mêmes coups à partir
+// d'une table vide.
+type AlphaBeta struct {
+	Depth     int                 // Profondeur maximale de la recherche (0 : jusqu'à la fin de la partie)
+	ThinkTime time.Duration       // Temps de réflexion par coup (0 : profondeur fixe Depth)
+	Table     *TranspositionTable // Table de transposition (nil : aucune position mémorisée)
+	Evaluator Evaluator           // Évaluation à l'horizon (nil : PositionalEvaluator avec DefaultWeights)
+	Solver    *Solver             // Solveur exact essayé avant la recherche (peut être nil)
+	SolveTime time.Duration       // Temps laissé au solveur (0 : la moitié de ThinkTime, ou DefaultSolveTime sans ThinkTime)
+	Book      *OpeningBook        // Bibliothèque d'ouvertures consultée en premier, ou après le solveur s'il n'aboutit pas (peut être nil)
+	Workers   int                 // Nombre de goroutines de la recherche (≤ 1 : recherche séquentielle)
+	Rand      *rand.Rand          // Source de l'ordre aléatoire des coups (nil : générateur global)
+	Info      func(SearchInfo)    // Appelée après chaque itération de la recherche (peut être nil)
+}
+
+// DefaultSolveTime est le temps laissé au solveur exact d'AlphaBeta quand
+// ni SolveTime ni ThinkTime ne le bornent : sans lui, la résolution d'une
+// ouverture prendrait plusieurs minutes.
+const DefaultSolveTime = 5 * time.Second
+
+// NewAlphaBeta crée un moteur alpha-bêta de profondeur depth disposant
+// d'une table de transposition de taille DefaultTableSize.
+func NewAlphaBeta(depth int) *AlphaBeta {
+	return &AlphaBeta{
+		Depth: depth,
+		Table: NewTranspositionTable(DefaultTableSize, ReplaceDepthPreferred),
+	}
+}
+
+// BestMove implémente Engine. Lorsque ThinkTime est non nul, la recherche
+// s'approfondit jusqu'à l'échéance. Le solveur passe avant la bibliothèque
+// d'ouvertures, dont les coups ne sont pas exacts ; s'il n'aboutit pas dans
+// le temps qui lui est laissé (voir SolveTime), le coup vient de la
+// bibliothèque, ou de la recherche alpha-bêta dans le reste du temps de
+// réflexion ou à la profondeur Depth.
+func (a *AlphaBeta) BestMove(ctx context.Context, b *Board, player string) (Move, error) {
+	if b.gameOver() || !b.hasMove(player) {
+		return Move{Column: -1}, ErrGameOver
+	}
+	if a.Book != nil && a.Solver == nil {
+		if column, ok := a.Book.Lookup(b); ok {
+			return Move{Column: column, Player: player}, nil
+		}
+	}
+	maxDepth := a.Depth
+	if a.ThinkTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.ThinkTime)
+		defer cancel()
+		maxDepth = 0
+	}
+	if a.Solver != nil {
+		solveTime := a.SolveTime
+		switch {
+		case solveTime > 0:
+		case a.ThinkTime > 0:
+			solveTime = a.ThinkTime / 2
+		default:
+			solveTime = DefaultSolveTime
+		}
+		solveCtx, cancel := context.WithTimeout(ctx, solveTime)
+		column, err := a.Solver.BestMove(solveCtx, b)
+		cancel()
+		if err == nil {
+			return Move{Column: column, Player: player}, nil
+		}
+		// les coups de la bibliothèque, choisis par une recherche
+		// heuristique, ne remplacent que le solveur qui n'a pas abouti
+		if a.Book != nil {
+			if column, ok := a.Book.Lookup(b); ok {
+				return Move{Column: column, Player: player}, nil
+			}
+		}
+	}
+	cfg := searchConfig{tt: a.Table, eval: a.Evaluator, workers: a.Workers, rng: a.Rand, info: a.Info}
+	return b.searchMove(searchIterative(ctx, b, maxDepth, player, cfg).Move, player), nil
+}
+
+// setRand implémente seededEngine.
+func (a *AlphaBeta) setRand(r *rand.Rand) {
+	a.Rand = r
+}
@@ -0,0 +1,115 @@
+package game
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAlphaBetaInfo(t *testing.T) {
+	// PlayerOneColor aligne trois jetons en bas du plateau : il gagne au
+	// coup suivant
+	b := newStandardBoard()
+	for i, c := range []int{1, 1, 2, 2, 3, 3} {
+		b.Drop(c, []string{PlayerOneColor, PlayerTwoColor}[i%2])
+	}
+	var infos []SearchInfo
+	a := NewAlphaBeta(6)
+	a.Rand = rand.New(rand.NewSource(1))
+	a.Info = func(i SearchInfo) { infos = append(infos, i) }
+	move, err := a.BestMove(context.Background(), b, PlayerOneColor)
+	if err != nil {
+		t.Fatalf("BestMove: %v", err)
+	}
+	if len(infos) == 0 {
+		t.Fatalf("expected Info to be called")
+	}
+	last := infos[len(infos)-1]
+	if last.Mate != 1 || last.Nodes == 0 || len(last.PV) != 1 || last.PV[0] != move {
+		t.Fatalf("unexpected last info %+v for move %+v", last, move)
+	}
+
+	// sans victoire immédiate, la variante principale alterne les joueurs
+	infos = nil
+	a = NewAlphaBeta(5)
+	a.Info = func(i SearchInfo) { infos = append(infos, i) }
+	a.BestMove(context.Background(), newStandardBoard(), PlayerOneColor)
+	if len(infos) != 5 {
+		t.Fatalf("expected one info per depth, got %d", len(infos))
+	}
+	for i, info := range infos {
+		if info.Depth != i+1 || info.Mate != 0 || len(info.PV) == 0 || len(info.PV) > info.Depth {
+			t.Fatalf("unexpected info %+v", info)
+		}
+		if i > 0 && info.Nodes < infos[i-1].Nodes {
+			t.Fatalf("expected the node count to grow, got %d then %d", infos[i-1].Nodes, info.Nodes)
+		}
+		for j, m := range info.PV {
+			if want := []string{PlayerOneColor, PlayerTwoColor}[j%2]; m.Player != want {
+				t.Fatalf("move %d of the PV %+v played by the wrong player", j, info.PV)
+			}
+		}
+	}
+}
+
+func TestAlphaBetaSolveTime(t *testing.T) {
+	// le solveur ne résout pas l'ouverture dans le temps qui lui est laissé :
+	// le coup vient de la recherche, à la profondeur Depth
+	b := newStandardBoard()
+	playMoves(t, b, 3)
+	for _, a := range []*AlphaBeta{
+		{Depth: 4, Solver: NewSolver(1 << 16), SolveTime: 20 * time.Millisecond},
+		{Depth: 4, Solver: NewSolver(1 << 16), ThinkTime: 100 * time.Millisecond},
+	} {
+		start := time.Now()
+		move, err := a.BestMove(context.Background(), b, PlayerTwoColor)
+		if err != nil || move.Column < 0 || move.Column >= boardWidth {
+			t.Fatalf("BestMove: %+v (%v)", move, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("expected the solver to be bounded, took %v", elapsed)
+		}
+	}
+}
+
+// historyEngine est un moteur qui enregistre les coups qu'il reçoit et
+// joue dans la première colonne libre.
+type historyEngine struct {
+	history []Move
+}
+
+func (e *historyEngine) BestMove(ctx context.Context, b *Board, player string) (Move, error) {
+	for c := 0; c < b.Width(); c++ {
+		if b.canPlay(c) {
+			return Move{Column: c, Player: player}, nil
+		}
+	}
+	return Move{Column: -1}, ErrGameOver
+}
+
+func (e *historyEngine) BestMoveAfter(ctx context.Context, b *Board, history []Move, player string) (Move, error) {
+	e.history = history
+	return e.BestMove(ctx, b, player)
+}
+
+func TestHistoryEngine(t *testing.T) {
+	e := &historyEngine{}
+	gm := Settings{AI: true, Opponent: e}.NewGameManager()
+	gm.MakePlayerTurn(3)
+	gm.MakeOpponentTurn(-1)
+	gm.MakePlayerTurn(4)
+	gm.MakeOpponentTurn(-1)
+	if got := gm.MoveString(); got != "4151" {
+		t.Fatalf("unexpected game %q", got)
+	}
+	want := []Move{
+		{Column: 3, Player: PlayerOneColor},
+		{Column: 0, Player: PlayerTwoColor, Turn: 1, Opponent: true},
+		{Column: 4, Player: PlayerOneColor, Turn: 2},
+	}
+	if !slices.Equal(e.history, want) {
+		t.Fatalf("unexpected history given to the engine: %+v", e.history)
+	}
+}
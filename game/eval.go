@@ -0,0 +1,135 @@
+package game
+
+import "math/bits"
+
+// Evaluator attribue un score heuristique à une position non terminale,
+// du point de vue du joueur fourni : plus le score est élevé, meilleure
+// est la position pour ce joueur. Le score doit rester très inférieur au
+// score d'une victoire (big).
+type Evaluator interface {
+	Evaluate(b *Board, player string) int
+}
+
+// EvaluatorFunc permet d'utiliser une simple fonction comme Evaluator.
+type EvaluatorFunc func(b *Board, player string) int
+
+// Evaluate appelle f(b, player).
+func (f EvaluatorFunc) Evaluate(b *Board, player string) int {
+	return f(b, player)
+}
+
+// ZeroEvaluator évalue toutes les positions à 0 : l'IA ne distingue alors
+// que les victoires et défaites visibles dans sa profondeur de recherche.
+type ZeroEvaluator struct{}
+
+// Evaluate renvoie toujours 0.
+func (ZeroEvaluator) Evaluate(*Board, string) int {
+	return 0
+}
+
+// Weights regroupe les poids de PositionalEvaluator. Chaque critère est
+// compté pour le joueur puis retranché pour son adversaire.
+type Weights struct {
+	OpenThree   int // Par alignement possible auquel il ne manque qu'un jeton (trois jetons et une case libre au Puissance 4)
+	OpenTwo     int // Par alignement possible auquel il manque deux jetons
+	Center      int // Par jeton dans la colonne centrale (les deux colonnes centrales si la largeur est paire)
+	ParityThree int // Par case menaçante sur une rangée favorable (impaire pour le premier joueur, paire pour le second)
+}
+
+// DefaultWeights sont les poids utilisés par défaut par l'IA.
+var DefaultWeights = Weights{
+	OpenThree:   50,
+	OpenTwo:     5,
+	Center:      4,
+	ParityThree: 30,
+}
+
+// PositionalEvaluator évalue une position d'après les alignements encore
+// réalisables, l'occupation de la colonne centrale et la parité des
+// menaces : le premier joueur (PlayerOneColor) profite des menaces sur les
+// rangées impaires (1, 3, 5 depuis le bas), le second des rangées paires.
+type PositionalEvaluator struct {
+	Weights Weights
+}
+
+// NewPositionalEvaluator crée un évaluateur positionnel avec les poids
+// fournis.
+func NewPositionalEvaluator(w Weights) *PositionalEvaluator {
+	return &PositionalEvaluator{Weights: w}
+}
+
+// defaultEvaluator est l'évaluateur utilisé lorsque aucun n'est configuré.
+var defaultEvaluator Evaluator = NewPositionalEvaluator(DefaultWeights)
+
+// Evaluate renvoie le score de la position pour player.
+func (e *PositionalEvaluator) Evaluate(b *Board, player string) int {
+	p := playerIndex(player)
+	if p < 0 {
+		return 0
+	}
+	return e.score(b, p) - e.score(b, 1-p)
+}
+
+// score calcule la partie du score revenant au joueur d'indice p.
+func (e *PositionalEvaluator) score(b *Board, p int) int {
+	g := b.geo
+	own := b.players[p]
+	empty := g.full &^ (b.players[0] | b.players[1])
+	threes, twos := g.openWindows(own, empty)
+
+	favourable := g.oddRows
+	if p == 1 {
+		favourable = g.full &^ g.oddRows
+	}
+	parity := bits.OnesCount64(g.threatCells(own, empty) & favourable)
+
+	return e.Weights.OpenThree*threes +
+		e.Weights.OpenTwo*twos +
+		e.Weights.Center*bits.OnesCount64(own&g.center) +
+		e.Weights.ParityThree*parity
+}
+
+// openWindows compte les alignements possibles (fenêtres de connect cases
+// sans jeton adverse) contenant exactement une case libre (threes) ou
+// exactement deux cases libres (twos).
+func (g *geometry) openWindows(own, empty uint64) (threes, twos int) {
+	for dir, shift := range g.directions {
+		for i := 0; i < g.connect; i++ {
+			m := g.runStarts[dir] & (empty >> (i * shift))
+			for k := 0; k < g.connect; k++ {
+				if k != i {
+					m &= own >> (k * shift)
+				}
+			}
+			threes += bits.OnesCount64(m)
+			for j := i + 1; j < g.connect; j++ {
+				m := g.runStarts[dir] & (empty >> (i * shift)) & (empty >> (j * shift))
+				for k := 0; k < g.connect; k++ {
+					if k != i && k != j {
+						m &= own >> (k * shift)
+					}
+				}
+				twos += bits.OnesCount64(m)
+			}
+		}
+	}
+	return threes, twos
+}
+
+// threatCells renvoie les cases libres qui compléteraient un alignement
+// de own si un jeton y était placé (sans tenir compte de la gravité).
+func (g *geometry) threatCells(own, empty uint64) uint64 {
+	var threats uint64
+	for dir, shift := range g.directions {
+		for i := 0; i < g.connect; i++ {
+			m := g.runStarts[dir] & (empty >> (i * shift))
+			for k := 0; k < g.connect; k++ {
+				if k != i {
+					m &= own >> (k * shift)
+				}
+			}
+			threats |= m << (i * shift)
+		}
+	}
+	return threats
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPositionalEvaluatorEmptyBoardIsEven(t *testing.T) {
+	e := NewPositionalEvaluator(DefaultWeights)
+	if v := e.Evaluate(newStandardBoard(), PlayerOneColor); v != 0 {
+		t.Fatalf("expected empty board to evaluate to 0, got %d", v)
+	}
+}
+
+func TestPositionalEvaluatorIsAntisymmetric(t *testing.T) {
+	e := NewPositionalEvaluator(DefaultWeights)
+	b := newStandardBoard()
+	for i, c := range []int{3, 2, 3, 4, 1, 3} {
+		player := PlayerOneColor
+		if i%2 == 1 {
+			player = PlayerTwoColor
+		}
+		b.Drop(c, player)
+	}
+	one, two := e.Evaluate(b, PlayerOneColor), e.Evaluate(b, PlayerTwoColor)
+	if one != -two {
+		t.Fatalf("expected scores to be opposite, got %d and %d", one, two)
+	}
+	if e.Evaluate(b, "*") != 0 {
+		t.Fatalf("expected 0 for an unknown player")
+	}
+}
+
+func TestPositionalEvaluatorPrefersCenter(t *testing.T) {
+	e := NewPositionalEvaluator(DefaultWeights)
+	center, edge := newStandardBoard(), newStandardBoard()
+	center.Drop(boardWidth/2, PlayerOneColor)
+	edge.Drop(0, PlayerOneColor)
+	if e.Evaluate(center, PlayerOneColor) <= e.Evaluate(edge, PlayerOneColor) {
+		t.Fatalf("expected a centre disc to be worth more than an edge disc")
+	}
+}
+
+func TestOpenWindowsCountsThreesAndTwos(t *testing.T) {
+	b := newStandardBoard()
+	b.Drop(0, PlayerOneColor)
+	b.Drop(1, PlayerOneColor)
+	b.Drop(2, PlayerOneColor)
+	empty := b.geo.full &^ (b.players[0] | b.players[1])
+
+	threes, _ := b.geo.openWindows(b.players[0], empty)
+	if threes != 1 {
+		t.Fatalf("expected one open three on the bottom row, got %d", threes)
+	}
+	threats := b.geo.threatCells(b.players[0], empty)
+	if threats != b.geo.cellBit(5, 3) {
+		t.Fatalf("expected the only threat to be at row 5 column 3, got %b", threats)
+	}
+}
+
+func TestPositionalEvaluatorThreatParity(t *testing.T) {
+	// même menace (case (ligne 4 depuis le haut, colonne 3), soit la
+	// deuxième rangée depuis le bas) : favorable au second joueur seulement.
+	w := Weights{ParityThree: 1}
+	e := NewPositionalEvaluator(w)
+	b := newStandardBoard()
+	setCell(b, 4, 0, PlayerOneColor)
+	setCell(b, 4, 1, PlayerOneColor)
+	setCell(b, 4, 2, PlayerOneColor)
+	if v := e.Evaluate(b, PlayerOneColor); v != 0 {
+		t.Fatalf("an even-row threat should not count for the first player, got %d", v)
+	}
+	b = newStandardBoard()
+	setCell(b, 4, 0, PlayerTwoColor)
+	setCell(b, 4, 1, PlayerTwoColor)
+	setCell(b, 4, 2, PlayerTwoColor)
+	if v := e.Evaluate(b, PlayerTwoColor); v != 1 {
+		t.Fatalf("an even-row threat should count for the second player, got %d", v)
+	}
+}
+
+func TestGameManagerWithEvaluator(t *testing.T) {
+	calls := 0
+	counting := EvaluatorFunc(func(b *Board, player string) int {
+		calls++
+		return 0
+	})
+	gm := NewGameManager(true, 3, WithEvaluator(counting))
+	if _, err := gm.MakeOpponentTurn(-1); err != nil {
+		t.Fatalf("unexpected error from AI opponent: %v", err)
+	}
+	if calls == 0 {
+		t.Fatalf("expected the configured evaluator to be used by the search")
+	}
+}
+
+// playEvaluatorMatch fait jouer une partie entre deux évaluateurs à
+// profondeur fixe, l'ordre des coups étant tiré de rng, et renvoie le
+// symbole du gagnant ("" en cas de nul).
+func playEvaluatorMatch(first, second Evaluator, depth int, rng *rand.Rand) string {
+	b := newStandardBoard()
+	evals := map[string]Evaluator{PlayerOneColor: first, PlayerTwoColor: second}
+	player := PlayerOneColor
+	for !b.gameOver() {
+		s := &searcher{searchConfig: searchConfig{eval: evals[player], rng: rng}}
+		_, move := s.alphabeta(b, player == PlayerTwoColor, 0, small, big, depth)
+		b.Drop(move, player)
+		if player == PlayerOneColor {
+			player = PlayerTwoColor
+		} else {
+			player = PlayerOneColor
+		}
+	}
+	switch {
+	case b.areFourConnected(PlayerOneColor):
+		return PlayerOneColor
+	case b.areFourConnected(PlayerTwoColor):
+		return PlayerTwoColor
+	}
+	return ""
+}
+
+func TestPositionalEvaluatorBeatsZeroEvaluator(t *testing.T) {
+	positional := NewPositionalEvaluator(DefaultWeights)
+	wins, losses := 0, 0
+	for game := 0; game < 4; game++ {
+		// une graine fixe par partie rend le match reproductible
+		rng := rand.New(rand.NewSource(int64(game + 1)))
+		var winner string
+		if game%2 == 0 {
+			winner = playEvaluatorMatch(positional, ZeroEvaluator{}, 4, rng)
+			if winner == PlayerOneColor {
+				wins++
+			} else if winner == PlayerTwoColor {
+				losses++
+			}
+		} else {
+			winner = playEvaluatorMatch(ZeroEvaluator{}, positional, 4, rng)
+			if winner == PlayerTwoColor {
+				wins++
+			} else if winner == PlayerOneColor {
+				losses++
+			}
+		}
+	}
+	t.Logf("positional vs zero: %d wins, %d losses", wins, losses)
+	if wins <= losses {
+		t.Fatalf("expected the positional evaluator to win the match, got %d wins and %d losses", wins, losses)
+	}
+}
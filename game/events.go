@@ -0,0 +1,94 @@
+package game
+
+// EventKind distingue les événements d'une partie.
+type EventKind int
+
+const (
+	MoveMade    EventKind = iota // Un coup a été joué (ou rejoué par Redo)
+	MoveUndone                   // Un coup a été annulé par Undo
+	GameWon                      // La partie est gagnée : Player est le gagnant, State vaut Win ou Lose
+	GameTied                     // La partie est nulle
+	GameReset                    // Une nouvelle partie commence (ResetGame)
+	TurnChanged                  // Player a le trait, la partie continuant
+)
+
+// String renvoie le nom de la sorte d'événement.
+func (k EventKind) String() string {
+	switch k {
+	case MoveMade:
+		return "move"
+	case MoveUndone:
+		return "undo"
+	case GameWon:
+		return "won"
+	case GameTied:
+		return "tie"
+	case GameReset:
+		return "reset"
+	case TurnChanged:
+		return "turn"
+	}
+	return "unknown"
+}
+
+// Event est un changement d'une partie, diffusé aux abonnés (voir
+// Subscribe). Un coup est suivi de l'événement GameWon, GameTied ou
+// TurnChanged qui en résulte ; une annulation est suivie de TurnChanged.
+type Event struct {
+	Kind   EventKind
+	Move   Move      // Coup joué ou annulé (MoveMade, MoveUndone)
+	Player string    // Gagnant (GameWon) ou joueur au trait (TurnChanged)
+	Turn   int       // Nombre de coups joués après l'événement
+	State  GameState // État de la partie après l'événement
+}
+
+// eventBuffer est le nombre d'événements mis en attente pour un abonné ;
+// au-delà, l'abonné est désabonné plutôt que de bloquer la partie.
+const eventBuffer = 64
+
+// Subscribe abonne l'appelant aux événements de la partie, dans l'ordre où
+// ils se produisent. Le canal est fermé par cancel, ou si l'abonné ne lit
+// pas assez vite ses événements : la partie n'attend jamais ses abonnés.
+func (gm *GameManager) Subscribe() (events <-chan Event, cancel func()) {
+	ch := make(chan Event, eventBuffer)
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if gm.subs == nil {
+		gm.subs = make(map[chan Event]struct{})
+	}
+	gm.subs[ch] = struct{}{}
+	return ch, func() {
+		gm.mu.Lock()
+		defer gm.mu.Unlock()
+		if _, ok := gm.subs[ch]; ok {
+			close(ch)
+			delete(gm.subs, ch)
+		}
+	}
+}
+
+// emit diffuse ev aux abonnés. gm.mu doit être verrouillé en écriture.
+func (gm *GameManager) emit(ev Event) {
+	ev.Turn, ev.State = len(gm.history), gm.state
+	for ch := range gm.subs {
+		select {
+		case ch <- ev:
+		default:
+			close(ch)
+			delete(gm.subs, ch)
+		}
+	}
+}
+
+// emitOutcome diffuse la fin de la partie, ou le joueur au trait si elle
+// continue. gm.mu doit être verrouillé en écriture.
+func (gm *GameManager) emitOutcome() {
+	switch gm.state {
+	case Running:
+		gm.emit(Event{Kind: TurnChanged, Player: gm.currentToken()})
+	case Tie:
+		gm.emit(Event{Kind: GameTied})
+	default:
+		gm.emit(Event{Kind: GameWon, Player: gm.winner})
+	}
+}
@@ -0,0 +1,186 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// drain renvoie les événements en attente sur events, jusqu'à sa
+// fermeture éventuelle.
+func drain(events <-chan Event) []Event {
+	var evs []Event
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return evs
+			}
+			evs = append(evs, ev)
+		default:
+			return evs
+		}
+	}
+}
+
+// kinds renvoie les sortes des événements evs.
+func kinds(evs []Event) []EventKind {
+	var ks []EventKind
+	for _, ev := range evs {
+		ks = append(ks, ev.Kind)
+	}
+	return ks
+}
+
+func TestSubscribe(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	events, cancel := gm.Subscribe()
+
+	gm.MakePlayerTurn(3)
+	evs := drain(events)
+	if len(evs) != 2 || evs[0].Kind != MoveMade || evs[0].Move.Column != 3 || evs[0].Turn != 1 {
+		t.Fatalf("unexpected events for the first move: %+v", evs)
+	}
+	if evs[1].Kind != TurnChanged || evs[1].Player != PlayerTwoColor || evs[1].State != Running {
+		t.Fatalf("expected the second player to move: %+v", evs[1])
+	}
+
+	if err := gm.PlayMoves("34343"); err != nil {
+		t.Fatalf("PlayMoves: %v", err)
+	}
+	gm.MakePlayerTurn(3)
+	evs = drain(events)
+	if last := evs[len(evs)-1]; last.Kind != GameWon || last.Player != PlayerOneColor || last.State != Win || last.Turn != 7 {
+		t.Fatalf("expected a win for the first player, got %+v", last)
+	}
+
+	gm.Undo()
+	if got := kinds(drain(events)); len(got) != 2 || got[0] != MoveUndone || got[1] != TurnChanged {
+		t.Fatalf("unexpected events for an undo: %v", got)
+	}
+	gm.ResetGame()
+	if evs := drain(events); len(evs) != 1 || evs[0].Kind != GameReset || evs[0].Turn != 0 {
+		t.Fatalf("unexpected events for a reset: %+v", evs)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatalf("expected cancel to close the channel")
+	}
+	cancel()
+}
+
+func TestSubscribeTie(t *testing.T) {
+	gm := NewGameManager(false, 0, WithBoardSize(4, 4, 4))
+	events, cancel := gm.Subscribe()
+	defer cancel()
+	if err := gm.PlayMoves("1234123423414123"); err != nil {
+		t.Fatalf("PlayMoves: %v", err)
+	}
+	if gm.GetState() != Tie {
+		t.Fatalf("expected a tie, got %v", gm.GetState())
+	}
+	evs := drain(events)
+	if last := evs[len(evs)-1]; last.Kind != GameTied || last.Turn != 16 {
+		t.Fatalf("unexpected last event %+v", last)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	events, cancel := gm.Subscribe()
+	defer cancel()
+	for range eventBuffer {
+		gm.MakePlayerTurn(0)
+		gm.Undo()
+	}
+	if n := len(drain(events)); n != eventBuffer {
+		t.Fatalf("expected %d buffered events, got %d", eventBuffer, n)
+	}
+	if _, ok := <-events; ok {
+		t.Fatalf("expected the slow subscriber to be dropped")
+	}
+}
+
+// blockingEngine attend d'être débloqué avant de jouer dans la colonne 0.
+type blockingEngine struct {
+	started, release chan struct{}
+}
+
+func (e blockingEngine) BestMove(ctx context.Context, b *Board, player string) (Move, error) {
+	e.started <- struct{}{}
+	<-e.release
+	return Move{Column: 0, Player: player}, nil
+}
+
+func TestPositionChangedDuringSearch(t *testing.T) {
+	e := blockingEngine{make(chan struct{}), make(chan struct{})}
+	gm := NewGameManager(true, 1, WithEngine(e))
+	gm.MakePlayerTurn(3)
+	errc := make(chan error)
+	go func() {
+		_, err := gm.MakeOpponentTurn(-1)
+		errc <- err
+	}()
+	<-e.started
+	// la partie reste lisible et modifiable pendant la réflexion
+	if gm.GetHoleColor(DefaultHeight-1, 3) != PlayerOneColor || !gm.Undo() {
+		t.Fatalf("expected the game to be usable during the search")
+	}
+	close(e.release)
+	if err := <-errc; !errors.Is(err, ErrPositionChanged) {
+		t.Fatalf("expected ErrPositionChanged, got %v", err)
+	}
+	if len(gm.History()) != 0 {
+		t.Fatalf("expected the stale move to be dropped, got %q", gm.MoveString())
+	}
+}
+
+// TestConcurrentUse joue une partie contre l'IA pendant que d'autres
+// goroutines lisent la partie et suivent ses événements (à lancer avec
+// -race).
+func TestConcurrentUse(t *testing.T) {
+	gm := NewGameManager(true, 2, WithSeed(1))
+	events, cancel := gm.Subscribe()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range events {
+		}
+	}()
+	done := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			width, height, _ := gm.BoardSize()
+			for i := range height {
+				for j := range width {
+					gm.GetHoleColor(i, j)
+				}
+			}
+			gm.History()
+			gm.WhereConnected()
+			gm.PositionKey()
+		}
+	}()
+	for col := 0; gm.GetState() == Running; col = (col + 1) % 7 {
+		if ok, _ := gm.MakePlayerTurn(col); !ok {
+			continue
+		}
+		if gm.GetState() == Running {
+			if _, err := gm.MakeOpponentTurn(-1); err != nil {
+				t.Fatalf("MakeOpponentTurn: %v", err)
+			}
+		}
+	}
+	close(done)
+	cancel()
+	wg.Wait()
+}
@@ -1,21 +1,64 @@
 package game
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
 )
 
 // GameManager gère le déroulement d'une partie de Puissance 4.
 // Il maintient l'état du jeu, gère les tours des joueurs et de l'IA,
-// et compte les victoires/défaites.
+// et compte les victoires/défaites. Ses méthodes peuvent être appelées
+// par plusieurs goroutines à la fois, par exemple pour dessiner le plateau
+// pendant que l'IA réfléchit.
 type GameManager struct {
+	// mu protège les champs ci-dessous, sauf ai, aiDiff et le moteur, fixés
+	// à la création
+	mu sync.RWMutex
+	// searching est verrouillé pendant la réflexion de l'IA, qui utilise
+	// rng sans verrouiller mu
+	searching sync.Mutex
+
 	board     Board     // Plateau de jeu
 	ai        bool      // true si l'adversaire est une IA
 	turn      int       // Numéro du tour actuel
 	state     GameState // État actuel de la partie
 	winner    string    // Symbole du joueur gagnant ("" si pas de gagnant)
+	timedOut  bool      // true si la partie s'est terminée au temps (voir TimeOut)
 	aiDiff    int       // Niveau de difficulté de l'IA
 	lostGames int       // Nombre de parties perdues
 	wonGames  int       // Nombre de parties gagnées
+
+	alphaBeta *AlphaBeta // Moteur alpha-bêta configuré par les options With…
+	engine    Engine     // Moteur choisissant les coups de l'IA (alphaBeta par défaut)
+	seed      int64      // Graine de la partie en cours
+	rng       *rand.Rand // Source aléatoire de l'IA, initialisée avec seed à chaque partie
+
+	history   []Move   // Coups joués depuis le début de la partie
+	positions []uint64 // Clé de la position atteinte après chaque coup de history (règle de répétition)
+	undone    []Move   // Coups annulés pouvant être rejoués (le prochain en dernier)
+
+	subs map[chan Event]struct{} // Abonnés aux événements (voir Subscribe)
+}
+
+// ErrPositionChanged est renvoyée par MakeOpponentTurn quand la position
+// a changé pendant la réflexion de l'IA.
+var ErrPositionChanged = errors.New("the position changed during the AI search")
+
+// Option configure un GameManager lors de sa création.
+type Option func(*GameManager)
+
+// WithTranspositionTable fait utiliser la table fournie par l'IA à la place
+// de la table créée par défaut. Une table nil désactive la mémorisation
+// des positions.
+func WithTranspositionTable(tt *TranspositionTable) Option {
+	return func(gm *GameManager) {
+		gm.alphaBeta.Table = tt
+	}
 }
 
 // GameState représente l'état d'une partie.
@@ -33,21 +76,140 @@ const (
 	PlayerTwoColor = "⬤" // Symbole du joueur 2
 )
 
+// WithThinkTime fait réfléchir l'IA pendant la durée d au plus pour chaque
+// coup, par approfondissement itératif, au lieu d'utiliser la profondeur
+// fixe aiDiff.
+func WithThinkTime(d time.Duration) Option {
+	return func(gm *GameManager) {
+		gm.alphaBeta.ThinkTime = d
+	}
+}
+
+// WithEvaluator fait utiliser l'évaluateur fourni par l'IA à l'horizon de
+// sa recherche, à la place de PositionalEvaluator avec DefaultWeights.
+func WithEvaluator(e Evaluator) Option {
+	return func(gm *GameManager) {
+		gm.alphaBeta.Evaluator = e
+	}
+}
+
+// WithPerfectPlay fait jouer l'IA parfaitement grâce au solveur exact
+// (« difficulté 10 »). Le solveur dispose de la moitié du temps de
+// réflexion (WithThinkTime), ou de DefaultSolveTime sans temps de
+// réflexion ; s'il n'aboutit pas, l'IA se rabat sur la recherche
+// alpha-bêta, dans le reste du temps ou à la profondeur aiDiff.
+func WithPerfectPlay() Option {
+	return func(gm *GameManager) {
+		gm.alphaBeta.Solver = NewSolver(DefaultSolverTableSize)
+	}
+}
+
+// WithSolver fait utiliser le solveur fourni par l'IA parfaite, par
+// exemple pour borner la taille de sa table, à la place du solveur créé
+// par WithPerfectPlay. Il est sans effet sans WithPerfectPlay.
+func WithSolver(s *Solver) Option {
+	return func(gm *GameManager) {
+		if gm.alphaBeta.Solver != nil {
+			gm.alphaBeta.Solver = s
+		}
+	}
+}
+
+// WithOpeningBook fait jouer instantanément à l'IA les coups de la
+// bibliothèque d'ouvertures fournie lorsque la position y figure. Avec
+// WithPerfectPlay, elle ne sert que si le solveur n'aboutit pas.
+func WithOpeningBook(book *OpeningBook) Option {
+	return func(gm *GameManager) {
+		gm.alphaBeta.Book = book
+	}
+}
+
+// WithWorkers répartit la recherche alpha-bêta de l'IA entre n goroutines
+// partageant la table de transposition (n ≤ 1 : recherche séquentielle).
+func WithWorkers(n int) Option {
+	return func(gm *GameManager) {
+		gm.alphaBeta.Workers = n
+	}
+}
+
+// WithSeed fixe la graine de la première partie. Les coups de l'IA ne
+// dépendent que de cette graine et des coups du joueur : une partie peut
+// ainsi être rejouée à l'identique, à condition que la recherche soit
+// séquentielle et en profondeur fixe (sans WithWorkers ni WithThinkTime).
+// Sans cette option, la graine est tirée de l'heure.
+func WithSeed(seed int64) Option {
+	return func(gm *GameManager) {
+		gm.seed = seed
+	}
+}
+
+// WithBoardSize fait jouer sur un plateau de width colonnes et height
+// rangées où il faut aligner connectN jetons (Puissance N), au lieu du
+// plateau standard 7x6. WithBoardSize panique si les dimensions sont
+// refusées par CheckGeometry. Le solveur exact (WithPerfectPlay) et la
+// bibliothèque d'ouvertures ne sont utilisés que sur le plateau standard.
+func WithBoardSize(width, height, connectN int) Option {
+	return func(gm *GameManager) {
+		variant := gm.board.variant
+		gm.board = *NewBoard(width, height, connectN)
+		gm.board.variant = variant
+	}
+}
+
+// WithVariant fait jouer la partie selon les règles fournies (Classic par
+// défaut). En PopOut, les coups de retrait se jouent par MakePlayerPop et
+// l'IA peut en jouer ; le solveur exact et la bibliothèque d'ouvertures ne
+// sont pas utilisés.
+func WithVariant(v Variant) Option {
+	return func(gm *GameManager) {
+		gm.board.variant = v
+	}
+}
+
+// WithEngine fait choisir les coups de l'IA par le moteur fourni (par
+// exemple NewMCTS) à la place de la recherche alpha-bêta. Les options
+// propres à l'alpha-bêta sont alors sans effet. Les moteurs du paquet
+// reçoivent la source aléatoire du GameManager (voir WithSeed).
+func WithEngine(e Engine) Option {
+	return func(gm *GameManager) {
+		gm.engine = e
+	}
+}
+
 // NewGameManager crée un nouveau gestionnaire de partie.
 // Le paramètre ai indique si l'adversaire est contrôlé par l'IA,
-// aiDiff définit le niveau de difficulté de l'IA.
-func NewGameManager(ai bool, aiDiff int) *GameManager {
-	b := *NewBoard()
-	return &GameManager{board: b, ai: ai, aiDiff: aiDiff, turn: 0, state: Running, winner: ""}
+// aiDiff définit le niveau de difficulté de l'IA. Les options permettent
+// d'ajuster la configuration de l'IA.
+func NewGameManager(ai bool, aiDiff int, opts ...Option) *GameManager {
+	b := *newStandardBoard()
+	gm := &GameManager{board: b, ai: ai, aiDiff: aiDiff, turn: 0, state: Running, winner: ""}
+	gm.alphaBeta = &AlphaBeta{Depth: aiDiff}
+	gm.seed = time.Now().UnixNano()
+	if ai {
+		gm.alphaBeta.Table = NewTranspositionTable(DefaultTableSize, ReplaceDepthPreferred)
+	}
+	for _, opt := range opts {
+		opt(gm)
+	}
+	if gm.engine == nil {
+		gm.engine = gm.alphaBeta
+	}
+	gm.rng = rand.New(rand.NewSource(gm.seed))
+	if e, ok := gm.engine.(seededEngine); ok {
+		e.setRand(gm.rng)
+	}
+	return gm
 }
 
 // GetHoleColor renvoie le symbole à la position (i,j) du plateau.
 // Renvoie une chaîne vide si la position est hors limites.
 func (gm *GameManager) GetHoleColor(i, j int) string {
-	if i < 0 || i >= boardHeight || j < 0 || j >= boardWidth {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	if i < 0 || i >= gm.board.geo.height || j < 0 || j >= gm.board.geo.width {
 		return ""
 	}
-	return gm.board.board[i][j]
+	return gm.board.cell(i, j)
 }
 
 // currentToken renvoie le symbole du joueur dont c'est le tour.
@@ -60,93 +222,336 @@ func (gm *GameManager) currentToken() string {
 
 // GetState renvoie l'état actuel de la partie.
 func (gm *GameManager) GetState() GameState {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
 	return gm.state
 }
 
 // MakePlayerTurn tente de placer un jeton dans la colonne spécifiée.
 // Renvoie (true, nil) si le coup est valide, (false, error) sinon.
 func (gm *GameManager) MakePlayerTurn(column int) (bool, error) {
-	if column < 0 || column >= boardWidth {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if column < 0 || column >= gm.board.geo.width {
 		return false, fmt.Errorf("column %d out of range", column)
 	}
-	tok := gm.currentToken()
-	if gm.board.Drop(column, tok) {
-		if gm.board.areFourConnected(tok) {
-			gm.state = Win
-			gm.winner = tok
-			gm.wonGames++
-		}
-		gm.turn++
-		if gm.turn == 42 {
-			gm.state = Tie
-		}
+	if gm.play(MoveDrop, column, false) {
+		gm.undone = nil
 		return true, nil
 	}
 	return false, fmt.Errorf("invalid move: column %d is full or invalid", column)
 }
 
+// MakePlayerPop tente de retirer le jeton du bas de la colonne spécifiée
+// (variante PopOut) pour le joueur dont c'est le tour, que ce soit le
+// joueur ou, en partie locale, son adversaire.
+// Renvoie (true, nil) si le coup est valide, (false, error) sinon.
+func (gm *GameManager) MakePlayerPop(column int) (bool, error) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if gm.board.variant != PopOut {
+		return false, fmt.Errorf("popping is not allowed in the %s variant", gm.board.variant)
+	}
+	if column < 0 || column >= gm.board.geo.width {
+		return false, fmt.Errorf("column %d out of range", column)
+	}
+	if gm.play(MovePop, column, false) {
+		gm.undone = nil
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid move: the bottom disc of column %d is not yours", column)
+}
+
 // MakeOpponentTurn effectue le coup de l'adversaire.
-// Si gm.ai == true, l'IA choisit une colonne et providedColumn est ignorée.
+// Si gm.ai == true, l'IA choisit une colonne et providedColumn est ignorée ;
+// en PopOut, l'IA peut retirer un jeton (voir History pour la sorte du coup).
 // Pour un adversaire humain (gm.ai == false), l'appelant doit fournir la colonne
 // choisie via providedColumn. La méthode renvoie la colonne jouée et une erreur
 // si le coup est invalide.
 func (gm *GameManager) MakeOpponentTurn(providedColumn int) (int, error) {
+	return gm.MakeOpponentTurnContext(context.Background(), providedColumn)
+}
+
+// MakeOpponentTurnContext est MakeOpponentTurn avec un contexte limitant la
+// réflexion de l'IA : à l'échéance de ctx, l'IA joue le meilleur coup de la
+// dernière profondeur entièrement explorée.
+//
+// L'IA réfléchit sur une copie du plateau, sans empêcher les autres
+// goroutines de lire la partie. Si la position change pendant sa réflexion
+// (coup joué, annulation, nouvelle partie, défaite au temps), son coup
+// n'est pas joué et ErrPositionChanged est renvoyée.
+func (gm *GameManager) MakeOpponentTurnContext(ctx context.Context, providedColumn int) (int, error) {
 	var column int
+	kind := MoveDrop
 	if gm.ai {
-		column = getAiMove(&gm.board, gm.aiDiff)
+		gm.searching.Lock()
+		gm.mu.RLock()
+		b, history, tok := gm.board.copyOfBoard(), slices.Clone(gm.history), gm.currentToken()
+		turn, key := gm.turn, gm.positionKey()
+		gm.mu.RUnlock()
+		var m Move
+		var err error
+		if he, ok := gm.engine.(HistoryEngine); ok {
+			m, err = he.BestMoveAfter(ctx, b, history, tok)
+		} else {
+			m, err = gm.engine.BestMove(ctx, b, tok)
+		}
+		gm.searching.Unlock()
+		if err != nil {
+			return -1, fmt.Errorf("ai move: %w", err)
+		}
+		column, kind = m.Column, m.Kind
+		gm.mu.Lock()
+		defer gm.mu.Unlock()
+		if gm.turn != turn || gm.positionKey() != key || gm.state != Running {
+			return -1, ErrPositionChanged
+		}
 	} else {
-		if providedColumn < 0 || providedColumn >= boardWidth {
+		gm.mu.Lock()
+		defer gm.mu.Unlock()
+		if providedColumn < 0 || providedColumn >= gm.board.geo.width {
 			return -1, fmt.Errorf("no valid column provided for opponent")
 		}
 		column = providedColumn
 	}
 
-	tok := gm.currentToken()
-	if !gm.board.Drop(column, tok) {
+	if !gm.play(kind, column, true) {
 		return column, fmt.Errorf("invalid move: column %d is full or invalid", column)
 	}
+	gm.undone = nil
+	return column, nil
+}
 
-	if gm.board.areFourConnected(tok) {
-		gm.state = Lose
-		gm.lostGames++
-		gm.winner = tok
+// MakeOpponentPop retire le jeton du bas de la colonne pour l'adversaire
+// humain (gm.ai == false), par exemple un joueur distant (variante
+// PopOut). Renvoie (true, nil) si le coup est valide, (false, error) sinon.
+func (gm *GameManager) MakeOpponentPop(column int) (bool, error) {
+	if gm.ai {
+		return false, fmt.Errorf("the AI chooses its own moves")
+	}
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if gm.board.variant != PopOut {
+		return false, fmt.Errorf("popping is not allowed in the %s variant", gm.board.variant)
+	}
+	if column < 0 || column >= gm.board.geo.width {
+		return false, fmt.Errorf("column %d out of range", column)
+	}
+	if gm.play(MovePop, column, true) {
+		gm.undone = nil
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid move: the bottom disc of column %d does not belong to the opponent", column)
+}
+
+// play joue pour le joueur au trait un coup de la sorte indiquée dans la
+// colonne, l'ajoute à l'historique et met à jour l'état de la partie.
+// opponent indique si le coup est celui de l'adversaire : une victoire de
+// l'adversaire compte comme une défaite du joueur. Renvoie false si le coup
+// est invalide. gm.mu doit être verrouillé en écriture.
+//
+// La partie est nulle lorsque le joueur au trait n'a plus de coup ou, en
+// PopOut, lorsqu'une même position se présente pour la troisième fois.
+func (gm *GameManager) play(kind MoveKind, column int, opponent bool) bool {
+	tok := gm.currentToken()
+	if kind == MovePop {
+		if !gm.board.Pop(column, tok) {
+			return false
+		}
+	} else if !gm.board.Drop(column, tok) {
+		return false
+	}
+	move := Move{Column: column, Kind: kind, Player: tok, Turn: gm.turn, Opponent: opponent}
+	gm.history = append(gm.history, move)
+	// un retrait peut aligner les jetons de l'adversaire de celui qui joue
+	if winner := gm.board.winner(tok); winner != "" {
+		gm.winner = winner
+		if (winner == tok) == opponent {
+			gm.state = Lose
+			gm.lostGames++
+		} else {
+			gm.state = Win
+			gm.wonGames++
+		}
 	}
 	gm.turn++
-	if gm.turn == 42 {
+
+	key := gm.positionKey()
+	repeated := 0
+	if key == 0 {
+		// position initiale : plateau vide, premier joueur au trait
+		repeated++
+	}
+	for _, k := range gm.positions {
+		if k == key {
+			repeated++
+		}
+	}
+	gm.positions = append(gm.positions, key)
+	if gm.state == Running && (!gm.board.hasMove(gm.currentToken()) || (gm.board.variant == PopOut && repeated >= 2)) {
 		gm.state = Tie
 	}
-	return column, nil
+	gm.emit(Event{Kind: MoveMade, Move: move})
+	gm.emitOutcome()
+	return true
 }
 
-// WhereConnected renvoie les coordonnées des quatre jetons alignés s'il y a un gagnant.
-// Retourne (false, [-1,-1,-1,-1], [-1,-1,-1,-1]) si pas de gagnant.
-func (gm *GameManager) WhereConnected() (bool, [4]int, [4]int) {
-	if gm.winner == "" {
-		return false, [4]int{-1, -1, -1, -1}, [4]int{-1, -1, -1, -1}
+// TimeOut fait perdre au temps le joueur au trait. opponent indique s'il
+// s'agit de l'adversaire (l'IA ou un joueur distant) : sa défaite compte
+// comme une victoire du joueur (Win) ; sinon la partie est perdue (Lose),
+// y compris en partie locale. Le coup en cours de réflexion de l'IA n'est
+// pas joué, et les coups annulés ne peuvent plus être rejoués. Renvoie false si la partie était déjà terminée.
+func (gm *GameManager) TimeOut(opponent bool) bool {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if gm.state != Running {
+		return false
 	}
-	connected, x, y := gm.board.WhereConnected(gm.winner)
-	return connected, x, y
+	// le gagnant est le joueur qui n'a pas le trait
+	gm.winner = PlayerOneColor
+	if gm.turn%2 == 0 {
+		gm.winner = PlayerTwoColor
+	}
+	gm.timedOut = true
+	// les coups annulés ne peuvent pas être rejoués dans une partie terminée
+	gm.undone = nil
+	if opponent {
+		gm.state = Win
+		gm.wonGames++
+	} else {
+		gm.state = Lose
+		gm.lostGames++
+	}
+	gm.emit(Event{Kind: GameWon, Player: gm.winner})
+	return true
+}
+
+// TimedOut indique si la partie s'est terminée au temps.
+func (gm *GameManager) TimedOut() bool {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.timedOut
+}
+
+// Winner renvoie le symbole du gagnant de la partie ("" si la partie
+// continue ou est nulle).
+func (gm *GameManager) Winner() string {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.winner
+}
+
+// PositionKey renvoie la clé Zobrist de la position courante, joueur au
+// trait compris. Les clés ne dépendent pas de l'exécution : deux parties
+// dans la même position ont la même clé, ce qui permet par exemple de
+// vérifier que deux instances jouant en réseau sont synchronisées.
+func (gm *GameManager) PositionKey() uint64 {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.positionKey()
+}
+
+// positionKey est PositionKey, gm.mu étant verrouillé.
+func (gm *GameManager) positionKey() uint64 {
+	if gm.turn%2 == 1 {
+		return gm.board.hash ^ zobristMaximizer
+	}
+	return gm.board.hash
+}
+
+// WhereConnected renvoie les coordonnées des connectN jetons alignés s'il
+// y a un gagnant. Retourne false et des slices remplies de -1 si pas de
+// gagnant.
+func (gm *GameManager) WhereConnected() (bool, []int, []int) {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	// sans gagnant, Board.WhereConnected renvoie des slices remplies de -1
+	return gm.board.WhereConnected(gm.winner)
+}
+
+// Variant renvoie les règles de la partie.
+func (gm *GameManager) Variant() Variant {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.board.variant
+}
+
+// Board renvoie une copie du plateau de la partie en cours, par exemple
+// pour l'analyser avec un Engine ou un Solver.
+func (gm *GameManager) Board() *Board {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.board.copyOfBoard()
+}
+
+// BoardSize renvoie le nombre de colonnes et de rangées du plateau et le
+// nombre de jetons à aligner pour gagner.
+func (gm *GameManager) BoardSize() (width, height, connectN int) {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.board.Width(), gm.board.Height(), gm.board.ConnectN()
 }
 
 // ResetGame réinitialise le plateau et l'état de la partie, sans modifier
-// le compteur de victoires/défaites.
+// le compteur de victoires/défaites. La graine de la nouvelle partie est
+// tirée de la source aléatoire de l'IA et la table de transposition est
+// vidée, afin que chaque partie puisse être rejouée à partir de sa graine.
+// ResetGame attend la fin de la réflexion de l'IA en cours, dont le coup
+// n'est pas joué.
 func (gm *GameManager) ResetGame() {
-	gm.board = *NewBoard()
+	gm.searching.Lock()
+	defer gm.searching.Unlock()
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	g, variant := gm.board.geo, gm.board.variant
+	gm.board = *NewBoard(g.width, g.height, g.connect)
+	gm.board.variant = variant
 	gm.turn = 0
 	gm.state = Running
 	gm.winner = ""
+	gm.timedOut = false
+	gm.history = nil
+	gm.positions = nil
+	gm.undone = nil
+	gm.seed = gm.rng.Int63()
+	gm.rng.Seed(gm.seed)
+	if gm.alphaBeta.Table != nil {
+		gm.alphaBeta.Table.Clear()
+	}
+	gm.emit(Event{Kind: GameReset})
+}
+
+// Seed renvoie la graine de la partie en cours : avec les coups du joueur,
+// elle suffit à rejouer la partie (voir WithSeed).
+func (gm *GameManager) Seed() int64 {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.seed
 }
 
 // GetWonGames renvoie le nombre de parties gagnées.
 func (gm *GameManager) GetWonGames() int {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
 	return gm.wonGames
 }
 
 // GetLostGames renvoie le nombre de parties perdues.
 func (gm *GameManager) GetLostGames() int {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
 	return gm.lostGames
 }
 
+// TTStats renvoie les statistiques de la table de transposition du moteur
+// alpha-bêta (zéro si aucune table n'est utilisée).
+func (gm *GameManager) TTStats() TTStats {
+	if gm.alphaBeta.Table == nil {
+		return TTStats{}
+	}
+	return gm.alphaBeta.Table.Stats()
+}
+
 // IsAI indique si l'adversaire est contrôlé par l'IA.
 func (gm *GameManager) IsAI() bool {
 	return gm.ai
@@ -1,6 +1,7 @@
 package game
 
 import (
+    "slices"
     "testing"
 )
 
@@ -156,3 +157,67 @@ func TestWhereConnectedAndReset(t *testing.T) {
         t.Fatalf("expected emptySpot at (0,0) after ResetGame, got %q", c)
     }
 }
+
+// playSeededGame joue une partie contre l'IA en jouant pour le joueur la
+// première colonne libre à partir de la colonne 3, et renvoie les coups de
+// l'IA.
+func playSeededGame(t *testing.T, gm *GameManager) []int {
+    t.Helper()
+    var aiMoves []int
+    for gm.GetState() == Running {
+        for c := 3; ; c = (c + 1) % boardWidth {
+            if ok, _ := gm.MakePlayerTurn(c); ok {
+                break
+            }
+        }
+        if gm.GetState() != Running {
+            break
+        }
+        col, err := gm.MakeOpponentTurn(-1)
+        if err != nil {
+            t.Fatalf("unexpected error from AI opponent: %v", err)
+        }
+        aiMoves = append(aiMoves, col)
+    }
+    return aiMoves
+}
+
+// Test de la reproductibilité d'une partie à partir de sa graine.
+func TestGameManagerSeedReplaysGame(t *testing.T) {
+    gm1 := NewGameManager(true, 4, WithSeed(42))
+    gm2 := NewGameManager(true, 4, WithSeed(42))
+    if gm1.Seed() != 42 {
+        t.Fatalf("expected seed 42, got %d", gm1.Seed())
+    }
+    first, second := playSeededGame(t, gm1), playSeededGame(t, gm2)
+    if !slices.Equal(first, second) {
+        t.Fatalf("games with the same seed differ:\n%v\n%v", first, second)
+    }
+
+    // la graine de la partie suivante est elle aussi reproductible
+    gm1.ResetGame()
+    gm2.ResetGame()
+    if gm1.Seed() != gm2.Seed() || gm1.Seed() == 42 {
+        t.Fatalf("expected the same new seed for both managers, got %d and %d", gm1.Seed(), gm2.Seed())
+    }
+    first, second = playSeededGame(t, gm1), playSeededGame(t, gm2)
+    if !slices.Equal(first, second) {
+        t.Fatalf("second games with the same seed differ:\n%v\n%v", first, second)
+    }
+
+    // une partie rejouée depuis la graine enregistrée est identique
+    replay := NewGameManager(true, 4, WithSeed(gm1.Seed()))
+    if moves := playSeededGame(t, replay); !slices.Equal(moves, first) {
+        t.Fatalf("replayed game differs:\n%v\n%v", moves, first)
+    }
+}
+
+// Test de la graine transmise au moteur MCTS.
+func TestGameManagerSeedsMCTS(t *testing.T) {
+    play := func() []int {
+        return playSeededGame(t, NewGameManager(true, 1, WithSeed(7), WithEngine(NewMCTS(300, 0, RolloutRandom))))
+    }
+    if first, second := play(), play(); !slices.Equal(first, second) {
+        t.Fatalf("MCTS games with the same seed differ:\n%v\n%v", first, second)
+    }
+}
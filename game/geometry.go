@@ -0,0 +1,150 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Dimensions du plateau standard de Puissance 4. Le solveur exact et la
+// bibliothèque d'ouvertures ne prennent en charge que ce plateau.
+const (
+	DefaultWidth   = 7
+	DefaultHeight  = 6
+	DefaultConnect = 4
+)
+
+// MaxWidth est la largeur maximale d'un plateau : chaque coup doit tenir
+// sur un chiffre dans la notation compacte (voir MoveString).
+const MaxWidth = 9
+
+// geometry regroupe les dimensions d'un plateau et les masques qui en
+// dépendent. Les géométries sont partagées par tous les plateaux de mêmes
+// dimensions et ne sont jamais modifiées.
+type geometry struct {
+	width, height, connect int
+
+	// directions contient les décalages (en bits) permettant de passer
+	// d'une case à sa voisine : horizontal, vertical, diagonale montante et
+	// diagonale descendante.
+	directions [4]int
+	// runStarts contient, pour chaque direction, le masque des cases depuis
+	// lesquelles un alignement de connect jetons tient dans le plateau. Il
+	// évite de détecter des alignements qui « débordent » d'une colonne à
+	// l'autre.
+	runStarts [4]uint64
+
+	full    uint64 // Toutes les cases du plateau
+	center  uint64 // Cases de la (ou des deux) colonne(s) centrale(s)
+	oddRows uint64 // Cases des rangées impaires en comptant depuis 1 à partir du bas
+}
+
+var (
+	geometries   = map[[3]int]*geometry{}
+	geometriesMu sync.Mutex
+)
+
+// standardGeometry est la géométrie du plateau standard.
+var standardGeometry = getGeometry(DefaultWidth, DefaultHeight, DefaultConnect)
+
+// CheckGeometry vérifie qu'un plateau de width colonnes et height rangées
+// avec connectN jetons à aligner est pris en charge : chaque plateau tient
+// dans un masque de 64 bits, width ne dépasse pas MaxWidth et un
+// alignement doit pouvoir tenir dans le plateau.
+func CheckGeometry(width, height, connectN int) error {
+	switch {
+	case width < 2 || width > MaxWidth:
+		return fmt.Errorf("board width %d out of range [2, %d]", width, MaxWidth)
+	case height < 2:
+		return fmt.Errorf("board height %d is too small", height)
+	case width*height > 64:
+		return fmt.Errorf("a %dx%d board has more than 64 cells", width, height)
+	case connectN < 2 || (connectN > width && connectN > height):
+		return fmt.Errorf("cannot connect %d on a %dx%d board", connectN, width, height)
+	}
+	return nil
+}
+
+// getGeometry renvoie la géométrie partagée des dimensions fournies, qui
+// doivent avoir été vérifiées par CheckGeometry.
+func getGeometry(width, height, connect int) *geometry {
+	geometriesMu.Lock()
+	defer geometriesMu.Unlock()
+	key := [3]int{width, height, connect}
+	if g, ok := geometries[key]; ok {
+		return g
+	}
+	g := &geometry{
+		width:      width,
+		height:     height,
+		connect:    connect,
+		directions: [4]int{height, 1, height + 1, height - 1},
+	}
+	for c := 0; c < width; c++ {
+		for r := 0; r < height; r++ {
+			bit := uint64(1) << (c*height + r)
+			g.full |= bit
+			if r%2 == 0 {
+				g.oddRows |= bit
+			}
+			if c == width/2 || (width%2 == 0 && c == width/2-1) {
+				g.center |= bit
+			}
+			fitsRight := c+connect <= width
+			if fitsRight {
+				g.runStarts[0] |= bit
+			}
+			if r+connect <= height {
+				g.runStarts[1] |= bit
+			}
+			if fitsRight && r+connect <= height {
+				g.runStarts[2] |= bit
+			}
+			if fitsRight && r >= connect-1 {
+				g.runStarts[3] |= bit
+			}
+		}
+	}
+	geometries[key] = g
+	return g
+}
+
+// cells renvoie le nombre de cases du plateau.
+func (g *geometry) cells() int {
+	return g.width * g.height
+}
+
+// cellBit renvoie le bit correspondant à la case (row, column), row étant
+// compté depuis le haut du plateau comme dans l'interface graphique.
+func (g *geometry) cellBit(row, column int) uint64 {
+	return uint64(1) << (column*g.height + g.height - 1 - row)
+}
+
+// alignments renvoie le masque des cases de départ d'un alignement de
+// connect jetons de m dans la direction d'indice dir.
+//
+// Les alignements sont obtenus par doublements successifs : runs contient
+// les départs des alignements de length jetons, et deux alignements de
+// length jetons qui se chevauchent couvrent ensuite la longueur voulue.
+func (g *geometry) alignments(m uint64, dir int) uint64 {
+	shift := g.directions[dir]
+	runs, length := m, 1
+	for 2*length <= g.connect {
+		runs &= runs >> (length * shift)
+		length *= 2
+	}
+	if length < g.connect {
+		runs &= runs >> ((g.connect - length) * shift)
+	}
+	return runs & g.runStarts[dir]
+}
+
+// hasAlignment retourne true si le masque m contient un alignement de
+// connect jetons dans une des quatre directions.
+func (g *geometry) hasAlignment(m uint64) bool {
+	for dir := range g.directions {
+		if g.alignments(m, dir) != 0 {
+			return true
+		}
+	}
+	return false
+}
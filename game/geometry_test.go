@@ -0,0 +1,179 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCheckGeometry(t *testing.T) {
+	valid := [][3]int{{7, 6, 4}, {8, 7, 4}, {9, 7, 5}, {8, 8, 4}, {2, 2, 2}, {4, 9, 5}}
+	for _, g := range valid {
+		if err := CheckGeometry(g[0], g[1], g[2]); err != nil {
+			t.Errorf("%v: unexpected error %v", g, err)
+		}
+	}
+	invalid := [][3]int{{1, 6, 4}, {10, 6, 4}, {7, 1, 4}, {9, 8, 4}, {7, 6, 1}, {7, 6, 8}}
+	for _, g := range invalid {
+		if err := CheckGeometry(g[0], g[1], g[2]); err == nil {
+			t.Errorf("%v: expected an error", g)
+		}
+	}
+}
+
+func TestNewBoardPanicsOnInvalidGeometry(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected NewBoard to panic")
+		}
+	}()
+	NewBoard(9, 8, 4)
+}
+
+// naiveAlignment vérifie cellule par cellule si player a aligné connectN
+// jetons, à partir des coordonnées de l'interface.
+func naiveAlignment(b *Board, player string) bool {
+	w, h, n := b.Width(), b.Height(), b.ConnectN()
+	for _, d := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		for r := 0; r < h; r++ {
+			for c := 0; c < w; c++ {
+				k := 0
+				for ; k < n; k++ {
+					rr, cc := r+k*d[0], c+k*d[1]
+					if rr < 0 || rr >= h || cc < 0 || cc >= w || b.cell(rr, cc) != player {
+						break
+					}
+				}
+				if k == n {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestAlignmentsMatchNaiveCheck(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, g := range [][3]int{{7, 6, 4}, {8, 7, 4}, {9, 7, 5}, {6, 5, 3}, {8, 8, 6}, {5, 4, 4}} {
+		for game := 0; game < 200; game++ {
+			b := NewBoard(g[0], g[1], g[2])
+			player := PlayerOneColor
+			for !b.gameOver() {
+				var c int
+				for c = rng.Intn(b.Width()); !b.canPlay(c); c = rng.Intn(b.Width()) {
+				}
+				b.Drop(c, player)
+				for _, p := range []string{PlayerOneColor, PlayerTwoColor} {
+					if got, want := b.areFourConnected(p), naiveAlignment(b, p); got != want {
+						b.printBoard()
+						t.Fatalf("%v: alignment for %s is %v, expected %v", g, p, got, want)
+					}
+				}
+				player = opponent(player)
+			}
+		}
+	}
+}
+
+func TestConnectFiveWhereConnected(t *testing.T) {
+	b := NewBoard(9, 7, 5)
+	for c := 4; c < 8; c++ {
+		b.Drop(c, PlayerOneColor)
+	}
+	if b.areFourConnected(PlayerOneColor) {
+		t.Fatalf("four tokens must not win a connect-five game")
+	}
+	b.Drop(8, PlayerOneColor)
+	connected, rows, cols := b.WhereConnected(PlayerOneColor)
+	if !connected || len(rows) != 5 || len(cols) != 5 {
+		t.Fatalf("expected five connected tokens, got %v %v %v", connected, rows, cols)
+	}
+	for k := range cols {
+		if cols[k] != 4+k || rows[k] != 6 {
+			t.Fatalf("unexpected coordinates rows=%v cols=%v", rows, cols)
+		}
+	}
+}
+
+func TestGameManagerWithBoardSize(t *testing.T) {
+	gm := NewGameManager(false, 0, WithBoardSize(8, 7, 4))
+	if w, h, n := gm.BoardSize(); w != 8 || h != 7 || n != 4 {
+		t.Fatalf("unexpected board size %dx%d connect %d", w, h, n)
+	}
+	if ok, err := gm.MakePlayerTurn(7); !ok {
+		t.Fatalf("expected column 7 to be playable: %v", err)
+	}
+	if ok, _ := gm.MakePlayerTurn(8); ok {
+		t.Fatalf("expected column 8 to be out of range")
+	}
+	if gm.GetHoleColor(6, 7) != PlayerOneColor {
+		t.Fatalf("expected the token at the bottom of column 7")
+	}
+	gm.ResetGame()
+	if w, h, _ := gm.BoardSize(); w != 8 || h != 7 {
+		t.Fatalf("ResetGame lost the board size: %dx%d", w, h)
+	}
+
+	// un plateau rempli sans alignement est une partie nulle
+	gm = NewGameManager(false, 0, WithBoardSize(2, 2, 2))
+	for _, c := range []int{0, 1, 1} {
+		gm.MakePlayerTurn(c)
+	}
+	if gm.GetState() != Win {
+		t.Fatalf("expected a diagonal win on a 2x2 board, got %v", gm.GetState())
+	}
+}
+
+func TestAIOnLargerBoard(t *testing.T) {
+	// l'IA a aligné quatre jetons en bas des colonnes 1 à 4 et doit
+	// compléter l'alignement de cinq en colonne 5
+	gm := NewGameManager(true, 4, WithBoardSize(9, 7, 5), WithSeed(3))
+	for i, c := range []int{0, 1, 0, 2, 0, 3, 8, 4, 8} {
+		gm.play(MoveDrop, c, i%2 == 1)
+	}
+	column, err := gm.MakeOpponentTurn(-1)
+	if err != nil {
+		t.Fatalf("MakeOpponentTurn: %v", err)
+	}
+	if column != 5 || gm.GetState() != Lose {
+		t.Fatalf("expected the AI to complete five in column 5, got %d (state %v)", column, gm.GetState())
+	}
+
+	mcts := newSeededMCTS(500, RolloutHeuristic)
+	b := NewBoard(8, 7, 4)
+	playMoves(t, b, 0, 7, 0, 7, 0)
+	if move, err := mcts.BestMove(context.Background(), b, PlayerTwoColor); err != nil || move.Column != 0 {
+		t.Fatalf("expected MCTS to block column 0 on an 8x7 board, got %d (%v)", move.Column, err)
+	}
+}
+
+func TestSolverRejectsOtherGeometries(t *testing.T) {
+	b := NewBoard(8, 7, 4)
+	if _, err := NewSolver(1 << 10).Solve(b); !errors.Is(err, ErrUnsupportedBoard) {
+		t.Fatalf("expected ErrUnsupportedBoard, got %v", err)
+	}
+	// le moteur alpha-bêta se rabat sur la recherche
+	engine := &AlphaBeta{Depth: 2, Solver: NewSolver(1 << 10)}
+	if move, err := engine.BestMove(context.Background(), b, PlayerOneColor); err != nil || move.Column < 0 || move.Column >= 8 {
+		t.Fatalf("expected a legal move, got %d (%v)", move.Column, err)
+	}
+}
+
+func TestSaveLoadKeepsBoardSize(t *testing.T) {
+	gm := NewGameManager(false, 0, WithBoardSize(9, 7, 5))
+	gm.MakePlayerTurn(8)
+	var buf bytes.Buffer
+	if err := gm.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if w, h, n := loaded.BoardSize(); w != 9 || h != 7 || n != 5 || loaded.MoveString() != "9" {
+		t.Fatalf("unexpected loaded game %dx%d connect %d moves %q", w, h, n, loaded.MoveString())
+	}
+}
@@ -0,0 +1,97 @@
+package game
+
+import "slices"
+
+// MoveKind distingue les deux sortes de coups.
+type MoveKind int
+
+const (
+	MoveDrop MoveKind = iota // Dépôt d'un jeton en haut de la colonne
+	MovePop                  // Retrait du jeton du bas de la colonne (variante PopOut)
+)
+
+// Move est un coup de l'historique d'une partie.
+type Move struct {
+	Column   int      // Colonne jouée
+	Kind     MoveKind // Dépôt ou retrait
+	Player   string   // Symbole du joueur ayant joué
+	Turn     int      // Numéro du tour (0 pour le premier coup de la partie)
+	Opponent bool     // true si le coup a été joué par MakeOpponentTurn (l'IA contre un joueur)
+}
+
+// History renvoie une copie des coups joués depuis le début de la partie,
+// du premier au dernier.
+func (gm *GameManager) History() []Move {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return slices.Clone(gm.history)
+}
+
+// Undo annule le dernier coup et restaure l'état de la partie, le gagnant,
+// le numéro du tour et les compteurs de victoires/défaites. Contre l'IA,
+// le dernier coup du joueur est annulé avec les réponses de l'IA qui l'ont
+// suivi, afin que ce soit de nouveau au joueur de jouer. Renvoie false s'il
+// n'y a rien à annuler.
+func (gm *GameManager) Undo() bool {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	n := len(gm.history) - 1
+	if gm.ai {
+		for n >= 0 && gm.history[n].Opponent {
+			n--
+		}
+	}
+	if n < 0 {
+		return false
+	}
+	for len(gm.history) > n {
+		gm.undoLast()
+	}
+	gm.emitOutcome()
+	return true
+}
+
+// Redo rejoue le dernier coup annulé par Undo, avec les réponses de l'IA
+// qui l'avaient suivi. Les coups annulés sont oubliés dès qu'un nouveau
+// coup est joué. Renvoie false s'il n'y a rien à rejouer ou si la partie
+// est terminée.
+func (gm *GameManager) Redo() bool {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if len(gm.undone) == 0 || gm.state != Running {
+		return false
+	}
+	for {
+		e := gm.undone[len(gm.undone)-1]
+		gm.undone = gm.undone[:len(gm.undone)-1]
+		gm.play(e.Kind, e.Column, e.Opponent)
+		if !gm.ai || len(gm.undone) == 0 || !gm.undone[len(gm.undone)-1].Opponent {
+			return true
+		}
+	}
+}
+
+// undoLast retire le dernier coup de l'historique et le place parmi les
+// coups pouvant être rejoués. gm.mu doit être verrouillé en écriture.
+func (gm *GameManager) undoLast() {
+	e := gm.history[len(gm.history)-1]
+	gm.history = gm.history[:len(gm.history)-1]
+	gm.positions = gm.positions[:len(gm.positions)-1]
+	switch gm.state {
+	case Win:
+		gm.wonGames--
+	case Lose:
+		gm.lostGames--
+	}
+	gm.state = Running
+	gm.winner = ""
+	gm.timedOut = false
+	if e.Kind == MovePop {
+		gm.board.undoPop(e.Column, e.Player)
+	} else {
+		gm.board.undoDrop(e.Column)
+	}
+	gm.turn--
+	gm.undone = append(gm.undone, e)
+	gm.emit(Event{Kind: MoveUndone, Move: e})
+}
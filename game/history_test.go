@@ -0,0 +1,118 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHistoryRecordsMoves(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	gm.MakePlayerTurn(3)
+	gm.MakeOpponentTurn(4)
+	gm.MakePlayerTurn(3)
+	want := []Move{
+		{Column: 3, Player: PlayerOneColor, Turn: 0},
+		{Column: 4, Player: PlayerTwoColor, Turn: 1, Opponent: true},
+		{Column: 3, Player: PlayerOneColor, Turn: 2},
+	}
+	if got := gm.History(); !slices.Equal(got, want) {
+		t.Fatalf("unexpected history: %v", got)
+	}
+	// l'historique renvoyé est une copie
+	gm.History()[0].Column = 6
+	if gm.History()[0].Column != 3 {
+		t.Fatalf("History exposed the internal slice")
+	}
+}
+
+func TestUndoRedoLocalGame(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	if gm.Undo() || gm.Redo() {
+		t.Fatalf("expected nothing to undo or redo on a new game")
+	}
+	gm.MakePlayerTurn(3)
+	gm.MakePlayerTurn(4)
+	if !gm.Undo() {
+		t.Fatalf("expected Undo to succeed")
+	}
+	if gm.turn != 1 || len(gm.History()) != 1 || gm.GetHoleColor(boardHeight-1, 4) != emptySpot {
+		t.Fatalf("Undo did not take back the last move: turn=%d history=%v", gm.turn, gm.History())
+	}
+	if !gm.Redo() || gm.turn != 2 || gm.GetHoleColor(boardHeight-1, 4) != PlayerTwoColor {
+		t.Fatalf("Redo did not replay the move: turn=%d", gm.turn)
+	}
+
+	// un nouveau coup oublie les coups annulés
+	gm.Undo()
+	gm.MakePlayerTurn(0)
+	if gm.Redo() {
+		t.Fatalf("expected Redo to fail after a new move")
+	}
+}
+
+func TestUndoRestoresWinAndCounters(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	for _, c := range []int{0, 1, 0, 1, 0, 1, 0} {
+		gm.MakePlayerTurn(c)
+	}
+	if gm.GetState() != Win || gm.GetWonGames() != 1 {
+		t.Fatalf("expected a won game, got state %v", gm.GetState())
+	}
+	gm.Undo()
+	if gm.GetState() != Running || gm.winner != "" || gm.GetWonGames() != 0 || gm.turn != 6 {
+		t.Fatalf("Undo did not restore the game: state=%v winner=%q won=%d turn=%d", gm.GetState(), gm.winner, gm.GetWonGames(), gm.turn)
+	}
+	gm.Redo()
+	if gm.GetState() != Win || gm.winner != PlayerOneColor || gm.GetWonGames() != 1 {
+		t.Fatalf("Redo did not restore the win: state=%v won=%d", gm.GetState(), gm.GetWonGames())
+	}
+}
+
+func TestUndoTakesBackTheAIReply(t *testing.T) {
+	gm := NewGameManager(true, 2, WithSeed(1))
+	gm.MakePlayerTurn(3)
+	aiColumn, err := gm.MakeOpponentTurn(-1)
+	if err != nil {
+		t.Fatalf("MakeOpponentTurn: %v", err)
+	}
+	if !gm.Undo() {
+		t.Fatalf("expected Undo to succeed")
+	}
+	if gm.turn != 0 || len(gm.History()) != 0 {
+		t.Fatalf("expected the player move and the AI reply to be undone, history=%v", gm.History())
+	}
+	if !gm.Redo() {
+		t.Fatalf("expected Redo to succeed")
+	}
+	history := gm.History()
+	if len(history) != 2 || history[0].Column != 3 || history[1].Column != aiColumn {
+		t.Fatalf("expected both moves to be replayed, history=%v", history)
+	}
+}
+
+func TestUndoAfterAIWin(t *testing.T) {
+	gm := NewGameManager(true, 2, WithSeed(1))
+	// l'IA aligne trois jetons en colonne 6 pendant que le joueur joue ailleurs
+	for _, c := range []int{0, 6, 1, 6, 0, 6} {
+		gm.play(MoveDrop, c, gm.turn%2 == 1)
+	}
+	gm.MakePlayerTurn(2)
+	if _, err := gm.MakeOpponentTurn(-1); err != nil || gm.GetState() != Lose {
+		t.Fatalf("expected the AI to win, got state %v (err %v)", gm.GetState(), err)
+	}
+	gm.Undo()
+	if gm.GetState() != Running || gm.GetLostGames() != 0 || gm.turn != 6 {
+		t.Fatalf("Undo did not restore the game: state=%v lost=%d turn=%d", gm.GetState(), gm.GetLostGames(), gm.turn)
+	}
+}
+
+func TestResetGameClearsHistory(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	gm.MakePlayerTurn(3)
+	gm.MakePlayerTurn(3)
+	gm.Undo()
+	gm.ResetGame()
+	if len(gm.History()) != 0 || gm.Undo() || gm.Redo() {
+		t.Fatalf("expected ResetGame to clear the history")
+	}
+}
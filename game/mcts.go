@@ -0,0 +1,224 @@
+package game
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RolloutPolicy détermine comment MCTS termine les parties simulées.
+type RolloutPolicy int
+
+const (
+	// RolloutRandom joue des coups uniformément aléatoires.
+	RolloutRandom RolloutPolicy = iota
+	// RolloutHeuristic joue un coup gagnant s'il en existe un, bloque
+	// sinon une victoire immédiate de l'adversaire, et joue au hasard
+	// dans les autres cas.
+	RolloutHeuristic
+)
+
+const (
+	// DefaultMCTSIterations est le nombre de simulations par coup
+	// lorsque MCTS.Iterations n'est pas renseigné.
+	DefaultMCTSIterations = 20000
+	// DefaultExploration est la constante d'exploration d'UCT utilisée
+	// lorsque MCTS.Exploration n'est pas renseignée.
+	DefaultExploration = math.Sqrt2
+)
+
+// mctsCheckInterval est le nombre de simulations entre deux vérifications
+// de l'échéance du contexte.
+const mctsCheckInterval = 64
+
+// MCTS est un moteur de recherche arborescente Monte-Carlo (UCT) : chaque
+// simulation descend l'arbre en choisissant le fils de plus grande borne
+// UCB1, ajoute un nouveau nœud puis termine la partie selon Rollout.
+// Le coup joué est celui de la racine le plus visité. Sur un plateau
+// PopOut, les retraits font partie des coups explorés et les simulations
+// trop longues sont comptées comme des parties nulles.
+//
+// Un MCTS ne doit pas être utilisé par plusieurs goroutines à la fois.
+type MCTS struct {
+	Iterations  int           // Nombre de simulations par coup (≤ 0 : DefaultMCTSIterations)
+	Exploration float64       // Constante d'exploration d'UCT (≤ 0 : DefaultExploration)
+	Rollout     RolloutPolicy // Politique de fin de partie des simulations
+	Rand        *rand.Rand    // Source aléatoire (nil : source initialisée avec l'heure)
+}
+
+// NewMCTS crée un moteur MCTS effectuant iterations simulations par coup
+// avec la constante d'exploration et la politique de simulation fournies.
+func NewMCTS(iterations int, exploration float64, rollout RolloutPolicy) *MCTS {
+	return &MCTS{Iterations: iterations, Exploration: exploration, Rollout: rollout}
+}
+
+// mctsNode est un nœud de l'arbre de recherche. Les récompenses sont
+// comptées du point de vue du joueur ayant joué le coup menant au nœud.
+type mctsNode struct {
+	parent   *mctsNode
+	move     int    // Coup menant au nœud (indice de Board.playMove, -1 pour la racine)
+	player   string // Joueur ayant joué move
+	children []*mctsNode
+	untried  []int // Coups pas encore développés
+	visits   int
+	reward   float64 // 1 par victoire, 0,5 par nul
+}
+
+// newMCTSNode crée le nœud atteint sur b après le coup move de player.
+func newMCTSNode(parent *mctsNode, b *Board, move int, player string) *mctsNode {
+	n := &mctsNode{parent: parent, move: move, player: player}
+	if b.winner(player) != "" {
+		return n
+	}
+	next := opponent(player)
+	for m := 0; m < b.searchMoves(); m++ {
+		if b.canPlayMove(m, next) {
+			n.untried = append(n.untried, m)
+		}
+	}
+	return n
+}
+
+// selectChild renvoie le fils de plus grande borne UCB1.
+func (n *mctsNode) selectChild(exploration float64) *mctsNode {
+	logVisits := math.Log(float64(n.visits))
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	for _, child := range n.children {
+		v := float64(child.visits)
+		value := child.reward/v + exploration*math.Sqrt(logVisits/v)
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
+
+// BestMove implémente Engine. Un coup gagnant immédiatement est joué sans
+// simulation ; à l'échéance de ctx, le coup le plus visité jusque-là est
+// renvoyé.
+func (m *MCTS) BestMove(ctx context.Context, b *Board, player string) (Move, error) {
+	if b.gameOver() || !b.hasMove(player) {
+		return Move{Column: -1}, ErrGameOver
+	}
+	if m.Rand == nil {
+		m.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if column := winningColumn(b, player); column >= 0 {
+		return Move{Column: column, Player: player}, nil
+	}
+	iterations := m.Iterations
+	if iterations <= 0 {
+		iterations = DefaultMCTSIterations
+	}
+	exploration := m.Exploration
+	if exploration <= 0 {
+		exploration = DefaultExploration
+	}
+
+	root := newMCTSNode(nil, b, -1, opponent(player))
+	for i := 0; i < iterations; i++ {
+		if i > 0 && i%mctsCheckInterval == 0 && ctx.Err() != nil {
+			break
+		}
+		board := b.copyOfBoard()
+		node := root
+		for len(node.untried) == 0 && len(node.children) > 0 {
+			node = node.selectChild(exploration)
+			board.playMove(node.move, node.player)
+		}
+		if len(node.untried) > 0 {
+			k := m.Rand.Intn(len(node.untried))
+			move := node.untried[k]
+			node.untried[k] = node.untried[len(node.untried)-1]
+			node.untried = node.untried[:len(node.untried)-1]
+			next := opponent(node.player)
+			board.playMove(move, next)
+			child := newMCTSNode(node, board, move, next)
+			node.children = append(node.children, child)
+			node = child
+		}
+		winner := m.rollout(board, opponent(node.player))
+		for n := node; n != nil; n = n.parent {
+			n.visits++
+			if winner == n.player {
+				n.reward++
+			} else if winner == "" {
+				n.reward += 0.5
+			}
+		}
+	}
+
+	best := root.children[0]
+	for _, child := range root.children[1:] {
+		if child.visits > best.visits {
+			best = child
+		}
+	}
+	return b.searchMove(best.move, player), nil
+}
+
+// rollout termine la partie sur b, player ayant le trait, et renvoie le
+// symbole du gagnant ("" en cas de nul). b est modifié. En PopOut, une
+// simulation est arrêtée (nulle) après deux fois plus de coups que de cases.
+func (m *MCTS) rollout(b *Board, player string) string {
+	if winner := b.winner(opponent(player)); winner != "" {
+		return winner
+	}
+	maxMoves := b.geo.cells() - b.movesMade
+	if b.variant == PopOut {
+		maxMoves = 2 * b.geo.cells()
+	}
+	for n := 0; n < maxMoves && b.hasMove(player); n++ {
+		move := -1
+		if m.Rollout == RolloutHeuristic {
+			if move = winningColumn(b, player); move < 0 {
+				move = winningColumn(b, opponent(player))
+			}
+		}
+		if move < 0 {
+			move = m.randomMove(b, player)
+		}
+		b.playMove(move, player)
+		if winner := b.winner(player); winner != "" {
+			return winner
+		}
+		player = opponent(player)
+	}
+	return ""
+}
+
+// randomMove renvoie un coup de player (indice de Board.playMove) choisi
+// au hasard parmi les coups possibles sur b.
+func (m *MCTS) randomMove(b *Board, player string) int {
+	var playable [2 * MaxWidth]int
+	n := 0
+	for move := 0; move < b.searchMoves(); move++ {
+		if b.canPlayMove(move, player) {
+			playable[n] = move
+			n++
+		}
+	}
+	return playable[m.Rand.Intn(n)]
+}
+
+// winningColumn renvoie une colonne où player gagne immédiatement par un
+// dépôt, ou -1 s'il n'y en a pas.
+func winningColumn(b *Board, player string) int {
+	p := playerIndex(player)
+	if p < 0 {
+		return -1
+	}
+	for c := 0; c < b.geo.width; c++ {
+		if b.canPlay(c) && b.geo.hasAlignment(b.players[p]|uint64(1)<<(c*b.geo.height+b.col[c])) {
+			return c
+		}
+	}
+	return -1
+}
+
+// setRand implémente seededEngine.
+func (m *MCTS) setRand(r *rand.Rand) {
+	m.Rand = r
+}
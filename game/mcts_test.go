@@ -0,0 +1,124 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// newSeededMCTS crée un moteur MCTS au générateur aléatoire déterministe.
+func newSeededMCTS(iterations int, rollout RolloutPolicy) *MCTS {
+	m := NewMCTS(iterations, 0, rollout)
+	m.Rand = rand.New(rand.NewSource(1))
+	return m
+}
+
+// playEngines fait jouer une partie entre deux moteurs et renvoie le
+// symbole du gagnant ("" en cas de nul).
+func playEngines(t *testing.T, first, second Engine) string {
+	t.Helper()
+	b := newStandardBoard()
+	engines := map[string]Engine{PlayerOneColor: first, PlayerTwoColor: second}
+	player := PlayerOneColor
+	for !b.gameOver() {
+		move, err := engines[player].BestMove(context.Background(), b, player)
+		if err != nil {
+			t.Fatalf("BestMove: %v", err)
+		}
+		if !b.Drop(move.Column, player) {
+			t.Fatalf("engine played illegal column %d", move.Column)
+		}
+		if b.areFourConnected(player) {
+			return player
+		}
+		player = opponent(player)
+	}
+	return ""
+}
+
+func TestMCTSTakesImmediateWin(t *testing.T) {
+	b := newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 2)
+	move, err := newSeededMCTS(100, RolloutRandom).BestMove(context.Background(), b, PlayerOneColor)
+	if err != nil {
+		t.Fatalf("BestMove: %v", err)
+	}
+	if move.Column != 0 {
+		t.Fatalf("expected the winning column 0, got %d", move.Column)
+	}
+}
+
+func TestMCTSBlocksImmediateLoss(t *testing.T) {
+	for _, rollout := range []RolloutPolicy{RolloutRandom, RolloutHeuristic} {
+		b := newStandardBoard()
+		playMoves(t, b, 0, 1, 0, 1, 0)
+		move, err := newSeededMCTS(5000, rollout).BestMove(context.Background(), b, PlayerTwoColor)
+		if err != nil {
+			t.Fatalf("rollout %d: BestMove: %v", rollout, err)
+		}
+		if move.Column != 0 {
+			t.Fatalf("rollout %d: expected the blocking column 0, got %d", rollout, move.Column)
+		}
+	}
+}
+
+func TestMCTSCancelledContextStillMoves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := newStandardBoard()
+	move, err := NewMCTS(0, 0, RolloutHeuristic).BestMove(ctx, b, PlayerOneColor)
+	if err != nil {
+		t.Fatalf("BestMove: %v", err)
+	}
+	if !b.Drop(move.Column, PlayerOneColor) {
+		t.Fatalf("expected a legal column, got %d", move.Column)
+	}
+}
+
+func TestMCTSGameOver(t *testing.T) {
+	b := newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 1, 0)
+	if _, err := NewMCTS(100, 0, RolloutRandom).BestMove(context.Background(), b, PlayerTwoColor); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected ErrGameOver, got %v", err)
+	}
+}
+
+func TestGameManagerWithEngine(t *testing.T) {
+	gm := NewGameManager(true, 1, WithEngine(newSeededMCTS(500, RolloutHeuristic)))
+	for _, c := range []int{3, 3, 2} {
+		if ok, err := gm.MakePlayerTurn(c); !ok {
+			t.Fatalf("MakePlayerTurn(%d): %v", c, err)
+		}
+		if gm.GetState() != Running {
+			t.Fatalf("unexpected end of game")
+		}
+		if _, err := gm.MakeOpponentTurn(-1); err != nil {
+			t.Fatalf("MakeOpponentTurn: %v", err)
+		}
+	}
+	if gm.turn != 6 {
+		t.Fatalf("expected 6 moves to be played, got %d", gm.turn)
+	}
+}
+
+func TestMCTSAgainstAlphaBeta(t *testing.T) {
+	if testing.Short() {
+		t.Skip("engine match skipped in short mode")
+	}
+	mcts := newSeededMCTS(3000, RolloutHeuristic)
+	alphaBeta := NewAlphaBeta(6)
+	results := map[string]int{}
+	for game := 0; game < 2; game++ {
+		var winner string
+		if game%2 == 0 {
+			winner = playEngines(t, mcts, alphaBeta)
+			winner = map[string]string{PlayerOneColor: "mcts", PlayerTwoColor: "alpha-beta", "": "draw"}[winner]
+		} else {
+			winner = playEngines(t, alphaBeta, mcts)
+			winner = map[string]string{PlayerOneColor: "alpha-beta", PlayerTwoColor: "mcts", "": "draw"}[winner]
+		}
+		results[winner]++
+	}
+	t.Logf("mcts vs alpha-beta: %v", results)
+}
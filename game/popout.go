@@ -0,0 +1,159 @@
+package game
+
+// Variant désigne les règles d'une partie.
+type Variant int
+
+const (
+	// Classic est le Puissance 4 classique : chaque coup dépose un jeton.
+	Classic Variant = iota
+	// PopOut permet aussi à un joueur de retirer un de ses jetons du bas
+	// d'une colonne, les jetons au-dessus descendant d'une case. Si un
+	// retrait aligne des jetons des deux joueurs, celui qui a retiré le
+	// jeton gagne ; une position répétée trois fois est une partie nulle.
+	PopOut
+)
+
+// String renvoie le nom de la variante tel qu'il apparaît dans les
+// sauvegardes.
+func (v Variant) String() string {
+	if v == PopOut {
+		return "popout"
+	}
+	return "classic"
+}
+
+// Variant renvoie les règles du plateau.
+func (b *Board) Variant() Variant {
+	return b.variant
+}
+
+// columnMask renvoie le masque des cases de la colonne.
+func (g *geometry) columnMask(column int) uint64 {
+	return (uint64(1)<<g.height - 1) << (column * g.height)
+}
+
+// canPop indique si player peut retirer le jeton du bas de la colonne :
+// la variante doit être PopOut et ce jeton doit lui appartenir.
+func (b *Board) canPop(column int, player string) bool {
+	p := playerIndex(player)
+	return b.variant == PopOut && p >= 0 && column >= 0 && column < b.geo.width &&
+		b.players[p]&(uint64(1)<<(column*b.geo.height)) != 0
+}
+
+// toggleColumnHash retire de la clé Zobrist (ou y ajoute) les jetons de la
+// colonne.
+func (b *Board) toggleColumnHash(column int) {
+	for r := 0; r < b.col[column]; r++ {
+		idx := column*b.geo.height + r
+		if b.players[0]&(uint64(1)<<idx) != 0 {
+			b.hash ^= zobristKeys[0][idx]
+		} else {
+			b.hash ^= zobristKeys[1][idx]
+		}
+	}
+}
+
+// Pop tente de retirer le jeton de player situé en bas de la colonne, les
+// jetons au-dessus descendant d'une case. Retourne false si le coup est
+// interdit (variante autre que PopOut, colonne invalide ou jeton du bas
+// n'appartenant pas au joueur).
+func (b *Board) Pop(column int, player string) bool {
+	if !b.canPop(column, player) {
+		return false
+	}
+	mask := b.geo.columnMask(column)
+	bottom := uint64(1) << (column * b.geo.height)
+	b.toggleColumnHash(column)
+	for p := range b.players {
+		b.players[p] = b.players[p]&^mask | (b.players[p]&mask&^bottom)>>1
+	}
+	b.col[column]--
+	b.movesMade--
+	b.toggleColumnHash(column)
+	return true
+}
+
+// undoPop annule le retrait par player du jeton du bas de la colonne.
+func (b *Board) undoPop(column int, player string) {
+	mask := b.geo.columnMask(column)
+	b.toggleColumnHash(column)
+	for p := range b.players {
+		b.players[p] = b.players[p]&^mask | (b.players[p]&mask)<<1
+	}
+	b.players[playerIndex(player)] |= uint64(1) << (column * b.geo.height)
+	b.col[column]++
+	b.movesMade++
+	b.toggleColumnHash(column)
+}
+
+// hasMove indique si player dispose d'un coup : une colonne non pleine
+// ou, en PopOut, un jeton à retirer.
+func (b *Board) hasMove(player string) bool {
+	if b.movesMade < b.geo.cells() {
+		return true
+	}
+	for c := 0; c < b.geo.width; c++ {
+		if b.canPop(c, player) {
+			return true
+		}
+	}
+	return false
+}
+
+// winner renvoie le gagnant de la position atteinte après un coup de
+// mover, ou "" s'il n'y en a pas. Un retrait pouvant aligner des jetons des
+// deux joueurs, mover l'emporte dans ce cas.
+func (b *Board) winner(mover string) string {
+	if b.areFourConnected(mover) {
+		return mover
+	}
+	if other := opponent(mover); b.areFourConnected(other) {
+		return other
+	}
+	return ""
+}
+
+// searchMoves renvoie le nombre d'indices de coups utilisés par les
+// moteurs de recherche : un dépôt par colonne, plus un retrait par colonne
+// en PopOut (voir playMove).
+func (b *Board) searchMoves() int {
+	if b.variant == PopOut {
+		return 2 * b.geo.width
+	}
+	return b.geo.width
+}
+
+// playMove joue le coup d'indice m pour player : un dépôt dans la colonne
+// m si m est inférieur à la largeur du plateau, le retrait du jeton du bas
+// de la colonne m-width sinon. Retourne false si le coup est interdit.
+func (b *Board) playMove(m int, player string) bool {
+	if m < b.geo.width {
+		return b.Drop(m, player)
+	}
+	return b.Pop(m-b.geo.width, player)
+}
+
+// canPlayMove indique si player peut jouer le coup d'indice m.
+func (b *Board) canPlayMove(m int, player string) bool {
+	if m < b.geo.width {
+		return b.canPlay(m)
+	}
+	return b.canPop(m-b.geo.width, player)
+}
+
+// undoMove annule le coup d'indice m joué par player.
+func (b *Board) undoMove(m int, player string) {
+	if m < b.geo.width {
+		b.undoDrop(m)
+	} else {
+		b.undoPop(m-b.geo.width, player)
+	}
+}
+
+// searchMove convertit un indice de coup (voir playMove) en Move.
+func (b *Board) searchMove(m int, player string) Move {
+	if m >= b.geo.width {
+		return Move{Column: m - b.geo.width, Kind: MovePop, Player: player}
+	}
+	return Move{Column: m, Player: player}
+}
@@ -0,0 +1,227 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+)
+
+// newPopOutBoard crée un plateau standard aux règles PopOut.
+func newPopOutBoard() *Board {
+	b := newStandardBoard()
+	b.variant = PopOut
+	return b
+}
+
+func TestPopShiftsColumnAndUndo(t *testing.T) {
+	b := newPopOutBoard()
+	b.Drop(3, PlayerOneColor)
+	b.Drop(3, PlayerTwoColor)
+	b.Drop(3, PlayerOneColor)
+	b.Drop(2, PlayerTwoColor)
+	before := *b.copyOfBoard()
+
+	if b.Pop(3, PlayerTwoColor) || b.Pop(0, PlayerOneColor) || b.Pop(7, PlayerOneColor) {
+		t.Fatalf("expected pops of an opponent disc, an empty column or an invalid column to fail")
+	}
+	if !b.Pop(3, PlayerOneColor) {
+		t.Fatalf("expected PlayerOneColor to pop its bottom disc")
+	}
+	// la colonne a descendu d'une case : la clé Zobrist doit être celle du
+	// même plateau construit directement
+	want := newPopOutBoard()
+	want.Drop(3, PlayerTwoColor)
+	want.Drop(3, PlayerOneColor)
+	want.Drop(2, PlayerTwoColor)
+	if b.players != want.players || b.hash != want.hash || b.col[3] != 2 || b.movesMade != 3 {
+		t.Fatalf("unexpected board after pop: players=%v hash=%x col=%v", b.players, b.hash, b.col)
+	}
+
+	b.undoPop(3, PlayerOneColor)
+	if b.players != before.players || b.hash != before.hash || !slices.Equal(b.col, before.col) || b.movesMade != before.movesMade {
+		t.Fatalf("undoPop did not restore the board")
+	}
+
+	classic := newStandardBoard()
+	classic.Drop(0, PlayerOneColor)
+	if classic.Pop(0, PlayerOneColor) {
+		t.Fatalf("expected pops to be refused with classic rules")
+	}
+}
+
+func TestPopOutSimultaneousFourWinsForPopper(t *testing.T) {
+	// colonnes 0 à 2 : PlayerTwoColor en bas et PlayerOneColor au-dessus ;
+	// colonne 3 : PlayerOneColor, PlayerTwoColor puis PlayerOneColor. Le
+	// retrait du bas de la colonne 3 aligne les deux joueurs.
+	gm := NewGameManager(false, 0, WithVariant(PopOut))
+	for _, c := range []int{3, 0, 0, 1, 1, 2, 2, 3, 3, 6} {
+		gm.MakePlayerTurn(c)
+	}
+	if ok, err := gm.MakePlayerPop(3); !ok {
+		t.Fatalf("MakePlayerPop: %v", err)
+	}
+	if gm.GetState() != Win || gm.winner != PlayerOneColor {
+		t.Fatalf("expected the popping player to win, got state %v winner %q", gm.GetState(), gm.winner)
+	}
+	if last := gm.History()[len(gm.History())-1]; last.Kind != MovePop || last.Column != 3 {
+		t.Fatalf("unexpected last move %+v", last)
+	}
+
+	// sans le jeton du haut de la colonne 3, seul l'adversaire est aligné
+	gm = NewGameManager(false, 0, WithVariant(PopOut))
+	for _, c := range []int{3, 0, 0, 1, 1, 2, 2, 3} {
+		gm.MakePlayerTurn(c)
+	}
+	gm.MakePlayerPop(3)
+	if gm.GetState() != Lose || gm.winner != PlayerTwoColor {
+		t.Fatalf("expected the opponent to win, got state %v winner %q", gm.GetState(), gm.winner)
+	}
+	// l'annulation du retrait rétablit la partie
+	if !gm.Undo() || gm.GetState() != Running || gm.GetLostGames() != 0 || gm.GetHoleColor(boardHeight-1, 3) != PlayerOneColor {
+		t.Fatalf("Undo did not restore the game before the pop")
+	}
+}
+
+func TestPopOutRepetitionIsATie(t *testing.T) {
+	gm := NewGameManager(false, 0, WithVariant(PopOut))
+	cycle := func() {
+		gm.MakePlayerTurn(0)
+		gm.MakePlayerTurn(1)
+		gm.MakePlayerPop(0)
+		gm.MakePlayerPop(1)
+	}
+	cycle()
+	cycle()
+	if gm.GetState() != Tie {
+		t.Fatalf("expected a tie on the third empty board, got %v after %q", gm.GetState(), gm.MoveString())
+	}
+	gm.Undo()
+	if gm.GetState() != Running {
+		t.Fatalf("expected Undo to cancel the repetition tie")
+	}
+}
+
+func TestPopOutFullBoard(t *testing.T) {
+	// plateau 3x2 rempli sans alignement de trois jetons
+	moves := []int{0, 1, 2, 0, 1, 2}
+	classic := NewGameManager(false, 0, WithBoardSize(3, 2, 3))
+	popout := NewGameManager(false, 0, WithBoardSize(3, 2, 3), WithVariant(PopOut))
+	for _, c := range moves {
+		classic.MakePlayerTurn(c)
+		popout.MakePlayerTurn(c)
+	}
+	if classic.GetState() != Tie {
+		t.Fatalf("expected a full classic board to be a tie, got %v", classic.GetState())
+	}
+	if popout.GetState() != Running {
+		t.Fatalf("expected a full PopOut board to go on, got %v", popout.GetState())
+	}
+	if ok, err := popout.MakePlayerPop(0); !ok {
+		t.Fatalf("MakePlayerPop: %v", err)
+	}
+
+	// sans jeton en bas du plateau, le joueur au trait ne peut plus jouer
+	b := NewBoard(2, 4, 4)
+	b.variant = PopOut
+	for c := 0; c < 2; c++ {
+		for _, p := range []string{PlayerTwoColor, PlayerOneColor, PlayerTwoColor, PlayerOneColor} {
+			b.Drop(c, p)
+		}
+	}
+	if b.hasMove(PlayerOneColor) || !b.hasMove(PlayerTwoColor) {
+		t.Fatalf("expected only PlayerTwoColor to have a move")
+	}
+}
+
+// popWinBoard renvoie une position PopOut où PlayerTwoColor, au trait, ne
+// gagne qu'en retirant le jeton du bas de la colonne 3.
+func popWinBoard() *Board {
+	b := newPopOutBoard()
+	for c := 0; c < 3; c++ {
+		b.Drop(c, PlayerOneColor)
+		b.Drop(c, PlayerTwoColor)
+	}
+	b.Drop(3, PlayerTwoColor)
+	b.Drop(3, PlayerOneColor)
+	b.Drop(3, PlayerTwoColor)
+	b.Drop(6, PlayerOneColor)
+	b.Drop(6, PlayerOneColor)
+	return b
+}
+
+func TestEnginesFindWinningPop(t *testing.T) {
+	want := Move{Column: 3, Kind: MovePop, Player: PlayerTwoColor}
+	for name, engine := range map[string]Engine{
+		"alphabeta": &AlphaBeta{Depth: 4},
+		"mcts":      newSeededMCTS(2000, RolloutHeuristic),
+	} {
+		b := popWinBoard()
+		move, err := engine.BestMove(context.Background(), b, PlayerTwoColor)
+		if err != nil {
+			t.Fatalf("%s: BestMove: %v", name, err)
+		}
+		if move != want {
+			t.Fatalf("%s: expected %+v, got %+v", name, want, move)
+		}
+	}
+}
+
+func TestAIPlaysPopOutGame(t *testing.T) {
+	for _, engine := range []Engine{nil, newSeededMCTS(200, RolloutRandom)} {
+		opts := []Option{WithVariant(PopOut), WithSeed(5)}
+		if engine != nil {
+			opts = append(opts, WithEngine(engine))
+		}
+		gm := NewGameManager(true, 3, opts...)
+		for turn := 0; gm.GetState() == Running && turn < 200; turn++ {
+			if turn%2 == 0 {
+				var played bool
+				for c := 0; c < boardWidth && !played; c++ {
+					played, _ = gm.MakePlayerTurn((turn + c) % boardWidth)
+				}
+				for c := 0; c < boardWidth && !played; c++ {
+					played, _ = gm.MakePlayerPop(c)
+				}
+				continue
+			}
+			if _, err := gm.MakeOpponentTurn(-1); err != nil {
+				t.Fatalf("MakeOpponentTurn: %v", err)
+			}
+		}
+	}
+}
+
+func TestSaveLoadPopOut(t *testing.T) {
+	gm := NewGameManager(false, 0, WithVariant(PopOut))
+	for _, c := range []int{3, 4, 3} {
+		gm.MakePlayerTurn(c)
+	}
+	gm.MakePlayerPop(4)
+	if got := gm.MoveString(); got != "454p5" {
+		t.Fatalf("unexpected move string %q", got)
+	}
+	var buf bytes.Buffer
+	if err := gm.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Variant() != PopOut || !slices.Equal(loaded.History(), gm.History()) {
+		t.Fatalf("unexpected loaded game: %v %v", loaded.Variant(), loaded.History())
+	}
+
+	moves, err := ParseMoveString("4p4")
+	if err != nil || !slices.Equal(moves, []Move{{Column: 3}, {Column: 3, Kind: MovePop}}) {
+		t.Fatalf("unexpected parse result %v (err %v)", moves, err)
+	}
+	// un retrait n'est accepté que dans une partie PopOut
+	if _, err := Load(bytes.NewBufferString(`{"version": 1, "mode": "local", "variant": "popout", "moves": "45p4"}`)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := Load(bytes.NewBufferString(`{"version": 1, "mode": "local", "moves": "45p4"}`)); err == nil {
+		t.Fatalf("expected a pop to be refused in a classic save")
+	}
+}
@@ -0,0 +1,65 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// MoveReview est l'analyse d'un coup d'une partie (voir Analyzer.Review).
+// Les valeurs sont du point de vue du joueur qui a joué le coup.
+type MoveReview struct {
+	Move      Move     // Coup joué
+	Best      MoveEval // Meilleur coup de la position : sa valeur est celle de la position avant le coup
+	Played    MoveEval // Valeur du coup joué, c'est-à-dire de la position après le coup
+	Blunder   bool     // Le coup perd une position qui n'était pas perdue
+	MissedWin bool     // Une victoire forcée était possible et le coup ne gagne pas
+}
+
+// Review analyse les coups de la partie gm, du premier au dernier, dans
+// l'état où elle se trouve à l'appel, et appelle progress (s'il n'est pas
+// nil) après chacun d'eux. Un coup est une gaffe (Blunder) s'il mène à une
+// défaite forcée alors que le meilleur coup n'en était pas une, et manque
+// une victoire (MissedWin) si le meilleur coup gagnait et pas lui. À
+// l'échéance de ctx, l'analyse s'arrête et renvoie les coups analysés avec
+// l'erreur de ctx.
+func (a *Analyzer) Review(ctx context.Context, gm *GameManager, progress func(MoveReview)) ([]MoveReview, error) {
+	gm.mu.RLock()
+	history := slices.Clone(gm.history)
+	b := NewBoard(gm.board.Width(), gm.board.Height(), gm.board.ConnectN())
+	b.variant = gm.board.variant
+	gm.mu.RUnlock()
+
+	lost := func(e MoveEval) bool { return e.Exact && e.Outcome == OutcomeLoss }
+	won := func(e MoveEval) bool { return e.Exact && e.Outcome == OutcomeWin }
+	var reviews []MoveReview
+	for i, m := range history {
+		evals, err := a.Analyze(ctx, b, m.Player)
+		if ctx.Err() != nil {
+			// une analyse interrompue n'est pas fiable
+			return reviews, ctx.Err()
+		}
+		if err != nil {
+			return reviews, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		played := slices.IndexFunc(evals, func(e MoveEval) bool {
+			return e.Move.Column == m.Column && e.Move.Kind == m.Kind
+		})
+		if played < 0 {
+			return reviews, fmt.Errorf("move %d: column %d cannot be played", i+1, m.Column+1)
+		}
+		r := MoveReview{Move: m, Best: evals[BestEval(evals)], Played: evals[played]}
+		r.Blunder = lost(r.Played) && !lost(r.Best)
+		r.MissedWin = won(r.Best) && !won(r.Played)
+		reviews = append(reviews, r)
+		if progress != nil {
+			progress(r)
+		}
+		if m.Kind == MovePop {
+			b.Pop(m.Column, m.Player)
+		} else {
+			b.Drop(m.Column, m.Player)
+		}
+	}
+	return reviews, nil
+}
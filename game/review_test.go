@@ -0,0 +1,41 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReview(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	// O laisse X aligner quatre jetons dans la colonne 1, puis X ne gagne
+	// pas et bloque la colonne 2
+	if err := gm.PlayMoves("1212122"); err != nil {
+		t.Fatalf("PlayMoves: %v", err)
+	}
+	a := &Analyzer{Depth: 4}
+	var calls int
+	reviews, err := a.Review(context.Background(), gm, func(MoveReview) { calls++ })
+	if err != nil || len(reviews) != 7 || calls != 7 {
+		t.Fatalf("Review: %v (%d moves, %d calls)", err, len(reviews), calls)
+	}
+	for i, r := range reviews[:5] {
+		if r.Blunder || r.MissedWin {
+			t.Fatalf("move %d: unexpected flags %+v", i+1, r)
+		}
+	}
+	r := reviews[5]
+	if !r.Blunder || r.MissedWin || r.Move.Player != PlayerTwoColor || r.Best.Move.Column != 0 || r.Played.String() != "loss in 2" {
+		t.Fatalf("expected the sixth move to be a blunder: %+v", r)
+	}
+	r = reviews[6]
+	if r.Blunder || !r.MissedWin || r.Best.String() != "win in 1" || r.Played.Move.Column != 1 {
+		t.Fatalf("expected the seventh move to miss a win: %+v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if reviews, err := a.Review(ctx, gm, nil); !errors.Is(err, context.Canceled) || len(reviews) != 0 {
+		t.Fatalf("expected the review to stop at once, got %d moves (%v)", len(reviews), err)
+	}
+}
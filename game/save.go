@@ -0,0 +1,281 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// saveVersion est la version du format de sauvegarde écrit par Save.
+const saveVersion = 1
+
+// maxSavedDepth borne la profondeur de recherche de l'IA acceptée par
+// Load : au-delà, une recherche à profondeur fixe ne se termine pas en un
+// temps raisonnable.
+const maxSavedDepth = 20
+
+// ErrInvalidSave est renvoyée lorsqu'une sauvegarde ne peut pas être lue
+// ou décrit une partie impossible.
+var ErrInvalidSave = errors.New("invalid saved game")
+
+// savedGame est la représentation JSON d'une partie.
+//
+// Board décrit le plateau ligne par ligne, de haut en bas : 'X' pour
+// PlayerOneColor, 'O' pour PlayerTwoColor et '.' pour une case vide. Il est
+// redondant avec Moves et sert à vérifier la sauvegarde (il peut être omis
+// dans un fichier écrit à la main, de même que les dimensions d'un plateau
+// standard). Difficulty est la profondeur de recherche de l'IA (le
+// paramètre aiDiff de NewGameManager), et non le niveau de
+// Settings.Difficulty. Opponent indique quel joueur ("first" ou "second")
+// a joué par MakeOpponentTurn.
+type savedGame struct {
+	Version    int      `json:"version"`
+	Mode       string   `json:"mode"`              // "ai" ou "local"
+	Variant    string   `json:"variant,omitempty"` // "classic" (par défaut) ou "popout"
+	Width      int      `json:"width,omitempty"`
+	Height     int      `json:"height,omitempty"`
+	Connect    int      `json:"connect,omitempty"`
+	Difficulty int      `json:"difficulty"` // Profondeur de recherche de l'IA (aiDiff)
+	Perfect    bool     `json:"perfect,omitempty"`
+	Seed       int64    `json:"seed"`
+	Workers    int      `json:"workers,omitempty"` // Goroutines de la recherche de l'IA (0 : une seule)
+	Won        int      `json:"won"`
+	Lost       int      `json:"lost"`
+	Moves      string   `json:"moves"`
+	Opponent   string   `json:"opponent,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"` // Fin de partie au temps (voir TimeOut) : "win" si le perdant est l'adversaire, "loss" sinon
+	Board      []string `json:"board"`
+}
+
+// MoveString renvoie les coups de la partie en notation compacte : la
+// colonne de chaque coup, numérotée à partir de 1 et précédée de 'p' pour
+// un retrait (par exemple "4453" ou "4453p4" en PopOut).
+func (gm *GameManager) MoveString() string {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return FormatMoves(gm.history)
+}
+
+// FormatMoves renvoie les coups fournis en notation compacte (voir
+// MoveString).
+func FormatMoves(moves []Move) string {
+	var sb strings.Builder
+	for _, m := range moves {
+		if m.Kind == MovePop {
+			sb.WriteByte('p')
+		}
+		sb.WriteByte(byte('1' + m.Column))
+	}
+	return sb.String()
+}
+
+// ParseMoveString lit une suite de coups en notation compacte (voir
+// MoveString) et renvoie les coups, colonnes numérotées à partir de 0 ;
+// seuls les champs Column et Kind sont renseignés. Les coups ne sont pas
+// rejoués : une colonne pleine, ou absente d'un plateau de moins de
+// MaxWidth colonnes, n'est pas détectée.
+func ParseMoveString(s string) ([]Move, error) {
+	moves := make([]Move, 0, len(s))
+	kind := MoveDrop
+	for _, r := range s {
+		if r == 'p' && kind == MoveDrop {
+			kind = MovePop
+			continue
+		}
+		c := int(r - '1')
+		if c < 0 || c >= MaxWidth {
+			return nil, fmt.Errorf("move %d: invalid column %q", len(moves)+1, r)
+		}
+		moves = append(moves, Move{Column: c, Kind: kind})
+		kind = MoveDrop
+	}
+	if kind == MovePop {
+		return nil, fmt.Errorf("move %d: missing column after 'p'", len(moves)+1)
+	}
+	return moves, nil
+}
+
+// PlayMoves joue les coups fournis en notation compacte (voir MoveString)
+// comme des coups du joueur, par MakePlayerTurn et MakePlayerPop. Les coups
+// précédant un coup illégal restent joués.
+func (gm *GameManager) PlayMoves(s string) error {
+	moves, err := ParseMoveString(s)
+	if err != nil {
+		return err
+	}
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	for i, m := range moves {
+		if gm.state != Running {
+			return fmt.Errorf("move %d played after the end of the game", i+1)
+		}
+		if !gm.play(m.Kind, m.Column, false) {
+			return fmt.Errorf("move %d: column %d cannot be played", i+1, m.Column+1)
+		}
+	}
+	return nil
+}
+
+// boardRows renvoie le plateau ligne par ligne au format de savedGame.Board.
+func (b *Board) boardRows() []string {
+	rows := make([]string, b.geo.height)
+	for i := range rows {
+		var sb strings.Builder
+		for j := 0; j < b.geo.width; j++ {
+			switch b.cell(i, j) {
+			case PlayerOneColor:
+				sb.WriteByte('X')
+			case PlayerTwoColor:
+				sb.WriteByte('O')
+			default:
+				sb.WriteByte('.')
+			}
+		}
+		rows[i] = sb.String()
+	}
+	return rows
+}
+
+// Save écrit la partie en cours au format JSON : mode de jeu, variante,
+// dimensions du plateau, difficulté de l'IA, scores, graine et nombre de
+// goroutines de la recherche (dont dépend la reproduction de la partie à
+// partir de sa graine), coups joués, fin au temps et plateau. Les coups annulés et le
+// reste de la configuration du moteur (table de transposition, temps de
+// réflexion…) ne sont pas sauvegardés.
+func (gm *GameManager) Save(w io.Writer) error {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	sg := savedGame{
+		Version:    saveVersion,
+		Mode:       "local",
+		Width:      gm.board.geo.width,
+		Height:     gm.board.geo.height,
+		Connect:    gm.board.geo.connect,
+		Difficulty: gm.aiDiff,
+		Perfect:    gm.alphaBeta.Solver != nil,
+		Seed:       gm.seed,
+		Workers:    gm.alphaBeta.Workers,
+		Won:        gm.wonGames,
+		Lost:       gm.lostGames,
+		Moves:      FormatMoves(gm.history),
+		Board:      gm.board.boardRows(),
+	}
+	if gm.timedOut {
+		sg.Timeout = "loss"
+		if gm.state == Win {
+			sg.Timeout = "win"
+		}
+	}
+	if gm.ai {
+		sg.Mode = "ai"
+	}
+	if gm.board.variant != Classic {
+		sg.Variant = gm.board.variant.String()
+	}
+	for _, m := range gm.history {
+		if !m.Opponent {
+			continue
+		}
+		parity := "first"
+		if m.Turn%2 == 1 {
+			parity = "second"
+		}
+		if sg.Opponent != "" && sg.Opponent != parity {
+			return errors.New("cannot save a game where both players moved as the opponent")
+		}
+		sg.Opponent = parity
+	}
+	data, err := json.MarshalIndent(sg, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Load lit une partie écrite par Save et renvoie un gestionnaire dans
+// lequel ses coups ont été rejoués. Les options configurent l'IA comme
+// pour NewGameManager ; la graine et le nombre de goroutines de la
+// recherche de la partie sauvegardée sont utilisés. La source aléatoire
+// de l'IA repart toutefois de la graine, et sa table de transposition est
+// vide : la suite d'une partie chargée n'est pas celle qu'aurait jouée
+// l'IA de la partie d'origine. Seule une partie rejouée depuis son début
+// avec la même graine est reproductible.
+// Une sauvegarde dont les coups sont illégaux, ou dont le plateau ne
+// correspond pas aux coups, est refusée avec ErrInvalidSave.
+func Load(r io.Reader, opts ...Option) (*GameManager, error) {
+	var sg savedGame
+	if err := json.NewDecoder(r).Decode(&sg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSave, err)
+	}
+	if sg.Version != saveVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSave, sg.Version)
+	}
+	if sg.Mode != "ai" && sg.Mode != "local" {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidSave, sg.Mode)
+	}
+	if sg.Mode == "ai" && (sg.Difficulty < 1 || sg.Difficulty > maxSavedDepth) {
+		return nil, fmt.Errorf("%w: AI search depth %d out of range [1, %d]", ErrInvalidSave, sg.Difficulty, maxSavedDepth)
+	}
+	if sg.Won < 0 || sg.Lost < 0 {
+		return nil, fmt.Errorf("%w: negative score", ErrInvalidSave)
+	}
+	variant := Classic
+	switch sg.Variant {
+	case "", Classic.String():
+	case PopOut.String():
+		variant = PopOut
+	default:
+		return nil, fmt.Errorf("%w: unknown variant %q", ErrInvalidSave, sg.Variant)
+	}
+	switch sg.Timeout {
+	case "", "win", "loss":
+	default:
+		return nil, fmt.Errorf("%w: unknown timeout %q", ErrInvalidSave, sg.Timeout)
+	}
+	opponentParity := -1
+	switch sg.Opponent {
+	case "":
+	case "first":
+		opponentParity = 0
+	case "second":
+		opponentParity = 1
+	default:
+		return nil, fmt.Errorf("%w: unknown opponent %q", ErrInvalidSave, sg.Opponent)
+	}
+	if sg.Width == 0 && sg.Height == 0 && sg.Connect == 0 {
+		sg.Width, sg.Height, sg.Connect = DefaultWidth, DefaultHeight, DefaultConnect
+	}
+	if err := CheckGeometry(sg.Width, sg.Height, sg.Connect); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSave, err)
+	}
+	moves, err := ParseMoveString(sg.Moves)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSave, err)
+	}
+
+	opts = append(slices.Clip(opts), WithSeed(sg.Seed), WithWorkers(sg.Workers), WithBoardSize(sg.Width, sg.Height, sg.Connect), WithVariant(variant))
+	if sg.Perfect {
+		opts = append(opts, WithPerfectPlay())
+	}
+	gm := NewGameManager(sg.Mode == "ai", sg.Difficulty, opts...)
+	for i, m := range moves {
+		if gm.state != Running {
+			return nil, fmt.Errorf("%w: move %d played after the end of the game", ErrInvalidSave, i+1)
+		}
+		if !gm.play(m.Kind, m.Column, i%2 == opponentParity) {
+			return nil, fmt.Errorf("%w: move %d: column %d cannot be played", ErrInvalidSave, i+1, m.Column+1)
+		}
+	}
+	if sg.Timeout != "" && !gm.TimeOut(sg.Timeout == "win") {
+		return nil, fmt.Errorf("%w: timeout after the end of the game", ErrInvalidSave)
+	}
+	if sg.Board != nil && !slices.Equal(sg.Board, gm.board.boardRows()) {
+		return nil, fmt.Errorf("%w: board does not match the moves", ErrInvalidSave)
+	}
+	gm.wonGames, gm.lostGames = sg.Won, sg.Lost
+	return gm, nil
+}
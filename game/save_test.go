@@ -0,0 +1,145 @@
+package game
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMoveStringRoundTrip(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	for _, c := range []int{3, 3, 4, 2} {
+		gm.MakePlayerTurn(c)
+	}
+	if got := gm.MoveString(); got != "4453" {
+		t.Fatalf("expected move string 4453, got %q", got)
+	}
+	moves, err := ParseMoveString("4453")
+	if err != nil || !slices.Equal(moves, []Move{{Column: 3}, {Column: 3}, {Column: 4}, {Column: 2}}) {
+		t.Fatalf("unexpected parse result %v (err %v)", moves, err)
+	}
+	for _, bad := range []string{"408", "12a", "0", "4p", "pp4"} {
+		if _, err := ParseMoveString(bad); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	gm := NewGameManager(true, 3, WithSeed(99))
+	gm.wonGames, gm.lostGames = 2, 1
+	for _, c := range []int{3, 2} {
+		gm.MakePlayerTurn(c)
+		if _, err := gm.MakeOpponentTurn(-1); err != nil {
+			t.Fatalf("MakeOpponentTurn: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gm.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !loaded.IsAI() || loaded.aiDiff != 3 || loaded.Seed() != 99 || loaded.alphaBeta.Workers != 0 {
+		t.Fatalf("mode, difficulty or seed not restored: ai=%v diff=%d seed=%d", loaded.IsAI(), loaded.aiDiff, loaded.Seed())
+	}
+	if loaded.GetWonGames() != 2 || loaded.GetLostGames() != 1 {
+		t.Fatalf("scores not restored: %d-%d", loaded.GetWonGames(), loaded.GetLostGames())
+	}
+	if !slices.Equal(loaded.History(), gm.History()) || loaded.turn != gm.turn {
+		t.Fatalf("history not restored:\n%v\n%v", loaded.History(), gm.History())
+	}
+	// les coups de l'IA restent les siens : l'annulation les retire avec ceux du joueur
+	loaded.Undo()
+	if len(loaded.History()) != 2 {
+		t.Fatalf("expected Undo to take back a move pair, history=%v", loaded.History())
+	}
+}
+
+func TestSaveLoadWorkers(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		gm := NewGameManager(true, 3, WithSeed(5), WithWorkers(workers))
+		var buf bytes.Buffer
+		if err := gm.Save(&buf); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		// le nombre de goroutines sauvegardé l'emporte sur celui des options
+		loaded, err := Load(&buf, WithWorkers(8))
+		if err != nil || loaded.alphaBeta.Workers != workers {
+			t.Fatalf("expected %d workers to be restored, got %d (%v)", workers, loaded.alphaBeta.Workers, err)
+		}
+	}
+}
+
+func TestSaveLoadFinishedGame(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	for _, c := range []int{0, 1, 0, 1, 0, 1, 0} {
+		gm.MakePlayerTurn(c)
+	}
+	var buf bytes.Buffer
+	if err := gm.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.GetState() != Win || loaded.winner != PlayerOneColor || loaded.GetWonGames() != 1 {
+		t.Fatalf("finished game not restored: state=%v won=%d", loaded.GetState(), loaded.GetWonGames())
+	}
+}
+
+func TestSaveLoadTimeout(t *testing.T) {
+	for _, opponent := range []bool{false, true} {
+		gm := NewGameManager(false, 0)
+		gm.PlayMoves("445")
+		gm.TimeOut(opponent)
+		var buf bytes.Buffer
+		if err := gm.Save(&buf); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		loaded, err := Load(&buf)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if loaded.GetState() != gm.GetState() || !loaded.TimedOut() || loaded.Winner() != PlayerOneColor {
+			t.Fatalf("timeout not restored: state %v, timed out %v, winner %q", loaded.GetState(), loaded.TimedOut(), loaded.Winner())
+		}
+		if loaded.GetWonGames() != gm.GetWonGames() || loaded.GetLostGames() != gm.GetLostGames() {
+			t.Fatalf("expected the timeout to be counted once, got %d:%d", loaded.GetWonGames(), loaded.GetLostGames())
+		}
+	}
+}
+
+func TestLoadRejectsInvalidGames(t *testing.T) {
+	tests := map[string]string{
+		"not json":        `moves: 4453`,
+		"version":         `{"version": 2, "mode": "local", "moves": ""}`,
+		"mode":            `{"version": 1, "mode": "online", "moves": ""}`,
+		"column":          `{"version": 1, "mode": "local", "moves": "48"}`,
+		"full column":     `{"version": 1, "mode": "local", "moves": "1111111"}`,
+		"after the end":   `{"version": 1, "mode": "local", "moves": "12121213"}`,
+		"score":           `{"version": 1, "mode": "local", "moves": "", "won": -1}`,
+		"opponent":        `{"version": 1, "mode": "local", "moves": "", "opponent": "third"}`,
+		"board mismatch":  `{"version": 1, "mode": "local", "moves": "4", "board": [".......", ".......", ".......", ".......", ".......", "...O..."]}`,
+		"board too short": `{"version": 1, "mode": "local", "moves": "4", "board": ["...X..."]}`,
+		"timeout":         `{"version": 1, "mode": "local", "moves": "", "timeout": "draw"}`,
+		"timeout at end":  `{"version": 1, "mode": "local", "moves": "1212121", "timeout": "loss"}`,
+		"no AI depth":     `{"version": 1, "mode": "ai", "moves": "", "difficulty": 0}`,
+		"AI depth":        `{"version": 1, "mode": "ai", "moves": "", "difficulty": 99}`,
+	}
+	for name, data := range tests {
+		if _, err := Load(strings.NewReader(data)); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("%s: expected ErrInvalidSave, got %v", name, err)
+		}
+	}
+	// le plateau peut être omis dans un fichier écrit à la main
+	gm, err := Load(strings.NewReader(`{"version": 1, "mode": "local", "moves": "4453"}`))
+	if err != nil || gm.MoveString() != "4453" {
+		t.Fatalf("expected a hand-written save to load, got %v", err)
+	}
+}
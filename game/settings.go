@@ -0,0 +1,119 @@
+package game
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// MaxDifficulty est le niveau de difficulté le plus élevé proposé par les
+// interfaces ; le niveau 0 (« difficulté 10 ») fait jouer l'IA
+// parfaitement.
+const MaxDifficulty = 9
+
+// DefaultPerfectMoveTime est le temps de réflexion par coup de l'IA
+// parfaite (niveau 0) quand les réglages n'en fixent pas : sans limite, le
+// solveur et la recherche jusqu'à la fin de la partie peuvent réfléchir
+// plusieurs minutes sur une ouverture.
+const DefaultPerfectMoveTime = 10 * time.Second
+
+// Settings décrit une partie telle que la configurent les interfaces, par
+// leur menu ou par la ligne de commande.
+type Settings struct {
+	AI         bool          // true pour jouer contre l'IA, false pour une partie locale à deux
+	Difficulty int           // Niveau de l'IA : 1 à MaxDifficulty, ou 0 pour un jeu parfait
+	Seed       int64         // Graine de la première partie (0 : tirée de l'heure) ; avec une graine, la recherche est séquentielle
+	MoveTime   time.Duration // Temps de réflexion de l'IA par coup (0 : profondeur fixe, ou DefaultPerfectMoveTime au niveau 0)
+	Opponent   Engine        // Moteur jouant les coups de l'IA, par exemple un moteur externe (nil : alpha-bêta selon Difficulty)
+	Clock      TimeControl   // Cadence de la partie (zéro : pas de limite de temps)
+	Book       *OpeningBook  // Bibliothèque d'ouvertures jouée instantanément par l'IA (nil : aucune)
+}
+
+// Check vérifie que le niveau de difficulté, le temps de réflexion et la
+// cadence sont valides.
+func (s Settings) Check() error {
+	if s.Difficulty < 0 || s.Difficulty > MaxDifficulty {
+		return fmt.Errorf("difficulty %d out of range [0, %d]", s.Difficulty, MaxDifficulty)
+	}
+	if s.MoveTime < 0 {
+		return fmt.Errorf("negative time per move %v", s.MoveTime)
+	}
+	return s.Clock.Check()
+}
+
+// depth renvoie la profondeur de recherche correspondant au niveau de
+// difficulté.
+func (s Settings) depth() int {
+	if s.Difficulty == 0 {
+		return 12
+	}
+	return s.Difficulty + 3
+}
+
+// moveTime renvoie le temps de réflexion de l'IA par coup, borné au
+// niveau 0 par DefaultPerfectMoveTime si les réglages n'en fixent pas.
+func (s Settings) moveTime() time.Duration {
+	if s.MoveTime == 0 && s.Difficulty == 0 {
+		return DefaultPerfectMoveTime
+	}
+	return s.MoveTime
+}
+
+// workers renvoie le nombre de goroutines de la recherche : tous les
+// cœurs, sauf avec une graine, pour que la partie puisse être rejouée à
+// l'identique (voir WithSeed).
+func (s Settings) workers() int {
+	if s.Seed != 0 {
+		return 1
+	}
+	return runtime.NumCPU()
+}
+
+// options renvoie les options de l'IA correspondant aux réglages : la
+// recherche (voir workers), la bibliothèque d'ouvertures et le solveur
+// exact au niveau 0.
+func (s Settings) options() []Option {
+	opts := []Option{WithWorkers(s.workers())}
+	if s.Book != nil {
+		opts = append(opts, WithOpeningBook(s.Book))
+	}
+	if s.Difficulty == 0 {
+		opts = append(opts, WithPerfectPlay())
+	}
+	if s.Seed != 0 {
+		opts = append(opts, WithSeed(s.Seed))
+	}
+	if d := s.moveTime(); d > 0 {
+		opts = append(opts, WithThinkTime(d))
+	}
+	return opts
+}
+
+// NewGameManager crée une partie selon les réglages ; opts est appliqué
+// après eux et permet par exemple de choisir le plateau.
+func (s Settings) NewGameManager(opts ...Option) *GameManager {
+	if !s.AI {
+		if s.Seed != 0 {
+			opts = append([]Option{WithSeed(s.Seed)}, opts...)
+		}
+		return NewGameManager(false, 0, opts...)
+	}
+	if s.Opponent != nil {
+		opts = append([]Option{WithEngine(s.Opponent)}, opts...)
+	}
+	return NewGameManager(true, s.depth(), append(s.options(), opts...)...)
+}
+
+// Engine crée un moteur alpha-bêta jouant au niveau de difficulté, avec le
+// temps de réflexion et la bibliothèque d'ouvertures des réglages, par exemple pour faire jouer l'IA
+// contre elle-même.
+func (s Settings) Engine() *AlphaBeta {
+	a := NewAlphaBeta(s.depth())
+	a.Workers = s.workers()
+	a.ThinkTime = s.moveTime()
+	a.Book = s.Book
+	if s.Difficulty == 0 {
+		a.Solver = NewSolver(DefaultSolverTableSize)
+	}
+	return a
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettingsCheck(t *testing.T) {
+	for _, s := range []Settings{{Difficulty: -1}, {Difficulty: MaxDifficulty + 1}, {Difficulty: 3, MoveTime: -time.Second}} {
+		if err := s.Check(); err == nil {
+			t.Errorf("%+v: expected an error", s)
+		}
+	}
+	if err := (Settings{AI: true, Difficulty: 0, MoveTime: time.Second}).Check(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSettingsNewGameManager(t *testing.T) {
+	gm := Settings{Seed: 42}.NewGameManager(WithBoardSize(8, 7, 4))
+	if gm.IsAI() || gm.Seed() != 42 {
+		t.Fatalf("expected a local game with seed 42, got ai=%v seed=%d", gm.IsAI(), gm.Seed())
+	}
+	if w, _, _ := gm.BoardSize(); w != 8 {
+		t.Fatalf("options were not applied")
+	}
+
+	gm = Settings{AI: true, Difficulty: 2, Seed: 7, MoveTime: time.Second}.NewGameManager()
+	if !gm.IsAI() || gm.aiDiff != 5 || gm.Seed() != 7 || gm.alphaBeta.ThinkTime != time.Second || gm.alphaBeta.Solver != nil {
+		t.Fatalf("unexpected AI configuration: depth %d seed %d", gm.aiDiff, gm.Seed())
+	}
+	if gm.alphaBeta.Workers != 1 {
+		t.Fatalf("expected a seeded game to search with one worker, got %d", gm.alphaBeta.Workers)
+	}
+	if gm = (Settings{AI: true}).NewGameManager(); gm.alphaBeta.Solver == nil || gm.aiDiff != 12 || gm.alphaBeta.ThinkTime != DefaultPerfectMoveTime {
+		t.Fatalf("expected difficulty 0 to use the solver within the default time")
+	}
+	if e := (Settings{Difficulty: 0}).Engine(); e.Solver == nil || e.Table == nil || e.ThinkTime != DefaultPerfectMoveTime {
+		t.Fatalf("expected the engine to use the solver and a table within the default time")
+	}
+	book := &OpeningBook{}
+	if gm = (Settings{AI: true, Difficulty: 3, Book: book}).NewGameManager(); gm.alphaBeta.Book != book {
+		t.Fatalf("expected the opening book to be used by the AI")
+	}
+	if e := (Settings{Difficulty: 3, Book: book}).Engine(); e.Book != book {
+		t.Fatalf("expected the opening book to be used by the engine")
+	}
+	if e := (Settings{Difficulty: 3, Seed: 1}).Engine(); e.Workers != 1 || e.ThinkTime != 0 {
+		t.Fatalf("expected a seeded engine to search with one worker at a fixed depth")
+	}
+}
+
+func TestPlayMovesAndBoardString(t *testing.T) {
+	gm := NewGameManager(false, 0)
+	if err := gm.PlayMoves("4453"); err != nil {
+		t.Fatalf("PlayMoves: %v", err)
+	}
+	want := strings.Repeat(".......\n", 4) + "...O...\n..OXX.."
+	if got := gm.Board().String(); got != want {
+		t.Fatalf("unexpected board\n%s", got)
+	}
+	if err := gm.PlayMoves("8"); err == nil {
+		t.Fatalf("expected column 8 to be refused")
+	}
+	gm = NewGameManager(false, 0)
+	if err := gm.PlayMoves("12121213"); err == nil || len(gm.History()) != 7 {
+		t.Fatalf("expected the move after the win to be refused, got %v", err)
+	}
+}
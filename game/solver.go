@@ -0,0 +1,549 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+// Outcome est le résultat théorique d'une position pour le joueur au trait.
+type Outcome int
+
+const (
+	OutcomeDraw Outcome = iota // Match nul avec un jeu parfait des deux côtés
+	OutcomeWin                 // Le joueur au trait gagne quoi que fasse l'adversaire
+	OutcomeLoss                // Le joueur au trait perd quoi qu'il fasse
+)
+
+// String renvoie une description lisible du résultat.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeWin:
+		return "win"
+	case OutcomeLoss:
+		return "loss"
+	}
+	return "draw"
+}
+
+// Solution est la valeur théorique exacte d'une position.
+//
+// Score suit la convention du solveur de Pascal Pons : 0 pour un nul, un
+// score positif si le joueur au trait gagne (d'autant plus grand que la
+// victoire est rapide), négatif s'il perd (d'autant plus petit que la
+// défaite est rapide). Distance est le nombre de demi-coups restant avant
+// la fin de la partie lorsque les deux joueurs jouent parfaitement.
+type Solution struct {
+	Player   string  // Joueur au trait
+	Outcome  Outcome // Résultat pour le joueur au trait
+	Score    int     // Score exact (convention de Pascal Pons)
+	Distance int     // Demi-coups jusqu'à la fin de la partie
+}
+
+// MoveSolution associe une colonne jouable à la valeur exacte du coup pour
+// le joueur qui le joue.
+type MoveSolution struct {
+	Column int
+	Solution
+}
+
+var (
+	// ErrInvalidPosition est renvoyée lorsque le nombre de jetons de chaque
+	// joueur ne correspond pas à une partie où PlayerOneColor commence et
+	// où les joueurs alternent.
+	ErrInvalidPosition = errors.New("position cannot be reached by alternating moves")
+	// ErrGameOver est renvoyée lorsque la position est déjà gagnée.
+	ErrGameOver = errors.New("game is already over")
+	// ErrUnsupportedBoard est renvoyée lorsque le plateau n'a pas les
+	// dimensions standard (DefaultWidth x DefaultHeight, DefaultConnect
+	// jetons à aligner) ou ne suit pas les règles classiques.
+	ErrUnsupportedBoard = errors.New("only the standard 7x6 connect-four board with classic rules is supported")
+)
+
+// Constantes du solveur : bornes des scores et fréquence de vérification
+// de l'échéance.
+const (
+	minScore        = -(boardWidth*boardHeight)/2 + 3
+	maxScore        = (boardWidth*boardHeight+1)/2 - 3
+	solverCheckMask = 1<<12 - 1
+)
+
+// DefaultSolverTableSize est le nombre d'entrées de la table de
+// transposition d'un solveur créé par défaut (32 Mo).
+const DefaultSolverTableSize = 1 << 22
+
+// Le solveur utilise sa propre disposition des bits, celle de Pascal
+// Pons : chaque colonne occupe boardHeight+1 bits, le bit supplémentaire
+// servant de sentinelle. Les décalages ne débordent ainsi jamais d'une
+// colonne à l'autre et position+mask forme une clé unique.
+const solverStride = boardHeight + 1
+
+var (
+	solverBottomMask = computeSolverBottomMask()
+	solverBoardMask  = solverBottomMask * (uint64(1)<<boardHeight - 1)
+	columnOrder      = computeColumnOrder()
+)
+
+func computeSolverBottomMask() uint64 {
+	var m uint64
+	for c := 0; c < boardWidth; c++ {
+		m |= uint64(1) << (c * solverStride)
+	}
+	return m
+}
+
+// computeColumnOrder renvoie l'ordre d'exploration des colonnes, du centre
+// vers les bords.
+func computeColumnOrder() [boardWidth]int {
+	var order [boardWidth]int
+	for i := range order {
+		order[i] = boardWidth/2 + (1-2*(i%2))*(i+1)/2
+	}
+	return order
+}
+
+// solverColumnMask renvoie les cases de la colonne c.
+func solverColumnMask(c int) uint64 {
+	return (uint64(1)<<boardHeight - 1) << (c * solverStride)
+}
+
+// position est la représentation utilisée par le solveur : les jetons du
+// joueur au trait (current), toutes les cases occupées (mask) et le
+// nombre de coups joués.
+type position struct {
+	current uint64
+	mask    uint64
+	moves   int
+}
+
+// positionFromBoard convertit un plateau en position pour le solveur, en
+// vérifiant qu'il s'agit du plateau standard aux règles classiques et
+// qu'il correspond à une partie où PlayerOneColor a commencé.
+func positionFromBoard(b *Board) (position, error) {
+	if b.geo != standardGeometry || b.variant != Classic {
+		return position{}, ErrUnsupportedBoard
+	}
+	one, two := bits.OnesCount64(b.players[0]), bits.OnesCount64(b.players[1])
+	if one != two && one != two+1 {
+		return position{}, ErrInvalidPosition
+	}
+	if b.geo.hasAlignment(b.players[0]) || b.geo.hasAlignment(b.players[1]) {
+		return position{}, ErrGameOver
+	}
+	p := position{moves: one + two}
+	own := b.players[p.moves%2]
+	for c := 0; c < boardWidth; c++ {
+		for r := 0; r < b.col[c]; r++ {
+			bit := uint64(1) << (c*solverStride + r)
+			p.mask |= bit
+			if own&(uint64(1)<<(c*boardHeight+r)) != 0 {
+				p.current |= bit
+			}
+		}
+	}
+	return p, nil
+}
+
+// possible renvoie les cases jouables (la plus basse case libre de chaque
+// colonne non pleine).
+func (p *position) possible() uint64 {
+	return (p.mask + solverBottomMask) & solverBoardMask
+}
+
+// play joue le coup représenté par la case move (issue de possible).
+func (p *position) play(move uint64) {
+	p.current ^= p.mask
+	p.mask |= move
+	p.moves++
+}
+
+// winningCells renvoie les cases libres qui compléteraient un alignement
+// de quatre jetons de own.
+func winningCells(own, mask uint64) uint64 {
+	// verticale
+	r := (own << 1) & (own << 2) & (own << 3)
+	// horizontale et diagonales
+	for _, d := range [3]int{solverStride, solverStride - 1, solverStride + 1} {
+		q := (own << d) & (own << (2 * d))
+		r |= q & (own << (3 * d))
+		r |= q & (own >> d)
+		q = (own >> d) & (own >> (2 * d))
+		r |= q & (own << d)
+		r |= q & (own >> (3 * d))
+	}
+	return r & (solverBoardMask ^ mask)
+}
+
+// winningPosition renvoie les cases qui donneraient la victoire au joueur
+// au trait ; opponentWinningPosition fait de même pour son adversaire.
+func (p *position) winningPosition() uint64 {
+	return winningCells(p.current, p.mask)
+}
+
+func (p *position) opponentWinningPosition() uint64 {
+	return winningCells(p.current^p.mask, p.mask)
+}
+
+// canWinNext indique si le joueur au trait peut gagner immédiatement.
+func (p *position) canWinNext() bool {
+	return p.winningPosition()&p.possible() != 0
+}
+
+// possibleNonLosingMoves renvoie les coups qui ne donnent pas une victoire
+// immédiate à l'adversaire (0 si tous les coups perdent). Le joueur au
+// trait ne doit pas pouvoir gagner immédiatement.
+func (p *position) possibleNonLosingMoves() uint64 {
+	possible := p.possible()
+	opponentWin := p.opponentWinningPosition()
+	forced := possible & opponentWin
+	if forced != 0 {
+		if forced&(forced-1) != 0 {
+			// deux menaces adverses à parer : la partie est perdue
+			return 0
+		}
+		possible = forced
+	}
+	// ne pas jouer juste sous une menace adverse
+	return possible &^ (opponentWin >> 1)
+}
+
+// moveScore évalue un coup pour l'ordonnancement : le nombre de menaces
+// créées par le coup.
+func (p *position) moveScore(move uint64) int {
+	return bits.OnesCount64(winningCells(p.current|move, p.mask))
+}
+
+// key renvoie une clé unique de la position.
+func (p *position) key() uint64 {
+	return p.current + p.mask
+}
+
+// Solver calcule la valeur exacte des positions par une recherche negamax
+// à fenêtre nulle, avec ordonnancement des coups et table de transposition.
+// Un Solver n'est pas utilisable par plusieurs goroutines à la fois.
+type Solver struct {
+	table []uint64 // clé << 8 | valeur
+	shift uint
+	nodes uint64
+
+	ctx     context.Context
+	aborted bool
+}
+
+// NewSolver crée un solveur dont la table de transposition contient au
+// plus tableSize entrées (arrondi à la puissance de deux inférieure).
+func NewSolver(tableSize int) *Solver {
+	n, shift := 1, uint(64)
+	for n*2 <= tableSize {
+		n *= 2
+		shift--
+	}
+	return &Solver{table: make([]uint64, n), shift: shift}
+}
+
+// Nodes renvoie le nombre de positions explorées depuis la création du
+// solveur.
+func (s *Solver) Nodes() uint64 {
+	return s.nodes
+}
+
+func (s *Solver) index(key uint64) uint64 {
+	if s.shift == 64 {
+		return 0
+	}
+	return (key * 0x9e3779b97f4a7c15) >> s.shift
+}
+
+func (s *Solver) get(key uint64) int {
+	e := s.table[s.index(key)]
+	if e>>8 != key {
+		return 0
+	}
+	return int(e & 0xff)
+}
+
+func (s *Solver) put(key uint64, value int) {
+	s.table[s.index(key)] = key<<8 | uint64(value)
+}
+
+// negamax renvoie la valeur de p si elle est dans ]alpha, beta[, une borne
+// supérieure <= alpha ou une borne inférieure >= beta sinon. Le joueur au
+// trait ne doit pas pouvoir gagner immédiatement.
+func (s *Solver) negamax(p *position, alpha, beta int) int {
+	s.nodes++
+	if s.ctx != nil && s.nodes&solverCheckMask == 0 && s.ctx.Err() != nil {
+		s.aborted = true
+	}
+	if s.aborted {
+		return 0
+	}
+
+	next := p.possibleNonLosingMoves()
+	if next == 0 {
+		return -(boardWidth*boardHeight - p.moves) / 2
+	}
+	if p.moves >= boardWidth*boardHeight-2 {
+		return 0
+	}
+
+	lower := -(boardWidth*boardHeight - 2 - p.moves) / 2
+	if alpha < lower {
+		alpha = lower
+		if alpha >= beta {
+			return alpha
+		}
+	}
+	upper := (boardWidth*boardHeight - 1 - p.moves) / 2
+
+	key := p.key()
+	if v := s.get(key); v != 0 {
+		if v > maxScore-minScore+1 {
+			lower = v + 2*minScore - maxScore - 2
+			if alpha < lower {
+				alpha = lower
+				if alpha >= beta {
+					return alpha
+				}
+			}
+		} else {
+			upper = v + minScore - 1
+		}
+	}
+	if beta > upper {
+		beta = upper
+		if alpha >= beta {
+			return beta
+		}
+	}
+
+	var moves [boardWidth]uint64
+	var scores [boardWidth]int
+	n := 0
+	for _, c := range columnOrder {
+		move := next & solverColumnMask(c)
+		if move == 0 {
+			continue
+		}
+		score := p.moveScore(move)
+		// tri par insertion stable : à score égal l'ordre du centre vers
+		// les bords est conservé
+		i := n
+		for ; i > 0 && scores[i-1] < score; i-- {
+			moves[i], scores[i] = moves[i-1], scores[i-1]
+		}
+		moves[i], scores[i] = move, score
+		n++
+	}
+
+	for i := 0; i < n; i++ {
+		child := *p
+		child.play(moves[i])
+		score := -s.negamax(&child, -beta, -alpha)
+		if s.aborted {
+			return 0
+		}
+		if score >= beta {
+			s.put(key, score+maxScore-2*minScore+2)
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	s.put(key, alpha-minScore+1)
+	return alpha
+}
+
+// solveScore renvoie le score exact de p par recherches successives à
+// fenêtre nulle.
+func (s *Solver) solveScore(p position) int {
+	if p.canWinNext() {
+		return (boardWidth*boardHeight + 1 - p.moves) / 2
+	}
+	lo := -(boardWidth*boardHeight - p.moves) / 2
+	hi := (boardWidth*boardHeight + 1 - p.moves) / 2
+	for lo < hi {
+		med := lo + (hi-lo)/2
+		if med <= 0 && lo/2 < med {
+			med = lo / 2
+		} else if med >= 0 && hi/2 > med {
+			med = hi / 2
+		}
+		r := s.negamax(&p, med, med+1)
+		if s.aborted {
+			return 0
+		}
+		if r <= med {
+			hi = r
+		} else {
+			lo = r
+		}
+	}
+	return lo
+}
+
+// solution construit la Solution correspondant au score exact de p.
+func solution(p position, score int) Solution {
+	sol := Solution{Player: PlayerOneColor, Score: score}
+	if p.moves%2 == 1 {
+		sol.Player = PlayerTwoColor
+	}
+	if score == 0 {
+		sol.Outcome = OutcomeDraw
+		sol.Distance = boardWidth*boardHeight - p.moves
+		return sol
+	}
+	// le coup gagnant est joué lorsque m coups ont déjà été joués, avec
+	// score = (cases + 1 - m) / 2 ; m a la parité du joueur qui gagne
+	winnerParity := p.moves % 2
+	s := score
+	sol.Outcome = OutcomeWin
+	if score < 0 {
+		winnerParity = 1 - winnerParity
+		s = -score
+		sol.Outcome = OutcomeLoss
+	}
+	m := boardWidth*boardHeight + 1 - 2*s
+	if m%2 != winnerParity {
+		m--
+	}
+	sol.Distance = m - p.moves + 1
+	return sol
+}
+
+// Solve renvoie la valeur exacte de la position du plateau pour le joueur
+// au trait.
+func (s *Solver) Solve(b *Board) (Solution, error) {
+	return s.SolveContext(context.Background(), b)
+}
+
+// SolveContext est Solve interrompue à l'échéance de ctx, auquel cas
+// l'erreur de ctx est renvoyée.
+func (s *Solver) SolveContext(ctx context.Context, b *Board) (Solution, error) {
+	p, err := positionFromBoard(b)
+	if err != nil {
+		return Solution{}, err
+	}
+	if p.moves == boardWidth*boardHeight {
+		return solution(p, 0), nil
+	}
+	s.ctx, s.aborted = ctx, false
+	score := s.solveScore(p)
+	if s.aborted {
+		return Solution{}, ctx.Err()
+	}
+	return solution(p, score), nil
+}
+
+// Analyze renvoie la valeur exacte de chaque coup jouable pour le joueur
+// au trait, du centre vers les bords.
+func (s *Solver) Analyze(b *Board) ([]MoveSolution, error) {
+	return s.AnalyzeContext(context.Background(), b)
+}
+
+// AnalyzeContext est Analyze interrompue à l'échéance de ctx.
+func (s *Solver) AnalyzeContext(ctx context.Context, b *Board) ([]MoveSolution, error) {
+	p, err := positionFromBoard(b)
+	if err != nil {
+		return nil, err
+	}
+	s.ctx, s.aborted = ctx, false
+	possible := p.possible()
+	winning := p.winningPosition()
+	var result []MoveSolution
+	for _, c := range columnOrder {
+		move := possible & solverColumnMask(c)
+		if move == 0 {
+			continue
+		}
+		var score int
+		if move&winning != 0 {
+			score = (boardWidth*boardHeight + 1 - p.moves) / 2
+		} else {
+			child := p
+			child.play(move)
+			if child.moves == boardWidth*boardHeight {
+				score = 0
+			} else {
+				score = -s.solveScore(child)
+			}
+			if s.aborted {
+				return nil, ctx.Err()
+			}
+		}
+		result = append(result, MoveSolution{Column: c, Solution: solution(p, score)})
+	}
+	return result, nil
+}
+
+// BestMove renvoie un coup optimal pour le joueur au trait : il conserve
+// la valeur exacte de la position (victoire la plus rapide, sinon nul,
+// sinon défaite la plus lente). Les colonnes centrales sont essayées en
+// premier.
+func (s *Solver) BestMove(ctx context.Context, b *Board) (int, error) {
+	p, err := positionFromBoard(b)
+	if err != nil {
+		return -1, err
+	}
+	possible := p.possible()
+	if possible == 0 {
+		return -1, errors.New("no playable column")
+	}
+	if win := possible & p.winningPosition(); win != 0 {
+		return solverColumn(win), nil
+	}
+	next := p.possibleNonLosingMoves()
+	if next == 0 {
+		// toutes les colonnes perdent immédiatement
+		return solverColumn(possible), nil
+	}
+	s.ctx, s.aborted = ctx, false
+	score := s.solveScore(p)
+	if s.aborted {
+		return -1, ctx.Err()
+	}
+	for _, c := range columnOrder {
+		move := next & solverColumnMask(c)
+		if move == 0 {
+			continue
+		}
+		child := p
+		child.play(move)
+		if child.moves == boardWidth*boardHeight {
+			return c, nil
+		}
+		// le coup est optimal si la valeur du fils est au plus -score ;
+		// une recherche interrompue renvoie 0, qui ne prouve rien
+		value := s.negamax(&child, -score, -score+1)
+		if s.aborted {
+			return -1, ctx.Err()
+		}
+		if value <= -score {
+			return c, nil
+		}
+	}
+	return solverColumn(next), nil
+}
+
+// solverColumn renvoie la colonne de la case de plus faible indice de
+// moves.
+func solverColumn(moves uint64) int {
+	return bits.TrailingZeros64(moves) / solverStride
+}
+
+var (
+	defaultSolver   *Solver
+	defaultSolverMu sync.Mutex
+)
+
+// Solve renvoie la valeur exacte de la position du plateau pour le joueur
+// au trait, en utilisant un solveur partagé par le paquet.
+func Solve(b *Board) (Solution, error) {
+	defaultSolverMu.Lock()
+	defer defaultSolverMu.Unlock()
+	if defaultSolver == nil {
+		defaultSolver = NewSolver(DefaultSolverTableSize)
+	}
+	return defaultSolver.Solve(b)
+}
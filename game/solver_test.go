@@ -0,0 +1,250 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// playMoves joue la suite de colonnes en alternant les joueurs, en
+// commençant par PlayerOneColor.
+func playMoves(t *testing.T, b *Board, columns ...int) {
+	t.Helper()
+	for _, c := range columns {
+		player := PlayerOneColor
+		if b.movesMade%2 == 1 {
+			player = PlayerTwoColor
+		}
+		if !b.Drop(c, player) {
+			t.Fatalf("could not play column %d", c)
+		}
+	}
+}
+
+// bruteForceScore calcule le score exact (convention de Pons) par un
+// negamax alpha-bêta sans aucune optimisation.
+func bruteForceScore(b *Board, alpha, beta int) int {
+	if b.movesMade == boardWidth*boardHeight {
+		return 0
+	}
+	player := PlayerOneColor
+	if b.movesMade%2 == 1 {
+		player = PlayerTwoColor
+	}
+	for c := 0; c < boardWidth; c++ {
+		if b.Drop(c, player) {
+			won := b.areFourConnected(player)
+			b.undoDrop(c)
+			if won {
+				return (boardWidth*boardHeight + 1 - b.movesMade) / 2
+			}
+		}
+	}
+	best := small
+	for c := 0; c < boardWidth; c++ {
+		if b.Drop(c, player) {
+			score := -bruteForceScore(b, -beta, -alpha)
+			b.undoDrop(c)
+			best = max(best, score)
+			alpha = max(alpha, score)
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// randomPosition joue des coups aléatoires jusqu'à obtenir une position
+// non terminée de moves coups.
+func randomPosition(r *rand.Rand, moves int) *Board {
+	for {
+		b := newStandardBoard()
+		for b.movesMade < moves && !b.gameOver() {
+			player := PlayerOneColor
+			if b.movesMade%2 == 1 {
+				player = PlayerTwoColor
+			}
+			b.Drop(r.Intn(boardWidth), player)
+		}
+		if !b.gameOver() && b.movesMade == moves {
+			return b
+		}
+	}
+}
+
+func TestSolveImmediateWin(t *testing.T) {
+	b := newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 2)
+	sol, err := Solve(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sol.Player != PlayerOneColor || sol.Outcome != OutcomeWin || sol.Distance != 1 {
+		t.Fatalf("expected an immediate win for the first player, got %+v", sol)
+	}
+	if sol.Score != (boardWidth*boardHeight+1-6)/2 {
+		t.Fatalf("unexpected score %d", sol.Score)
+	}
+}
+
+func TestSolveLossInTwo(t *testing.T) {
+	b := newStandardBoard()
+	// le premier joueur a deux menaces sur la rangée du bas (colonnes 1 et 5)
+	playMoves(t, b, 2, 2, 3, 3, 4)
+	sol, err := Solve(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sol.Player != PlayerTwoColor || sol.Outcome != OutcomeLoss || sol.Distance != 2 {
+		t.Fatalf("expected a loss in two plies for the second player, got %+v", sol)
+	}
+}
+
+func TestSolveRejectsInvalidPositions(t *testing.T) {
+	b := newStandardBoard()
+	b.Drop(0, PlayerTwoColor)
+	if _, err := Solve(b); !errors.Is(err, ErrInvalidPosition) {
+		t.Fatalf("expected ErrInvalidPosition, got %v", err)
+	}
+	b = newStandardBoard()
+	playMoves(t, b, 0, 1, 0, 1, 0, 1, 0)
+	if _, err := Solve(b); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected ErrGameOver, got %v", err)
+	}
+}
+
+func TestSolveMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	solver := NewSolver(1 << 16)
+	for i := 0; i < 30; i++ {
+		b := randomPosition(r, 28+r.Intn(6))
+		want := bruteForceScore(b.copyOfBoard(), small, big)
+		sol, err := solver.Solve(b)
+		if err != nil {
+			t.Fatalf("position %d: unexpected error: %v", i, err)
+		}
+		if sol.Score != want {
+			t.Fatalf("position %d: expected score %d, got %d", i, want, sol.Score)
+		}
+	}
+}
+
+func TestAnalyzeAgreesWithSolve(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	solver := NewSolver(1 << 18)
+	for i := 0; i < 5; i++ {
+		b := randomPosition(r, 20)
+		sol, err := solver.Solve(b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		moves, err := solver.Analyze(b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		best := small
+		for _, m := range moves {
+			best = max(best, m.Score)
+		}
+		if best != sol.Score {
+			t.Fatalf("position %d: best column score %d differs from position score %d", i, best, sol.Score)
+		}
+		column, err := solver.BestMove(context.Background(), b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		child := b.copyOfBoard()
+		playMoves(t, child, column)
+		if !child.gameOver() {
+			after, err := solver.Solve(child)
+			if err != nil || -after.Score != sol.Score {
+				t.Fatalf("position %d: best move %d does not keep the score (%d vs %d, %v)", i, column, -after.Score, sol.Score, err)
+			}
+		}
+	}
+}
+
+func TestSolveContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	b := newStandardBoard()
+	playMoves(t, b, 3)
+	if _, err := NewSolver(1<<16).SolveContext(ctx, b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to interrupt the solver, got %v", err)
+	}
+}
+
+// countdownContext est un contexte annulé après ses n premiers appels à
+// Err, pour interrompre une recherche à un moment précis.
+type countdownContext struct {
+	context.Context
+	n       int
+	expired bool
+}
+
+func (c *countdownContext) Err() error {
+	if c.n--; c.n < 0 {
+		c.expired = true
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestBestMoveCancelled(t *testing.T) {
+	// position perdante : BestMove vérifie ensuite les coups un à un
+	b := randomPosition(rand.New(rand.NewSource(2)), 10)
+	solve := &countdownContext{Context: context.Background(), n: 1 << 30}
+	if _, err := NewSolver(1<<16).SolveContext(solve, b); err != nil {
+		t.Fatalf("SolveContext: %v", err)
+	}
+	best := &countdownContext{Context: context.Background(), n: 1 << 30}
+	if _, err := NewSolver(1<<16).BestMove(best, b); err != nil {
+		t.Fatalf("BestMove: %v", err)
+	}
+	solved, checks := 1<<30-solve.n, 1<<30-best.n
+	if checks <= solved {
+		t.Fatalf("expected the moves to be checked after solving (%d/%d checks)", solved, checks)
+	}
+	// annulé pendant la vérification des coups, BestMove ne renvoie pas
+	// de coup non vérifié
+	for _, n := range []int{solved, (solved + checks) / 2, checks - 1} {
+		ctx := &countdownContext{Context: context.Background(), n: n}
+		column, err := NewSolver(1<<16).BestMove(ctx, b)
+		if !ctx.expired {
+			t.Fatalf("expected the search to be cancelled after %d checks", n)
+		}
+		if !errors.Is(err, context.Canceled) || column != -1 {
+			t.Fatalf("cancelled after %d checks: got column %d (%v)", n, column, err)
+		}
+	}
+}
+
+func TestPerfectPlayTakesWin(t *testing.T) {
+	gm := NewGameManager(true, 1, WithPerfectPlay())
+	playMoves(t, &gm.board, 6, 0, 1, 0, 1, 0, 1)
+	gm.turn = 7
+	// c'est au tour de l'IA (second joueur) : elle gagne en colonne 0
+	column, err := gm.MakeOpponentTurn(-1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if column != 0 || gm.GetState() != Lose {
+		t.Fatalf("expected the perfect AI to win in column 0, got %d (state %v)", column, gm.GetState())
+	}
+}
+
+func BenchmarkSolveMidgame(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	positions := make([]*Board, 8)
+	for i := range positions {
+		positions[i] = randomPosition(r, 16)
+	}
+	solver := NewSolver(DefaultSolverTableSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		solver.Solve(positions[i%len(positions)])
+	}
+}
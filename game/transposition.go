@@ -0,0 +1,210 @@
+package game
+
+import "sync/atomic"
+
+// Bound indique comment interpréter la valeur stockée dans une entrée de
+// la table de transposition.
+type Bound uint8
+
+const (
+	BoundNone  Bound = iota // Entrée vide
+	BoundExact              // Valeur exacte
+	BoundLower              // La valeur réelle est >= à la valeur stockée
+	BoundUpper              // La valeur réelle est <= à la valeur stockée
+)
+
+// ReplacementPolicy détermine quelle entrée conserver lorsque deux
+// positions tombent dans la même case de la table.
+type ReplacementPolicy int
+
+const (
+	ReplaceAlways         ReplacementPolicy = iota // La nouvelle entrée remplace toujours l'ancienne
+	ReplaceDepthPreferred                          // L'entrée issue de la recherche la plus profonde est conservée
+)
+
+// DefaultTableSize est le nombre d'entrées de la table de transposition
+// créée par défaut pour un GameManager (16 Mo).
+const DefaultTableSize = 1 << 20
+
+// TTStats regroupe les compteurs d'utilisation d'une table de transposition.
+type TTStats struct {
+	Probes     uint64 // Nombre de consultations
+	Hits       uint64 // Consultations ayant trouvé la position
+	Misses     uint64 // Consultations n'ayant pas trouvé la position
+	Stores     uint64 // Entrées écrites
+	Overwrites uint64 // Entrées écrites à la place d'une autre position
+	Rejected   uint64 // Écritures refusées par la politique de remplacement
+}
+
+// HitRate renvoie la proportion de consultations ayant trouvé la position.
+func (s TTStats) HitRate() float64 {
+	if s.Probes == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Probes)
+}
+
+// ttEntry est une entrée de la table. Les données compactées (valeur,
+// meilleur coup, profondeur et borne) sont stockées avec leur XOR avec la
+// clé Zobrist : une entrée dont les deux mots ont été écrits par des
+// recherches concurrentes différentes ne correspond plus à aucune clé et
+// est ignorée, ce qui permet de partager la table sans verrou.
+type ttEntry struct {
+	check atomic.Uint64 // clé ^ données
+	data  atomic.Uint64
+}
+
+// ttCounters contient les compteurs de TTStats, mis à jour atomiquement.
+type ttCounters struct {
+	probes, hits, misses, stores, overwrites, rejected atomic.Uint64
+}
+
+// TranspositionTable mémorise le résultat des recherches alpha-bêta afin
+// de ne pas réévaluer une position atteinte par des ordres de coups
+// différents. Elle peut être partagée par plusieurs recherches
+// concurrentes (voir AlphaBeta.Workers).
+type TranspositionTable struct {
+	entries []ttEntry
+	mask    uint64
+	policy  ReplacementPolicy
+	stats   ttCounters
+}
+
+// NewTranspositionTable crée une table d'au plus size entrées (arrondi à
+// la puissance de deux inférieure, au minimum une entrée) utilisant la
+// politique de remplacement fournie.
+func NewTranspositionTable(size int, policy ReplacementPolicy) *TranspositionTable {
+	n := 1
+	for n*2 <= size {
+		n *= 2
+	}
+	return &TranspositionTable{entries: make([]ttEntry, n), mask: uint64(n - 1), policy: policy}
+}
+
+// Size renvoie le nombre d'entrées de la table.
+func (tt *TranspositionTable) Size() int {
+	return len(tt.entries)
+}
+
+// Stats renvoie les compteurs d'utilisation de la table. Pendant une
+// recherche, ils ne comptent que les accès des itérations terminées.
+func (tt *TranspositionTable) Stats() TTStats {
+	return TTStats{
+		Probes:     tt.stats.probes.Load(),
+		Hits:       tt.stats.hits.Load(),
+		Misses:     tt.stats.misses.Load(),
+		Stores:     tt.stats.stores.Load(),
+		Overwrites: tt.stats.overwrites.Load(),
+		Rejected:   tt.stats.rejected.Load(),
+	}
+}
+
+// ResetStats remet les compteurs d'utilisation à zéro.
+func (tt *TranspositionTable) ResetStats() {
+	for _, c := range []*atomic.Uint64{
+		&tt.stats.probes, &tt.stats.hits, &tt.stats.misses,
+		&tt.stats.stores, &tt.stats.overwrites, &tt.stats.rejected,
+	} {
+		c.Store(0)
+	}
+}
+
+// Clear vide la table et remet les compteurs à zéro. Elle ne doit pas être
+// appelée pendant une recherche.
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.entries {
+		tt.entries[i].check.Store(0)
+		tt.entries[i].data.Store(0)
+	}
+	tt.ResetStats()
+}
+
+// packEntry compacte les données d'une entrée sur 64 bits :
+// valeur (32 bits), coup + 1 (8 bits), profondeur (8 bits), borne (8 bits).
+func packEntry(value, move, depth int, bound Bound) uint64 {
+	return uint64(uint32(int32(value))) |
+		uint64(uint8(move+1))<<32 |
+		uint64(uint8(depth))<<40 |
+		uint64(bound)<<48
+}
+
+// unpackEntry est l'inverse de packEntry.
+func unpackEntry(data uint64) (value, move, depth int, bound Bound) {
+	value = int(int32(uint32(data)))
+	move = int(uint8(data>>32)) - 1
+	depth = int(uint8(data >> 40))
+	bound = Bound(uint8(data >> 48))
+	return
+}
+
+// addStats ajoute aux compteurs de la table ceux d'une recherche. Les
+// recherches comptent leurs accès à part (voir probeCounted) et ne les
+// reportent qu'à la fin de chaque itération : les goroutines d'une
+// recherche parallèle ne se disputent pas ainsi les compteurs à chaque
+// nœud.
+func (tt *TranspositionTable) addStats(s *TTStats) {
+	for _, c := range []struct {
+		counter *atomic.Uint64
+		n       uint64
+	}{
+		{&tt.stats.probes, s.Probes}, {&tt.stats.hits, s.Hits}, {&tt.stats.misses, s.Misses},
+		{&tt.stats.stores, s.Stores}, {&tt.stats.overwrites, s.Overwrites}, {&tt.stats.rejected, s.Rejected},
+	} {
+		if c.n != 0 {
+			c.counter.Add(c.n)
+		}
+	}
+	*s = TTStats{}
+}
+
+// probe cherche la position de clé key. Elle renvoie la valeur, le
+// meilleur coup, la profondeur restante de la recherche ayant produit
+// l'entrée et le type de borne ; ok vaut false si la position est absente.
+func (tt *TranspositionTable) probe(key uint64) (value, move, depth int, bound Bound, ok bool) {
+	var stats TTStats
+	value, move, depth, bound, ok = tt.probeCounted(key, &stats)
+	tt.addStats(&stats)
+	return value, move, depth, bound, ok
+}
+
+// probeCounted est probe, l'accès étant compté dans stats plutôt que dans
+// les compteurs de la table.
+func (tt *TranspositionTable) probeCounted(key uint64, stats *TTStats) (value, move, depth int, bound Bound, ok bool) {
+	stats.Probes++
+	e := &tt.entries[key&tt.mask]
+	data := e.data.Load()
+	if e.check.Load()^data != key || Bound(uint8(data>>48)) == BoundNone {
+		stats.Misses++
+		return 0, -1, 0, BoundNone, false
+	}
+	stats.Hits++
+	value, move, depth, bound = unpackEntry(data)
+	return value, move, depth, bound, true
+}
+
+// store enregistre le résultat d'une recherche de profondeur depth sur la
+// position de clé key, selon la politique de remplacement de la table.
+func (tt *TranspositionTable) store(key uint64, value, move, depth int, bound Bound) {
+	var stats TTStats
+	tt.storeCounted(key, value, move, depth, bound, &stats)
+	tt.addStats(&stats)
+}
+
+// storeCounted est store, l'écriture étant comptée dans stats plutôt que
+// dans les compteurs de la table.
+func (tt *TranspositionTable) storeCounted(key uint64, value, move, depth int, bound Bound, stats *TTStats) {
+	e := &tt.entries[key&tt.mask]
+	old := e.data.Load()
+	occupied := Bound(uint8(old>>48)) != BoundNone
+	if occupied && e.check.Load()^old != key {
+		if tt.policy == ReplaceDepthPreferred && int(uint8(old>>40)) > depth {
+			stats.Rejected++
+			return
+		}
+		stats.Overwrites++
+	}
+	data := packEntry(value, move, depth, bound)
+	e.data.Store(data)
+	e.check.Store(key ^ data)
+	stats.Stores++
+}
@@ -0,0 +1,140 @@
+package game
+
+import "testing"
+
+func TestTranspositionTableSizeRoundedDown(t *testing.T) {
+	if got := NewTranspositionTable(1000, ReplaceAlways).Size(); got != 512 {
+		t.Fatalf("expected size 512, got %d", got)
+	}
+	if got := NewTranspositionTable(0, ReplaceAlways).Size(); got != 1 {
+		t.Fatalf("expected size 1 for a zero request, got %d", got)
+	}
+}
+
+func TestTranspositionTableStoreAndProbe(t *testing.T) {
+	tt := NewTranspositionTable(16, ReplaceAlways)
+	if _, _, _, _, ok := tt.probe(42); ok {
+		t.Fatalf("expected a miss on an empty table")
+	}
+	tt.store(42, -1234, 6, 9, BoundLower)
+	v, m, d, bound, ok := tt.probe(42)
+	if !ok || v != -1234 || m != 6 || d != 9 || bound != BoundLower {
+		t.Fatalf("unexpected entry: ok=%v value=%d move=%d depth=%d bound=%d", ok, v, m, d, bound)
+	}
+	stats := tt.Stats()
+	if stats.Probes != 2 || stats.Hits != 1 || stats.Misses != 1 || stats.Stores != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.HitRate() != 0.5 {
+		t.Fatalf("expected hit rate 0.5, got %v", stats.HitRate())
+	}
+}
+
+func TestTranspositionTableReplacementPolicies(t *testing.T) {
+	// deux clés qui tombent dans la même case d'une table de 16 entrées
+	k1, k2 := uint64(3), uint64(3+16)
+
+	always := NewTranspositionTable(16, ReplaceAlways)
+	always.store(k1, 1, 0, 8, BoundExact)
+	always.store(k2, 2, 0, 1, BoundExact)
+	if _, _, _, _, ok := always.probe(k2); !ok {
+		t.Fatalf("ReplaceAlways should keep the newest entry")
+	}
+	if always.Stats().Overwrites != 1 {
+		t.Fatalf("expected one overwrite, got %+v", always.Stats())
+	}
+
+	deeper := NewTranspositionTable(16, ReplaceDepthPreferred)
+	deeper.store(k1, 1, 0, 8, BoundExact)
+	deeper.store(k2, 2, 0, 1, BoundExact)
+	if _, _, _, _, ok := deeper.probe(k1); !ok {
+		t.Fatalf("ReplaceDepthPreferred should keep the deeper entry")
+	}
+	if deeper.Stats().Rejected != 1 {
+		t.Fatalf("expected one rejected store, got %+v", deeper.Stats())
+	}
+	deeper.store(k2, 2, 0, 9, BoundExact)
+	if _, _, _, _, ok := deeper.probe(k2); !ok {
+		t.Fatalf("ReplaceDepthPreferred should accept a deeper entry")
+	}
+
+	deeper.Clear()
+	if _, _, _, _, ok := deeper.probe(k2); ok {
+		t.Fatalf("expected a miss after Clear")
+	}
+}
+
+func TestZobristHashRestoredByUndo(t *testing.T) {
+	b := newStandardBoard()
+	b.Drop(3, PlayerOneColor)
+	before := b.hash
+	b.Drop(4, PlayerTwoColor)
+	if b.hash == before {
+		t.Fatalf("expected hash to change after Drop")
+	}
+	b.undoDrop(4)
+	if b.hash != before {
+		t.Fatalf("expected hash to be restored after undoDrop")
+	}
+
+	// deux ordres de coups différents mènent à la même clé
+	other := newStandardBoard()
+	other.Drop(4, PlayerTwoColor)
+	other.Drop(3, PlayerOneColor)
+	b.Drop(4, PlayerTwoColor)
+	if b.hash != other.hash {
+		t.Fatalf("transposed positions should share the same hash")
+	}
+}
+
+func TestSearchWithTableMatchesPlainSearch(t *testing.T) {
+	b := newStandardBoard()
+	for _, c := range []int{3, 3, 2, 4} {
+		player := PlayerOneColor
+		if b.movesMade%2 == 1 {
+			player = PlayerTwoColor
+		}
+		b.Drop(c, player)
+	}
+	for depth := 1; depth <= 7; depth++ {
+		plain, _ := alphabeta(b.copyOfBoard(), true, 0, small, big, depth)
+		s := &searcher{searchConfig: searchConfig{tt: NewTranspositionTable(1<<16, ReplaceDepthPreferred)}}
+		withTable, _ := s.alphabeta(b.copyOfBoard(), true, 0, small, big, depth)
+		if plain != withTable {
+			t.Fatalf("depth %d: expected value %d with table, got %d", depth, plain, withTable)
+		}
+	}
+}
+
+func TestTranspositionStatsByDifficulty(t *testing.T) {
+	for strength := 1; strength <= 9; strength++ {
+		tt := NewTranspositionTable(1<<16, ReplaceDepthPreferred)
+		s := &searcher{searchConfig: searchConfig{tt: tt}}
+		s.alphabeta(newStandardBoard(), true, 0, small, big, strength)
+		s.flushStats()
+		stats := tt.Stats()
+		t.Logf("difficulty %d: probes=%d hits=%d (%.1f%%) stores=%d overwrites=%d",
+			strength, stats.Probes, stats.Hits, 100*stats.HitRate(), stats.Stores, stats.Overwrites)
+		if strength >= 4 && stats.Hits == 0 {
+			t.Errorf("difficulty %d: expected transpositions to be found", strength)
+		}
+	}
+}
+
+func TestGameManagerTTStats(t *testing.T) {
+	gm := NewGameManager(true, 5)
+	if _, err := gm.MakeOpponentTurn(-1); err != nil {
+		t.Fatalf("unexpected error from AI opponent: %v", err)
+	}
+	if gm.TTStats().Probes == 0 {
+		t.Fatalf("expected the AI search to use the transposition table")
+	}
+
+	none := NewGameManager(true, 5, WithTranspositionTable(nil))
+	if _, err := none.MakeOpponentTurn(-1); err != nil {
+		t.Fatalf("unexpected error from AI opponent: %v", err)
+	}
+	if none.TTStats() != (TTStats{}) {
+		t.Fatalf("expected empty stats without a table, got %+v", none.TTStats())
+	}
+}
@@ -2,7 +2,11 @@ module github.com/AbassHammed/c4
 
 go 1.25.3
 
-require golang.org/x/image v0.32.0
+require (
+	golang.org/x/image v0.32.0
+	golang.org/x/net v0.45.0
+	golang.org/x/sys v0.36.0
+)
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20250923094054-ea854a63cce1 // indirect
@@ -12,7 +16,6 @@ require (
 	github.com/jezek/xgb v1.1.1 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 )
 
 require (
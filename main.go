@@ -1,7 +1,710 @@
 package main
 
-import "github.com/AbassHammed/c4/ui"
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/AbassHammed/c4/engine"
+	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/netplay"
+	"github.com/AbassHammed/c4/server"
+	"github.com/AbassHammed/c4/spectate"
+	"github.com/AbassHammed/c4/tournament"
+	"github.com/AbassHammed/c4/tui"
+	"github.com/AbassHammed/c4/ui"
+)
+
+const usage = `usage: c4 [command] [flags]
+
+commands:
+  gui       play in a window (default)
+  tui       play in the terminal
+  analyze   evaluate a position given by its moves or a save file, or review a saved game
+  selfplay  let the AI play against itself
+  bench     time the AI search on fixed positions
+  book      build an opening book
+  serve     serve games over HTTP and WebSocket
+  engine    serve the AI through the engine protocol on stdin/stdout
+  tournament
+            play engine configurations against each other and estimate their Elo
+
+run "c4 <command> -h" for the flags of a command
+`
 
 func main() {
-	ui.StartGuiGame()
+	cmd, args := "gui", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	var err error
+	switch cmd {
+	case "gui":
+		err = runGui(args)
+	case "tui":
+		err = runTui(args)
+	case "analyze":
+		err = runAnalyze(args)
+	case "selfplay":
+		err = runSelfPlay(args)
+	case "bench":
+		err = runBench(args)
+	case "book":
+		err = runBook(args)
+	case "serve":
+		err = runServe(args)
+	case "engine":
+		err = runEngine(args)
+	case "tournament":
+		err = runTournament(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// gameFlags regroupe les options de lancement communes à gui et tui.
+type gameFlags struct {
+	mode       string
+	difficulty int
+	first      string
+	seed       int64
+	moveTime   time.Duration
+	clock      string
+	host       string
+	join       string
+	name       string
+	engine     string
+	book       string
+}
+
+// register déclare les options dans fs.
+func (f *gameFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.mode, "mode", "menu", "game started at launch: menu, ai or local")
+	fs.IntVar(&f.difficulty, "difficulty", 5, "AI difficulty from 1 to 9, 0 for perfect play")
+	fs.StringVar(&f.first, "first", "player", "who moves first against the AI or a network opponent: player or ai (opponent)")
+	fs.Int64Var(&f.seed, "seed", 0, "seed of the first game, searched with one goroutine so that it replays (0: taken from the clock)")
+	fs.DurationVar(&f.moveTime, "movetime", 0, "AI thinking time per move, e.g. 2s (0: fixed depth, 10s for perfect play)")
+	fs.StringVar(&f.clock, "clock", "59s/move", "time control of the window: 59s/move, 5m (sudden death), 3m+2s (Fischer) or none; a player out of time loses")
+	fs.StringVar(&f.host, "host", "", "host a network game on this address, e.g. :"+netplay.DefaultPort)
+	fs.StringVar(&f.join, "join", "", "join the network game hosted at this address")
+	fs.StringVar(&f.name, "name", defaultName(), "player name shown to a network opponent")
+	fs.StringVar(&f.engine, "engine", "", "external engine command playing for the AI, e.g. \"c4 engine -solver\"")
+	fs.StringVar(&f.book, "book", "", "opening book written by c4 book, played instantly by the AI (standard board)")
+}
+
+// defaultName renvoie le nom du joueur par défaut en réseau : le nom de la
+// machine.
+func defaultName() string {
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "player"
+}
+
+// settings vérifie les options et renvoie les réglages de la partie, en
+// indiquant si elle commence sans passer par le menu et si l'IA joue le
+// premier coup.
+func (f *gameFlags) settings() (s game.Settings, start, aiFirst bool, err error) {
+	s = game.Settings{Difficulty: f.difficulty, Seed: f.seed, MoveTime: f.moveTime}
+	if s.Clock, err = game.ParseTimeControl(f.clock); err != nil {
+		return s, false, false, err
+	}
+	if s.Book, err = readBook(f.book); err != nil {
+		return s, false, false, err
+	}
+	if err := s.Check(); err != nil {
+		return s, false, false, err
+	}
+	switch f.mode {
+	case "menu":
+	case "ai":
+		s.AI, start = true, true
+	case "local":
+		start = true
+	default:
+		return s, false, false, fmt.Errorf("unknown mode %q (menu, ai or local)", f.mode)
+	}
+	switch f.first {
+	case "player":
+	case "ai", "opponent":
+		aiFirst = true
+	default:
+		return s, false, false, fmt.Errorf("unknown first player %q (player or ai)", f.first)
+	}
+	if f.host != "" && f.join != "" {
+		return s, false, false, errors.New("-host and -join cannot be used together")
+	}
+	return s, start, aiFirst, nil
+}
+
+// readBook lit la bibliothèque d'ouvertures écrite par c4 book dans path,
+// ou renvoie nil si path est vide.
+func readBook(path string) (*game.OpeningBook, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return game.ReadOpeningBook(f)
+}
+
+// startEngine lance le moteur externe demandé par -engine et le fait jouer
+// pour l'IA des réglages s, avec le temps de réflexion de -movetime. Elle
+// renvoie nil sans cette option.
+func (f *gameFlags) startEngine(s *game.Settings) (*engine.External, error) {
+	args := strings.Fields(f.engine)
+	if len(args) == 0 {
+		return nil, nil
+	}
+	e, err := engine.Start(args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	e.MoveTime = f.moveTime
+	s.Opponent = e
+	return e, nil
+}
+
+// connect héberge la partie en réseau demandée par -host, en attendant
+// qu'un joueur la rejoigne, ou rejoint celle demandée par -join. Elle
+// renvoie nil sans l'une de ces options.
+func (f *gameFlags) connect() (*netplay.Conn, error) {
+	switch {
+	case f.host != "":
+		h, err := netplay.Listen(f.host, netplay.Options{Name: f.name, GuestFirst: f.first != "player"})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("waiting for a player on %s\n", h.Addr())
+		c, err := h.Accept(context.Background())
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		return c, nil
+	case f.join != "":
+		return netplay.Dial(context.Background(), f.join, f.name)
+	}
+	return nil, nil
+}
+
+// runGui lance l'interface graphique : c4 [gui] [-mode M] [-difficulty N]
+// [-first F] [-seed N] [-movetime D] [-host A | -join A] [-name N]
+// [-engine commande] [-spectators A] [-scale X], ou en spectateur : c4 [gui] -watch A.
+func runGui(args []string) error {
+	fs := flag.NewFlagSet("gui", flag.ExitOnError)
+	var gf gameFlags
+	gf.register(fs)
+	scale := fs.Float64("scale", 1, "window scale factor")
+	spectators := fs.String("spectators", "", "let spectators watch the games on this address, e.g. :"+spectate.DefaultPort)
+	watch := fs.String("watch", "", "watch the games of the instance at this address, without playing")
+	fs.Parse(args)
+
+	settings, start, aiFirst, err := gf.settings()
+	if err != nil {
+		return err
+	}
+	if *scale <= 0 {
+		return fmt.Errorf("invalid window scale %v", *scale)
+	}
+	cfg := ui.Config{Settings: settings, Start: start, AIFirst: aiFirst, Scale: *scale, Name: gf.name}
+	if *watch != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if cfg.Watch, err = spectate.Watch(ctx, *watch); err != nil {
+			return err
+		}
+		defer cfg.Watch.Close()
+		ui.StartGuiGame(cfg)
+		return nil
+	}
+	e, err := gf.startEngine(&cfg.Settings)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		defer e.Close()
+	}
+	if *spectators != "" {
+		if cfg.Feed, err = spectate.Listen(*spectators); err != nil {
+			return err
+		}
+		defer cfg.Feed.Close()
+		fmt.Printf("spectators can watch on %s\n", cfg.Feed.Addr())
+	}
+	if cfg.Remote, err = gf.connect(); err != nil {
+		return err
+	}
+	if cfg.Remote != nil {
+		defer cfg.Remote.Close()
+	}
+	ui.StartGuiGame(cfg)
+	return nil
+}
+
+// runTui lance le jeu dans le terminal : c4 tui [-mode M] [-difficulty N]
+// [-first F] [-seed N] [-movetime D] [-host A | -join A] [-name N]
+// [-engine commande].
+func runTui(args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	var gf gameFlags
+	gf.register(fs)
+	fs.Parse(args)
+
+	settings, start, aiFirst, err := gf.settings()
+	if err != nil {
+		return err
+	}
+	e, err := gf.startEngine(&settings)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		defer e.Close()
+	}
+	remote, err := gf.connect()
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		defer remote.Close()
+	}
+	return tui.StartTuiGame(tui.Config{Settings: settings, Start: start, AIFirst: aiFirst, Remote: remote})
+}
+
+// runAnalyze affiche la valeur d'une position, donnée par ses coups en
+// notation compacte ou par une sauvegarde : c4 analyze [-depth N]
+// [-movetime D] [-popout] [-load fichier] [coups]. Sur le plateau standard
+// aux règles classiques, chaque coup reçoit sa valeur exacte ; sinon (ou si
+// le solveur dépasse le temps imparti), la recherche alpha-bêta donne le
+// meilleur coup. Avec une sauvegarde pour seul argument (c4 analyze
+// [-depth N] [-movetime D] fichier), les coups de la partie sont passés en
+// revue (voir reviewGame) : l'argument désigne un fichier dès qu'il contient
+// un caractère étranger à la notation des coups (chiffres de 1 à 9 et 'p'),
+// et -review fichier lève l'ambiguïté pour les autres noms.
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	depth := fs.Int("depth", 12, "alpha-beta search depth when the position cannot be solved")
+	moveTime := fs.Duration("movetime", 0, "time limit of the analysis (0: none; per position when reviewing a game, 0: 2s)")
+	popout := fs.Bool("popout", false, "play the moves with the PopOut rules")
+	load := fs.String("load", "", "analyze the position of a saved game")
+	review := fs.String("review", "", "review the moves of a saved game, like a file name given as the only argument")
+	fs.Parse(args)
+	if *review == "" && *load == "" && fs.NArg() == 1 && !isMoveList(fs.Arg(0)) {
+		*review = fs.Arg(0)
+	} else if *review != "" && (*load != "" || fs.NArg() > 0) {
+		return errors.New("-review cannot be combined with -load or moves")
+	}
+	if *review != "" {
+		return reviewGame(*review, *depth, *moveTime)
+	}
+
+	var gm *game.GameManager
+	if *load != "" {
+		f, err := os.Open(*load)
+		if err != nil {
+			return err
+		}
+		gm, err = game.Load(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	} else {
+		variant := game.Classic
+		if *popout {
+			variant = game.PopOut
+		}
+		gm = game.NewGameManager(false, 0, game.WithVariant(variant))
+		if err := gm.PlayMoves(strings.Join(fs.Args(), "")); err != nil {
+			return err
+		}
+	}
+
+	b := gm.Board()
+	fmt.Println(b)
+	if gm.GetState() != game.Running {
+		fmt.Println("game over")
+		return nil
+	}
+	player, name := game.PlayerOneColor, "X"
+	if len(gm.History())%2 == 1 {
+		player, name = game.PlayerTwoColor, "O"
+	}
+	fmt.Printf("%s to move\n", name)
+
+	ctx := context.Background()
+	if *moveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *moveTime)
+		defer cancel()
+	}
+	moves, err := game.NewSolver(game.DefaultSolverTableSize).AnalyzeContext(ctx, b)
+	if err == nil {
+		for _, m := range moves {
+			fmt.Printf("column %d: %s in %d plies\n", m.Column+1, m.Outcome, m.Distance)
+		}
+		return nil
+	}
+	if !errors.Is(err, game.ErrUnsupportedBoard) && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	engine := game.NewAlphaBeta(*depth)
+	start := time.Now()
+	move, err := engine.BestMove(context.Background(), b, player)
+	if err != nil {
+		return err
+	}
+	kind := "drop in"
+	if move.Kind == game.MovePop {
+		kind = "pop out"
+	}
+	fmt.Printf("best move: %s column %d (depth %d, %v)\n", kind, move.Column+1, *depth, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// isMoveList indique si s n'est fait que de caractères de la notation
+// compacte des coups ; runAnalyze prend un autre argument pour une
+// sauvegarde.
+func isMoveList(s string) bool {
+	return strings.Trim(s, "123456789p") == ""
+}
+
+// reviewGame affiche les coups de la partie sauvegardée dans path, au fur
+// et à mesure de leur analyse : la valeur de la position avant et après
+// chaque coup pour son auteur, puis les gaffes et les victoires manquées
+// avec le meilleur coup. Le solveur dispose de moveTime par position (2 s
+// si moveTime est nul), la recherche alpha-bêta de la profondeur depth.
+func reviewGame(path string, depth int, moveTime time.Duration) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	gm, err := game.Load(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	a := game.NewAnalyzer(depth)
+	a.SolveTime = moveTime
+	if a.SolveTime == 0 {
+		a.SolveTime = 2 * time.Second
+	}
+	fmt.Printf("%-4s %-3s %-5s %-12s %-12s\n", "", "", "move", "before", "after")
+	var ply int
+	var blunders, missed [2]int
+	_, err = a.Review(context.Background(), gm, func(r game.MoveReview) {
+		ply++
+		player, name := 0, "X"
+		if r.Move.Player == game.PlayerTwoColor {
+			player, name = 1, "O"
+		}
+		var notes []string
+		if r.Blunder {
+			notes = append(notes, "blunder")
+			blunders[player]++
+		}
+		if r.MissedWin {
+			notes = append(notes, "missed win")
+			missed[player]++
+		}
+		note := ""
+		if len(notes) > 0 {
+			note = fmt.Sprintf("?? %s, best %s (%v)", strings.Join(notes, ", "), game.FormatMoves([]game.Move{r.Best.Move}), r.Best)
+		}
+		fmt.Printf("%3d. %-3s %-5s %-12v %-12v %s\n", ply, name, game.FormatMoves([]game.Move{r.Move}), r.Best, r.Played, note)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("blunders: X %d, O %d; missed wins: X %d, O %d\n", blunders[0], blunders[1], missed[0], missed[1])
+	return nil
+}
+
+// runSelfPlay fait jouer l'IA contre elle-même et affiche le résultat de
+// chaque partie : c4 selfplay [-games N] [-difficulty N] [-difficulty2 N]
+// [-seed N] [-movetime D] [-workers N] [-popout]. Les deux IA changent de
+// couleur à chaque partie.
+func runSelfPlay(args []string) error {
+	fs := flag.NewFlagSet("selfplay", flag.ExitOnError)
+	games := fs.Int("games", 10, "number of games")
+	difficulty := fs.Int("difficulty", 5, "difficulty of the first AI, from 1 to 9 (0: perfect play)")
+	difficulty2 := fs.Int("difficulty2", -1, "difficulty of the second AI (-1: same as the first)")
+	seed := fs.Int64("seed", 1, "seed of the first game, the following games use the next seeds")
+	moveTime := fs.Duration("movetime", 0, "thinking time per move (0: fixed depth)")
+	workers := fs.Int("workers", 1, "search goroutines per AI (1 keeps the games reproducible)")
+	popout := fs.Bool("popout", false, "play with the PopOut rules")
+	fs.Parse(args)
+
+	if *difficulty2 < 0 {
+		*difficulty2 = *difficulty
+	}
+	variant := game.Classic
+	if *popout {
+		variant = game.PopOut
+	}
+	var engines [2]*game.AlphaBeta
+	for i, d := range []int{*difficulty, *difficulty2} {
+		s := game.Settings{AI: true, Difficulty: d, MoveTime: *moveTime}
+		if err := s.Check(); err != nil {
+			return err
+		}
+		engines[i] = s.Engine()
+		engines[i].Workers = *workers
+	}
+
+	var wins [2]int
+	for i := 0; i < *games; i++ {
+		gm := game.NewGameManager(false, 0, game.WithVariant(variant))
+		// players[0] joue le premier coup
+		players := [2]int{i % 2, 1 - i%2}
+		for _, e := range engines {
+			e.Rand = rand.New(rand.NewSource(*seed + int64(i)))
+			e.Table.Clear()
+		}
+		for gm.GetState() == game.Running {
+			turn := len(gm.History()) % 2
+			player := game.PlayerOneColor
+			if turn == 1 {
+				player = game.PlayerTwoColor
+			}
+			move, err := engines[players[turn]].BestMove(context.Background(), gm.Board(), player)
+			if err != nil {
+				return err
+			}
+			if move.Kind == game.MovePop {
+				_, err = gm.MakePlayerPop(move.Column)
+			} else {
+				_, err = gm.MakePlayerTurn(move.Column)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		result := "draw"
+		if state := gm.GetState(); state != game.Tie {
+			// en partie locale, Win signifie que l'auteur du dernier coup a gagné
+			history := gm.History()
+			winner := len(history) - 1
+			if state == game.Lose {
+				winner++
+			}
+			wins[players[winner%2]]++
+			result = fmt.Sprintf("AI %d wins", players[winner%2]+1)
+		}
+		fmt.Printf("game %d: AI %d first, %s  %s\n", i+1, players[0]+1, result, gm.MoveString())
+	}
+	fmt.Printf("AI 1 (difficulty %d): %d wins, AI 2 (difficulty %d): %d wins, %d draws\n",
+		*difficulty, wins[0], *difficulty2, wins[1], *games-wins[0]-wins[1])
+	return nil
+}
+
+// benchPositions sont les positions, en notation compacte, sur lesquelles
+// runBench mesure la recherche.
+var benchPositions = []string{"", "44", "4453", "444333", "43453", "4443524", "44443332"}
+
+// runBench mesure la durée de la recherche alpha-bêta sur des positions
+// fixes : c4 bench [-depth N] [-workers N].
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	depth := fs.Int("depth", 10, "alpha-beta search depth")
+	workers := fs.Int("workers", 1, "search goroutines")
+	fs.Parse(args)
+
+	var total time.Duration
+	for _, moves := range benchPositions {
+		gm := game.NewGameManager(false, 0)
+		if err := gm.PlayMoves(moves); err != nil {
+			return fmt.Errorf("position %q: %v", moves, err)
+		}
+		player := game.PlayerOneColor
+		if len(moves)%2 == 1 {
+			player = game.PlayerTwoColor
+		}
+		engine := game.NewAlphaBeta(*depth)
+		engine.Workers = *workers
+		engine.Rand = rand.New(rand.NewSource(1))
+		start := time.Now()
+		move, err := engine.BestMove(context.Background(), gm.Board(), player)
+		if err != nil {
+			return err
+		}
+		elapsed := time.Since(start)
+		total += elapsed
+		fmt.Printf("%-12q column %d  %10v  tt hit rate %.1f%%\n", moves, move.Column+1, elapsed.Round(time.Microsecond), 100*engine.Table.Stats().HitRate())
+	}
+	fmt.Printf("total %v (depth %d, %d workers)\n", total.Round(time.Millisecond), *depth, *workers)
+	return nil
+}
+
+// runBook construit une bibliothèque d'ouvertures et l'écrit dans un
+// fichier : c4 book [-plies N] [-depth N] [-seed N] [-o fichier].
+func runBook(args []string) error {
+	fs := flag.NewFlagSet("book", flag.ExitOnError)
+	plies := fs.Int("plies", 8, "positions covered by the book, in plies from the start")
+	depth := fs.Int("depth", 12, "alpha-beta search depth used for each position")
+	out := fs.String("o", "book.c4b", "output file")
+	seed := fs.Int64("seed", 1, "seed of the move ordering, the same seed builds the same book")
+	fs.Parse(args)
+	if *depth < 1 {
+		return fmt.Errorf("invalid search depth %d", *depth)
+	}
+
+	book, err := game.BuildOpeningBook(context.Background(), game.BookOptions{
+		Plies:       *plies,
+		SearchDepth: *depth,
+		Seed:        *seed,
+		Progress: func(done, total int) {
+			if done%500 == 0 || done == total {
+				fmt.Fprintf(os.Stderr, "\r%d/%d positions", done, total)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if _, err := book.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runServe expose des parties à travers HTTP (voir le paquet server) :
+// c4 serve [-addr A] [-seed N] [-movetime D] [-book fichier].
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	seed := fs.Int64("seed", 0, "seed of the AI (0: taken from the clock)")
+	moveTime := fs.Duration("movetime", 0, "AI thinking time per move, e.g. 2s (0: fixed depth, 10s for perfect play)")
+	bookFile := fs.String("book", "", "opening book written by c4 book, played instantly by the AI (standard board)")
+	fs.Parse(args)
+
+	book, err := readBook(*bookFile)
+	if err != nil {
+		return err
+	}
+	settings := game.Settings{Seed: *seed, MoveTime: *moveTime, Book: book}
+	if err := settings.Check(); err != nil {
+		return err
+	}
+	log.Printf("serving games on %s", *addr)
+	return http.ListenAndServe(*addr, server.New(settings))
+}
+
+// runEngine sert l'IA par le protocole des moteurs (voir le paquet engine)
+// sur l'entrée et la sortie standard : c4 engine [-solver] [-workers N]
+// [-book fichier].
+// La profondeur ou le temps de chaque recherche vient de la commande "go".
+func runEngine(args []string) error {
+	fs := flag.NewFlagSet("engine", flag.ExitOnError)
+	solver := fs.Bool("solver", false, "try the exact solver before searching (standard board only)")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of search goroutines")
+	bookFile := fs.String("book", "", "opening book written by c4 book, played instantly (standard board)")
+	fs.Parse(args)
+
+	book, err := readBook(*bookFile)
+	if err != nil {
+		return err
+	}
+	a := game.NewAlphaBeta(0)
+	a.Workers = *workers
+	a.Book = book
+	if *solver {
+		a.Solver = game.NewSolver(game.DefaultSolverTableSize)
+	}
+	return engine.Serve(os.Stdin, os.Stdout, "c4 alphabeta", a)
+}
+
+// runTournament fait s'affronter des configurations de l'IA et affiche
+// leur classement (voir le paquet tournament) : c4 tournament
+// [-format F] [-games N] [-concurrency N] [-movetime D] [-openings N]
+// [-seed N] [-popout] [-sprt elo0,elo1 [-alpha A] [-beta B]] joueur...
+// Par exemple : c4 tournament -games 20 ab:5 ab:5,eval=zero "mcts:5000"
+// "ext:./c4 engine".
+func runTournament(args []string) error {
+	fs := flag.NewFlagSet("tournament", flag.ExitOnError)
+	format := fs.String("format", "roundrobin", "roundrobin (everyone meets everyone) or gauntlet (the first player meets the others)")
+	games := fs.Int("games", 10, "games per match, each player moving first in half of them")
+	concurrency := fs.Int("concurrency", runtime.NumCPU(), "games played at the same time")
+	moveTime := fs.Duration("movetime", 0, "thinking time per move of the players without a time option (0: fixed depth)")
+	openings := fs.Int("openings", 2, "random plies played before the engines take over")
+	seed := fs.Int64("seed", 1, "seed of the openings and engines")
+	popout := fs.Bool("popout", false, "play with the PopOut rules")
+	sprtBounds := fs.String("sprt", "", "stop as soon as a SPRT decides between elo0 and elo1 for the first player, e.g. 0,10")
+	alpha := fs.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := fs.Float64("beta", 0.05, "SPRT false negative rate")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: c4 tournament [flags] player player...")
+		fmt.Fprintln(fs.Output(), "players: ab[:level][,option=value...], mcts[:simulations][,option=value...] or ext:command")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg := tournament.Config{Games: *games, Concurrency: *concurrency, Openings: *openings, Seed: *seed}
+	var err error
+	if cfg.Format, err = tournament.ParseFormat(*format); err != nil {
+		return err
+	}
+	if *popout {
+		cfg.Options = append(cfg.Options, game.WithVariant(game.PopOut))
+	}
+	if *sprtBounds != "" {
+		cfg.SPRT = &tournament.SPRT{Alpha: *alpha, Beta: *beta}
+		if _, err := fmt.Sscanf(*sprtBounds, "%g,%g", &cfg.SPRT.Elo0, &cfg.SPRT.Elo1); err != nil {
+			return fmt.Errorf("invalid SPRT bounds %q, expected elo0,elo1", *sprtBounds)
+		}
+	}
+	for _, spec := range fs.Args() {
+		p, err := tournament.ParsePlayer(spec, *moveTime)
+		if err != nil {
+			return err
+		}
+		cfg.Players = append(cfg.Players, p)
+	}
+	cfg.OnGame = func(g tournament.Game) {
+		result := []string{"1/2-1/2", "1-0", "0-1"}[g.Outcome]
+		if g.Reason != "" {
+			result += " (" + g.Reason + ")"
+		}
+		fmt.Printf("game %d: %s vs %s  %s  %s\n", g.Number, cfg.Players[g.First].Name, cfg.Players[g.Second].Name, result, g.Moves)
+	}
+
+	r, err := tournament.Run(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	if err := r.WriteTable(os.Stdout); err != nil {
+		return err
+	}
+	if cfg.SPRT != nil {
+		lower, upper := cfg.SPRT.Bounds()
+		fmt.Printf("\nSPRT elo0=%g elo1=%g: LLR %.2f [%.2f, %.2f], %v\n", cfg.SPRT.Elo0, cfg.SPRT.Elo1, r.LLR, lower, upper, r.Decision)
+	}
+	return nil
 }
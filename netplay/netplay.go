@@ -0,0 +1,537 @@
+// Package netplay permet à deux instances du jeu de s'affronter à travers
+// le réseau local : l'une héberge la partie (Listen), l'autre la rejoint
+// par son adresse (Dial).
+//
+// Le protocole échange des messages JSON, un par ligne, sur une connexion
+// TCP. L'invité se présente par un message "hello" portant la version du
+// protocole ; l'hôte répond par "welcome" avec les règles de la partie et
+// les coups déjà joués. Chaque coup est ensuite envoyé dans un message
+// "move" portant son numéro et la clé de la position atteinte
+// (GameManager.PositionKey) : chaque instance valide les coups de l'autre
+// en les rejouant et signale toute divergence par un message "desync".
+//
+// Chaque instance tient sa propre partie : les coups du joueur local y
+// sont joués par MakePlayerTurn ou MakePlayerPop, ceux du joueur distant
+// par MakeOpponentTurn ou MakeOpponentPop. Après une coupure, les deux
+// instances appellent Reconnect : l'invité se reconnecte avec l'identifiant
+// de la session et les deux parties sont resynchronisées à partir de leurs
+// coups.
+package netplay
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// Version est la version du protocole. Une instance refuse les invités
+// d'une autre version.
+const Version = 1
+
+// DefaultPort est le port utilisé lorsque l'adresse n'en précise pas.
+const DefaultPort = "4404"
+
+// handshakeTimeout borne la durée de l'échange de "hello" et "welcome".
+const handshakeTimeout = 10 * time.Second
+
+var (
+	// ErrVersion est renvoyée lorsque l'hôte et l'invité n'utilisent pas la
+	// même version du protocole.
+	ErrVersion = errors.New("netplay: protocol version mismatch")
+	// ErrRefused est renvoyée lorsque l'hôte refuse la connexion.
+	ErrRefused = errors.New("netplay: connection refused by the host")
+	// ErrDesync est renvoyée lorsque les deux parties ne sont plus dans la
+	// même position : la session ne peut pas continuer.
+	ErrDesync = errors.New("netplay: games out of sync")
+	// ErrDisconnected est renvoyée lorsque la connexion est coupée ; la
+	// partie peut reprendre après Reconnect.
+	ErrDisconnected = errors.New("netplay: disconnected")
+	// ErrPeerLeft est renvoyée lorsque le joueur distant a quitté la partie.
+	ErrPeerLeft = errors.New("netplay: the other player left")
+	// ErrNotYourTurn est renvoyée par Play lorsque c'est au joueur distant
+	// de jouer.
+	ErrNotYourTurn = errors.New("netplay: not your turn")
+)
+
+// message est un message du protocole. Seuls les champs utiles à chaque
+// type sont renseignés.
+type message struct {
+	Type    string `json:"type"`              // hello, welcome, move, desync, error ou bye
+	Version int    `json:"version,omitempty"` // hello, welcome
+	Name    string `json:"name,omitempty"`    // hello, welcome : nom du joueur
+	Session string `json:"session,omitempty"` // hello (reconnexion), welcome
+
+	// welcome : règles de la partie
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	Connect    int    `json:"connect,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+	GuestFirst bool   `json:"guestFirst,omitempty"`
+
+	Moves  string `json:"moves,omitempty"`  // hello (reconnexion), welcome : coups joués en notation compacte
+	Ply    int    `json:"ply,omitempty"`    // move : numéro du coup (0 pour le premier)
+	Column int    `json:"column,omitempty"` // move : colonne, numérotée à partir de 0
+	Pop    bool   `json:"pop,omitempty"`    // move : retrait plutôt que dépôt
+	Key    uint64 `json:"key,omitempty"`    // move, welcome : clé de la position atteinte
+	Error  string `json:"error,omitempty"`  // desync, error : explication
+}
+
+// Options décrit la partie proposée par l'hôte.
+type Options struct {
+	Name                   string       // Nom du joueur local, affiché chez l'autre joueur
+	Width, Height, Connect int          // Dimensions du plateau (zéro : plateau standard)
+	Variant                game.Variant // Règles de la partie
+	GuestFirst             bool         // true si l'invité joue le premier coup
+}
+
+// Conn est une partie en réseau, vue depuis une des deux instances. Une
+// Conn n'est pas utilisable par plusieurs goroutines à la fois.
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	gm      *game.GameManager
+	first   bool   // true si le joueur local joue le premier coup
+	session string // Identifiant de la session, utilisé pour se reconnecter
+	peer    string // Nom du joueur distant
+
+	host *Host  // Hôte de la session (nil pour l'invité)
+	addr string // Adresse de l'hôte (invité)
+	name string // Nom du joueur local
+}
+
+// Game renvoie la partie en cours. Elle ne doit pas être modifiée
+// directement : les coups se jouent par Play et Receive.
+func (c *Conn) Game() *game.GameManager {
+	return c.gm
+}
+
+// PeerName renvoie le nom du joueur distant.
+func (c *Conn) PeerName() string {
+	return c.peer
+}
+
+// IsHost indique si l'instance locale héberge la partie.
+func (c *Conn) IsHost() bool {
+	return c.host != nil
+}
+
+// MyTurn indique si c'est au joueur local de jouer.
+func (c *Conn) MyTurn() bool {
+	return c.gm.GetState() == game.Running && c.isLocalPly(len(c.gm.History()))
+}
+
+// isLocalPly indique si le coup numéro ply revient au joueur local.
+func (c *Conn) isLocalPly(ply int) bool {
+	return (ply%2 == 0) == c.first
+}
+
+// Play joue un coup du joueur local et l'envoie au joueur distant. Un coup
+// illégal est refusé sans être envoyé. Si l'envoi échoue, le coup reste
+// joué et l'erreur enveloppe ErrDisconnected : il sera transmis lors de
+// Reconnect.
+func (c *Conn) Play(kind game.MoveKind, column int) error {
+	if !c.MyTurn() {
+		return ErrNotYourTurn
+	}
+	ply := len(c.gm.History())
+	if err := c.apply(game.Move{Column: column, Kind: kind}, true); err != nil {
+		return err
+	}
+	return c.send(message{Type: "move", Ply: ply, Column: column, Pop: kind == game.MovePop, Key: c.gm.PositionKey()})
+}
+
+// Receive attend le prochain coup du joueur distant, le valide et le joue.
+// Une erreur enveloppant ErrDisconnected signale une coupure de la
+// connexion ; ErrDesync, ErrPeerLeft ou l'erreur de ctx mettent fin à
+// l'attente sans qu'une reconnexion soit utile (sauf pour ctx).
+func (c *Conn) Receive(ctx context.Context) (game.Move, error) {
+	// comme dans accept, l'échéance n'est effacée qu'une fois posée
+	expired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(expired)
+		c.conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer func() {
+		if !stop() {
+			<-expired
+			c.conn.SetReadDeadline(time.Time{})
+		}
+	}()
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return game.Move{}, ctx.Err()
+			}
+			return game.Move{}, err
+		}
+		switch msg.Type {
+		case "move":
+			return c.receiveMove(msg)
+		case "desync":
+			return game.Move{}, fmt.Errorf("%w: %s", ErrDesync, msg.Error)
+		case "bye":
+			return game.Move{}, ErrPeerLeft
+		}
+		// les autres messages sont ignorés, pour les versions futures
+	}
+}
+
+// receiveMove valide et joue le coup reçu dans msg.
+func (c *Conn) receiveMove(msg message) (game.Move, error) {
+	ply := len(c.gm.History())
+	if msg.Ply != ply || c.isLocalPly(ply) || c.gm.GetState() != game.Running {
+		return game.Move{}, c.desync(fmt.Sprintf("unexpected move %d, expected move %d of the other player", msg.Ply+1, ply+1))
+	}
+	kind := game.MoveDrop
+	if msg.Pop {
+		kind = game.MovePop
+	}
+	if err := c.apply(game.Move{Column: msg.Column, Kind: kind}, false); err != nil {
+		return game.Move{}, c.desync(fmt.Sprintf("move %d refused: %v", ply+1, err))
+	}
+	if key := c.gm.PositionKey(); key != msg.Key {
+		return game.Move{}, c.desync(fmt.Sprintf("position after move %d differs", ply+1))
+	}
+	history := c.gm.History()
+	return history[len(history)-1], nil
+}
+
+// desync signale au joueur distant que les parties divergent et renvoie
+// l'erreur correspondante.
+func (c *Conn) desync(reason string) error {
+	c.send(message{Type: "desync", Error: reason})
+	return fmt.Errorf("%w: %s", ErrDesync, reason)
+}
+
+// apply joue le coup m dans la partie, pour le joueur local si local est
+// vrai et pour le joueur distant sinon.
+func (c *Conn) apply(m game.Move, local bool) error {
+	var err error
+	switch {
+	case local && m.Kind == game.MovePop:
+		_, err = c.gm.MakePlayerPop(m.Column)
+	case local:
+		_, err = c.gm.MakePlayerTurn(m.Column)
+	case m.Kind == game.MovePop:
+		_, err = c.gm.MakeOpponentPop(m.Column)
+	default:
+		_, err = c.gm.MakeOpponentTurn(m.Column)
+	}
+	return err
+}
+
+// catchUp joue les coups de moves qui suivent ceux de la partie, moves
+// devant commencer par ces derniers.
+func (c *Conn) catchUp(moves string) error {
+	played := c.gm.MoveString()
+	if !strings.HasPrefix(moves, played) {
+		return fmt.Errorf("moves %q do not extend %q", moves, played)
+	}
+	list, err := game.ParseMoveString(moves[len(played):])
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		if c.gm.GetState() != game.Running {
+			return errors.New("move played after the end of the game")
+		}
+		if err := c.apply(m, c.isLocalPly(len(c.gm.History()))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close quitte la partie : le joueur distant en est averti et, chez
+// l'hôte, plus aucun invité n'est accepté.
+func (c *Conn) Close() error {
+	c.send(message{Type: "bye"})
+	err := c.conn.Close()
+	if c.host != nil {
+		c.host.Close()
+	}
+	return err
+}
+
+// Reconnect rétablit la connexion après une coupure : l'invité se
+// reconnecte à l'hôte, qui attend son retour, puis les deux parties sont
+// resynchronisées. Un coup joué par Play pendant la coupure est transmis.
+func (c *Conn) Reconnect(ctx context.Context) error {
+	c.conn.Close()
+	if c.host != nil {
+		return c.host.accept(ctx, c)
+	}
+	return c.dial(ctx)
+}
+
+// send écrit un message sur la connexion.
+func (c *Conn) send(msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	return nil
+}
+
+// read lit le prochain message de la connexion.
+func (c *Conn) read() (message, error) {
+	return readMessage(c.r)
+}
+
+// readMessage lit un message sur r.
+func readMessage(r *bufio.Reader) (message, error) {
+	var msg message
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return msg, fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return msg, fmt.Errorf("netplay: invalid message: %v", err)
+	}
+	return msg, nil
+}
+
+// withPort ajoute DefaultPort à une adresse qui ne précise pas de port.
+func withPort(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(addr, DefaultPort)
+	}
+	return addr
+}
+
+// Dial rejoint la partie hébergée à l'adresse addr (DefaultPort si elle
+// ne précise pas de port), name étant le nom du joueur local.
+func Dial(ctx context.Context, addr, name string) (*Conn, error) {
+	c := &Conn{addr: withPort(addr), name: name}
+	if err := c.dial(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// dial se connecte à l'hôte et effectue la poignée de main : première
+// connexion si la partie n'existe pas encore, reconnexion sinon.
+func (c *Conn) dial(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	c.conn, c.r = conn, bufio.NewReader(conn)
+	hello := message{Type: "hello", Version: Version, Name: c.name}
+	if c.gm != nil {
+		hello.Session, hello.Moves = c.session, c.gm.MoveString()
+	}
+	if err := c.send(hello); err != nil {
+		conn.Close()
+		return err
+	}
+	welcome, err := c.read()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	switch {
+	case welcome.Type == "error" && welcome.Version != 0 && welcome.Version != Version:
+		conn.Close()
+		return fmt.Errorf("%w: host uses version %d, we use %d", ErrVersion, welcome.Version, Version)
+	case welcome.Type == "error" || welcome.Type == "desync":
+		conn.Close()
+		return fmt.Errorf("%w: %s", ErrRefused, welcome.Error)
+	case welcome.Type != "welcome":
+		conn.Close()
+		return fmt.Errorf("%w: unexpected %q message", ErrRefused, welcome.Type)
+	}
+
+	if c.gm == nil {
+		width, height, connect := welcome.Width, welcome.Height, welcome.Connect
+		if err := game.CheckGeometry(width, height, connect); err != nil {
+			conn.Close()
+			return fmt.Errorf("%w: %v", ErrRefused, err)
+		}
+		variant := game.Classic
+		if welcome.Variant == game.PopOut.String() {
+			variant = game.PopOut
+		}
+		c.gm = game.NewGameManager(false, 0, game.WithBoardSize(width, height, connect), game.WithVariant(variant))
+		c.first, c.session, c.peer = welcome.GuestFirst, welcome.Session, welcome.Name
+	}
+	if err := c.catchUp(welcome.Moves); err != nil {
+		return c.desync(err.Error())
+	}
+	if c.gm.PositionKey() != welcome.Key {
+		return c.desync("position differs after reconnecting")
+	}
+	return nil
+}
+
+// Host attend les joueurs qui rejoignent une partie.
+type Host struct {
+	ln   net.Listener
+	opts Options
+}
+
+// Listen héberge une partie décrite par opts sur l'adresse addr (par
+// exemple ":4404", DefaultPort si elle ne précise pas de port).
+func Listen(addr string, opts Options) (*Host, error) {
+	if opts.Width == 0 && opts.Height == 0 && opts.Connect == 0 {
+		opts.Width, opts.Height, opts.Connect = game.DefaultWidth, game.DefaultHeight, game.DefaultConnect
+	}
+	if err := game.CheckGeometry(opts.Width, opts.Height, opts.Connect); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", withPort(addr))
+	if err != nil {
+		return nil, err
+	}
+	return &Host{ln: ln, opts: opts}, nil
+}
+
+// Addr renvoie l'adresse sur laquelle l'hôte attend les joueurs.
+func (h *Host) Addr() net.Addr {
+	return h.ln.Addr()
+}
+
+// Close arrête d'attendre les joueurs.
+func (h *Host) Close() error {
+	return h.ln.Close()
+}
+
+// Accept attend qu'un joueur rejoigne la partie et renvoie la partie en
+// réseau. Les invités dont la version du protocole diffère sont refusés
+// et Accept continue d'attendre.
+func (h *Host) Accept(ctx context.Context) (*Conn, error) {
+	var id [8]byte
+	rand.Read(id[:])
+	c := &Conn{
+		host:    h,
+		name:    h.opts.Name,
+		session: hex.EncodeToString(id[:]),
+		first:   !h.opts.GuestFirst,
+		gm:      game.NewGameManager(false, 0, game.WithBoardSize(h.opts.Width, h.opts.Height, h.opts.Connect), game.WithVariant(h.opts.Variant)),
+	}
+	if err := h.accept(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// accept attend l'invité de la partie c : un nouveau joueur si la partie
+// n'a pas encore d'invité, son retour sinon. À l'échéance de ctx, l'attente
+// est interrompue par un délai dépassé, levé au retour pour que l'hôte
+// puisse attendre de nouveau.
+func (h *Host) accept(ctx context.Context, c *Conn) error {
+	ln, _ := h.ln.(interface{ SetDeadline(time.Time) error })
+	expired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(expired)
+		if ln != nil {
+			ln.SetDeadline(time.Unix(1, 0))
+		}
+	})
+	defer func() {
+		if !stop() {
+			<-expired
+			if ln != nil {
+				ln.SetDeadline(time.Time{})
+			}
+		}
+	}()
+	for {
+		conn, err := h.ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		err = h.handshake(conn, c)
+		if err == nil {
+			return nil
+		}
+		conn.Close()
+		if errors.Is(err, ErrDesync) {
+			return err
+		}
+	}
+}
+
+// handshake répond au "hello" d'un joueur se connectant pour la partie c.
+// Une erreur signale un joueur refusé ; ErrDesync signale en plus que la
+// partie ne peut pas continuer.
+func (h *Host) handshake(conn net.Conn, c *Conn) error {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	r := bufio.NewReader(conn)
+	hello, err := readMessage(r)
+	if err != nil {
+		return err
+	}
+	refuse := func(format string, args ...any) error {
+		reason := fmt.Sprintf(format, args...)
+		data, _ := json.Marshal(message{Type: "error", Version: Version, Error: reason})
+		conn.Write(append(data, '\n'))
+		return fmt.Errorf("%w: %s", ErrRefused, reason)
+	}
+	switch {
+	case hello.Type != "hello":
+		return refuse("expected hello, got %q", hello.Type)
+	case hello.Version != Version:
+		return refuse("unsupported protocol version %d", hello.Version)
+	case c.peer != "" && hello.Session != c.session:
+		return refuse("a game is already in progress")
+	case c.peer == "" && hello.Session != "":
+		return refuse("unknown session")
+	}
+
+	c.conn, c.r = conn, r
+	if c.peer == "" {
+		c.peer = hello.Name
+		if c.peer == "" {
+			c.peer = "guest"
+		}
+	}
+	// le dernier coup de l'invité a pu se perdre pendant la coupure
+	played := c.gm.MoveString()
+	if hello.Moves != played && !strings.HasPrefix(played, hello.Moves) {
+		extra := ""
+		if strings.HasPrefix(hello.Moves, played) {
+			extra = hello.Moves[len(played):]
+		}
+		list, err := game.ParseMoveString(extra)
+		if extra == "" || err != nil || len(list) != 1 || c.gm.GetState() != game.Running || c.isLocalPly(len(c.gm.History())) {
+			return c.desync(fmt.Sprintf("moves %q and %q differ", hello.Moves, played))
+		}
+		if err := c.apply(list[0], false); err != nil {
+			return c.desync(err.Error())
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return c.send(message{
+		Type:       "welcome",
+		Version:    Version,
+		Name:       h.opts.Name,
+		Session:    c.session,
+		Width:      h.opts.Width,
+		Height:     h.opts.Height,
+		Connect:    h.opts.Connect,
+		Variant:    h.opts.Variant.String(),
+		GuestFirst: h.opts.GuestFirst,
+		Moves:      c.gm.MoveString(),
+		Key:        c.gm.PositionKey(),
+	})
+}
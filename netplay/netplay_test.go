@@ -0,0 +1,291 @@
+package netplay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// pair héberge une partie sur l'interface locale et la fait rejoindre,
+// puis renvoie les deux côtés de la partie.
+func pair(t *testing.T, opts Options) (host, guest *Conn) {
+	t.Helper()
+	h, err := Listen("127.0.0.1:0", opts)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { h.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	accepted := make(chan error, 1)
+	go func() {
+		var err error
+		host, err = h.Accept(ctx)
+		accepted <- err
+	}()
+	guest, err = Dial(ctx, h.Addr().String(), "guest")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if err := <-accepted; err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	return host, guest
+}
+
+// exchange joue le coup de from et le fait recevoir par to.
+func exchange(t *testing.T, from, to *Conn, column int) {
+	t.Helper()
+	if err := from.Play(game.MoveDrop, column); err != nil {
+		t.Fatalf("Play(%d): %v", column, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m, err := to.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if m.Column != column || !m.Opponent {
+		t.Fatalf("unexpected move received %+v", m)
+	}
+}
+
+func TestLoopbackGame(t *testing.T) {
+	host, guest := pair(t, Options{Name: "alice"})
+	if !host.IsHost() || guest.IsHost() || guest.PeerName() != "alice" || host.PeerName() != "guest" {
+		t.Fatalf("unexpected handshake: peers %q and %q", host.PeerName(), guest.PeerName())
+	}
+	if !host.MyTurn() || guest.MyTurn() {
+		t.Fatalf("expected the host to move first")
+	}
+	for i, c := range []int{0, 1, 0, 1, 0, 1, 0} {
+		if i%2 == 0 {
+			exchange(t, host, guest, c)
+		} else {
+			exchange(t, guest, host, c)
+		}
+	}
+	if host.Game().GetState() != game.Win || guest.Game().GetState() != game.Lose {
+		t.Fatalf("expected the host to win, got %v and %v", host.Game().GetState(), guest.Game().GetState())
+	}
+	if host.Game().MoveString() != guest.Game().MoveString() || host.MyTurn() || guest.MyTurn() {
+		t.Fatalf("games differ: %q and %q", host.Game().MoveString(), guest.Game().MoveString())
+	}
+
+	guest.Close()
+	if _, err := host.Receive(context.Background()); !errors.Is(err, ErrPeerLeft) {
+		t.Fatalf("expected ErrPeerLeft, got %v", err)
+	}
+}
+
+func TestGuestFirstPopOut(t *testing.T) {
+	host, guest := pair(t, Options{Width: 5, Height: 4, Connect: 4, Variant: game.PopOut, GuestFirst: true})
+	if w, h, _ := guest.Game().BoardSize(); w != 5 || h != 4 || guest.Game().Variant() != game.PopOut {
+		t.Fatalf("the guest did not receive the rules")
+	}
+	if err := host.Play(game.MoveDrop, 0); !errors.Is(err, ErrNotYourTurn) {
+		t.Fatalf("expected ErrNotYourTurn, got %v", err)
+	}
+	exchange(t, guest, host, 2)
+	exchange(t, host, guest, 3)
+	// un coup illégal est refusé localement et n'est pas envoyé
+	if err := guest.Play(game.MovePop, 3); err == nil {
+		t.Fatalf("expected popping an opponent disc to be refused")
+	}
+	if err := guest.Play(game.MovePop, 2); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	m, err := host.Receive(context.Background())
+	if err != nil || m.Kind != game.MovePop || m.Column != 2 {
+		t.Fatalf("unexpected move %+v (%v)", m, err)
+	}
+	if host.Game().PositionKey() != guest.Game().PositionKey() {
+		t.Fatalf("positions differ after the pop")
+	}
+}
+
+func TestReceiveHonoursContext(t *testing.T) {
+	host, guest := pair(t, Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := guest.Receive(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end Receive, got %v", err)
+	}
+	// le délai de la première attente ne s'applique pas à la suivante
+	exchange(t, host, guest, 3)
+}
+
+func TestAcceptAgainAfterTimeout(t *testing.T) {
+	h, err := Listen("127.0.0.1:0", Options{})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer h.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := h.Accept(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end Accept, got %v", err)
+	}
+
+	// le délai de la première attente ne s'applique pas à la suivante
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := h.Accept(ctx)
+		accepted <- err
+	}()
+	if _, err := Dial(ctx, h.Addr().String(), "guest"); err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if err := <-accepted; err != nil {
+		t.Fatalf("expected the second Accept to succeed, got %v", err)
+	}
+}
+
+// rawGuest se connecte à l'hôte sans passer par Dial et envoie hello.
+func rawGuest(t *testing.T, addr string, hello message) (net.Conn, *bufio.Reader, message) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	data, _ := json.Marshal(hello)
+	conn.Write(append(data, '\n'))
+	r := bufio.NewReader(conn)
+	reply, err := readMessage(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return conn, r, reply
+}
+
+func TestVersionMismatchAndDesync(t *testing.T) {
+	h, err := Listen("127.0.0.1:0", Options{})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer h.Close()
+	accepted := make(chan *Conn, 1)
+	go func() {
+		c, err := h.Accept(context.Background())
+		if err != nil {
+			t.Errorf("Accept: %v", err)
+		}
+		accepted <- c
+	}()
+
+	// un invité d'une autre version est refusé, l'hôte attend le suivant
+	_, _, reply := rawGuest(t, h.Addr().String(), message{Type: "hello", Version: Version + 1})
+	if reply.Type != "error" || reply.Version != Version {
+		t.Fatalf("expected the host to refuse the guest, got %+v", reply)
+	}
+
+	conn, r, reply := rawGuest(t, h.Addr().String(), message{Type: "hello", Version: Version, Name: "cheater"})
+	if reply.Type != "welcome" || reply.Width != game.DefaultWidth || reply.Session == "" {
+		t.Fatalf("unexpected welcome %+v", reply)
+	}
+	host := <-accepted
+	if err := host.Play(game.MoveDrop, 3); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	if msg, err := readMessage(r); err != nil || msg.Type != "move" || msg.Column != 3 || msg.Key != host.Game().PositionKey() {
+		t.Fatalf("unexpected move message %+v (%v)", msg, err)
+	}
+	// coup valide mais annoncé avec une mauvaise position
+	data, _ := json.Marshal(message{Type: "move", Ply: 1, Column: 3, Key: 1})
+	conn.Write(append(data, '\n'))
+	if _, err := host.Receive(context.Background()); !errors.Is(err, ErrDesync) {
+		t.Fatalf("expected ErrDesync, got %v", err)
+	}
+	if msg, err := readMessage(r); err != nil || msg.Type != "desync" {
+		t.Fatalf("expected a desync message, got %+v (%v)", msg, err)
+	}
+}
+
+func TestDialRefusedByOtherVersion(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readMessage(bufio.NewReader(conn))
+		data, _ := json.Marshal(message{Type: "error", Version: Version + 1, Error: "unsupported protocol version"})
+		conn.Write(append(data, '\n'))
+	}()
+	if _, err := Dial(context.Background(), ln.Addr().String(), "guest"); !errors.Is(err, ErrVersion) {
+		t.Fatalf("expected ErrVersion, got %v", err)
+	}
+}
+
+// reconnect reconnecte les deux côtés de la partie.
+func reconnect(t *testing.T, host, guest *Conn) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- host.Reconnect(ctx) }()
+	if err := guest.Reconnect(ctx); err != nil {
+		t.Fatalf("guest Reconnect: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("host Reconnect: %v", err)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	host, guest := pair(t, Options{})
+	exchange(t, host, guest, 3)
+
+	// coupure : le coup de l'invité ne parvient pas à l'hôte
+	guest.conn.Close()
+	if _, err := host.Receive(context.Background()); !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("expected ErrDisconnected, got %v", err)
+	}
+	if err := guest.Play(game.MoveDrop, 4); !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("expected the move to be kept until reconnection, got %v", err)
+	}
+	reconnect(t, host, guest)
+	if host.Game().MoveString() != "45" || !host.MyTurn() {
+		t.Fatalf("the host did not receive the move played during the outage: %q", host.Game().MoveString())
+	}
+
+	// coupure : l'invité ne reçoit pas le coup de l'hôte
+	guest.conn.Close()
+	host.Play(game.MoveDrop, 2)
+	reconnect(t, host, guest)
+	if guest.Game().MoveString() != "453" || !guest.MyTurn() {
+		t.Fatalf("the guest did not catch up: %q", guest.Game().MoveString())
+	}
+	exchange(t, guest, host, 3)
+
+	// pendant l'attente de l'hôte, un autre joueur ne peut pas prendre la
+	// place de l'invité
+	guest.conn.Close()
+	done := make(chan error, 1)
+	go func() { done <- host.Reconnect(context.Background()) }()
+	_, _, reply := rawGuest(t, host.host.Addr().String(), message{Type: "hello", Version: Version})
+	if reply.Type != "error" {
+		t.Fatalf("expected another player to be refused, got %+v", reply)
+	}
+	if err := guest.Reconnect(context.Background()); err != nil {
+		t.Fatalf("guest Reconnect: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("host Reconnect: %v", err)
+	}
+	exchange(t, host, guest, 1)
+}
@@ -0,0 +1,576 @@
+// Package server expose des parties de Puissance 4 à travers HTTP, pour
+// des clients web ou des robots jouant selon les règles du paquet game.
+//
+// L'API échange des objets JSON :
+//
+//	POST   /games              crée une partie (NewGame) et renvoie son état (State)
+//	GET    /games/{id}         renvoie l'état de la partie
+//	POST   /games/{id}/moves   joue un coup (MoveRequest) et renvoie l'état de la partie
+//	DELETE /games/{id}         supprime la partie
+//	GET    /games/{id}/events  diffuse les coups de la partie par WebSocket (Event)
+//
+// Contre l'IA, sa réponse est jouée avant que la requête du coup ne
+// reçoive l'état de la partie ; pendant sa réflexion, l'état de la partie
+// reste lisible mais un autre coup est refusé (409). Une partie sans
+// requête pendant idleTimeout est supprimée. Chaque réflexion de l'IA
+// n'utilise qu'un cœur, et le serveur en mène au plus une par cœur. Les colonnes sont numérotées
+// à partir de 0 et les joueurs notés X (premier joueur) et O. Une erreur est renvoyée
+// avec le code HTTP correspondant et un objet {"error": "..."}.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+	"golang.org/x/net/websocket"
+)
+
+// maxGames borne le nombre de parties tenues par un serveur.
+const maxGames = 100
+
+// maxBodySize borne la taille du corps des requêtes.
+const maxBodySize = 1 << 16
+
+// defaultMoveTime borne la réflexion de l'IA sur chaque coup quand les
+// réglages du serveur ne fixent pas de temps de réflexion : à l'échéance,
+// l'IA joue le meilleur coup trouvé.
+const defaultMoveTime = 5 * time.Second
+
+// sessionTableSize et sessionSolverTableSize bornent les tables de
+// transposition de l'IA de chaque partie, pour que maxGames parties
+// tiennent en mémoire.
+const (
+	sessionTableSize       = 1 << 16
+	sessionSolverTableSize = 1 << 18
+)
+
+// idleTimeout est la durée après laquelle une partie sans requête est
+// supprimée.
+const idleTimeout = 30 * time.Minute
+
+// eventBuffer est le nombre d'événements mis en attente pour un abonné ;
+// un abonné trop lent pour les lire est déconnecté.
+const eventBuffer = 32
+
+// NewGame décrit la partie créée par POST /games. Les champs omis
+// désignent une partie locale à deux joueurs sur le plateau standard, aux
+// règles classiques.
+type NewGame struct {
+	Mode       string `json:"mode"`       // "local" (deux joueurs humains) ou "ai"
+	Difficulty int    `json:"difficulty"` // Niveau de l'IA : 1 à game.MaxDifficulty, ou 0 pour un jeu parfait
+	AIFirst    bool   `json:"aiFirst"`    // true si l'IA joue le premier coup
+	Variant    string `json:"variant"`    // "classic" ou "popout"
+	Width      int    `json:"width"`      // Dimensions du plateau (zéro : plateau standard)
+	Height     int    `json:"height"`
+	Connect    int    `json:"connect"` // Nombre de jetons à aligner (zéro : game.DefaultConnect)
+	Seed       int64  `json:"seed"`    // Graine de l'IA (zéro : celle du serveur)
+}
+
+// MoveRequest est le coup joué par POST /games/{id}/moves pour le joueur
+// au trait.
+type MoveRequest struct {
+	Column int  `json:"column"`
+	Pop    bool `json:"pop"` // Retrait du jeton du bas de la colonne (PopOut) plutôt que dépôt
+}
+
+// Move est un coup de la partie.
+type Move struct {
+	Column int    `json:"column"`
+	Pop    bool   `json:"pop,omitempty"`
+	Player string `json:"player"`       // X ou O
+	AI     bool   `json:"ai,omitempty"` // true pour un coup de l'IA
+}
+
+// State est l'état d'une partie.
+type State struct {
+	ID         string   `json:"id"`
+	Mode       string   `json:"mode"`
+	Difficulty int      `json:"difficulty,omitempty"`
+	Variant    string   `json:"variant"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	Connect    int      `json:"connect"`
+	Moves      []Move   `json:"moves"`
+	Board      []string `json:"board"`            // Rangées de haut en bas, une case par caractère : X, O ou .
+	Status     string   `json:"status"`           // "running", "won" ou "tie"
+	ToMove     string   `json:"toMove,omitempty"` // Joueur au trait pendant la partie
+	Winner     string   `json:"winner,omitempty"`
+	Line       [][2]int `json:"line,omitempty"` // Cases alignées par le gagnant : [rangée, colonne], rangée 0 en haut
+}
+
+// Event est un message diffusé par GET /games/{id}/events : l'état de la
+// partie à la connexion (type "state"), puis chaque coup (type "move")
+// avec l'état qui en résulte.
+type Event struct {
+	Type  string `json:"type"`
+	Move  *Move  `json:"move,omitempty"`
+	State State  `json:"state"`
+}
+
+// Server tient les parties et répond aux requêtes de l'API. Il est
+// utilisable par plusieurs goroutines à la fois.
+type Server struct {
+	settings game.Settings
+	moveTime time.Duration // Réflexion maximale de l'IA par coup
+	mux      *http.ServeMux
+	now      func() time.Time // Horloge de l'expiration des parties
+	searches chan struct{}    // Sémaphore des réflexions de l'IA (une par cœur)
+
+	mu    sync.Mutex
+	games map[string]*session
+}
+
+// session est une partie tenue par le serveur.
+type session struct {
+	id         string
+	mode       string
+	difficulty int
+	lastUsed   time.Time // Dernière requête reçue (protégé par Server.mu)
+
+	mu       sync.Mutex // Protège thinking, la modification de gm et subs
+	gm       *game.GameManager
+	thinking bool // L'IA réfléchit à sa réponse, sans que mu soit verrouillé
+	subs     map[chan Event]struct{}
+}
+
+// New crée un serveur. La graine, le temps de réflexion et la
+// bibliothèque d'ouvertures de settings s'appliquent à l'IA des parties
+// créées ; le mode et la difficulté sont choisis par chaque requête de
+// création. Sans temps de réflexion, chaque coup de l'IA est interrompu
+// après defaultMoveTime.
+func New(settings game.Settings) *Server {
+	moveTime := settings.MoveTime
+	if moveTime == 0 {
+		moveTime = defaultMoveTime
+	}
+	s := &Server{
+		settings: settings,
+		moveTime: moveTime,
+		mux:      http.NewServeMux(),
+		now:      time.Now,
+		searches: make(chan struct{}, runtime.NumCPU()),
+		games:    make(map[string]*session),
+	}
+	s.mux.HandleFunc("POST /games", s.handleCreate)
+	s.mux.HandleFunc("GET /games/{id}", s.handleState)
+	s.mux.HandleFunc("DELETE /games/{id}", s.handleDelete)
+	s.mux.HandleFunc("POST /games/{id}/moves", s.handleMove)
+	s.mux.HandleFunc("GET /games/{id}/events", s.handleEvents)
+	return s
+}
+
+// ServeHTTP répond à une requête de l'API, après avoir supprimé les
+// parties inactives.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.evictIdle()
+	s.mux.ServeHTTP(w, r)
+}
+
+// evictIdle supprime les parties sans requête depuis idleTimeout et
+// déconnecte leurs abonnés.
+func (s *Server) evictIdle() {
+	var idle []*session
+	s.mu.Lock()
+	now := s.now()
+	for id, sess := range s.games {
+		if now.Sub(sess.lastUsed) > idleTimeout {
+			delete(s.games, id)
+			idle = append(idle, sess)
+		}
+	}
+	s.mu.Unlock()
+	for _, sess := range idle {
+		sess.close()
+	}
+}
+
+// opponentMove joue la réponse de l'IA dans gm en au plus s.moveTime ; à
+// l'échéance, l'IA joue le meilleur coup trouvé. Chaque recherche
+// n'utilise qu'un cœur et attend son tour quand tous sont occupés.
+func (s *Server) opponentMove(gm *game.GameManager) error {
+	s.searches <- struct{}{}
+	defer func() { <-s.searches }()
+	ctx, cancel := context.WithTimeout(context.Background(), s.moveTime)
+	defer cancel()
+	_, err := gm.MakeOpponentTurnContext(ctx, -1)
+	return err
+}
+
+// newGameManager vérifie la partie demandée et la crée.
+func (s *Server) newGameManager(req NewGame) (*game.GameManager, error) {
+	settings := s.settings
+	switch req.Mode {
+	case "", "local":
+		settings.AI = false
+	case "ai":
+		settings.AI, settings.Difficulty = true, req.Difficulty
+	default:
+		return nil, fmt.Errorf("unknown mode %q (local or ai)", req.Mode)
+	}
+	if req.Seed != 0 {
+		settings.Seed = req.Seed
+	}
+	if settings.AI && settings.Difficulty == 0 && settings.MoveTime == 0 {
+		// l'IA parfaite réfléchit jusqu'à l'échéance de son coup : le
+		// solveur n'en prend que la moitié (voir game.WithPerfectPlay)
+		settings.MoveTime = s.moveTime
+	}
+	if err := settings.Check(); err != nil {
+		return nil, err
+	}
+	var opts []game.Option
+	switch req.Variant {
+	case "", game.Classic.String():
+	case game.PopOut.String():
+		opts = append(opts, game.WithVariant(game.PopOut))
+	default:
+		return nil, fmt.Errorf("unknown variant %q (classic or popout)", req.Variant)
+	}
+	if req.Width != 0 || req.Height != 0 || req.Connect != 0 {
+		width, height, connect := req.Width, req.Height, req.Connect
+		if width == 0 {
+			width = game.DefaultWidth
+		}
+		if height == 0 {
+			height = game.DefaultHeight
+		}
+		if connect == 0 {
+			connect = game.DefaultConnect
+		}
+		if err := game.CheckGeometry(width, height, connect); err != nil {
+			return nil, err
+		}
+		opts = append(opts, game.WithBoardSize(width, height, connect))
+	}
+	if settings.AI {
+		opts = append(opts,
+			game.WithWorkers(1),
+			game.WithTranspositionTable(game.NewTranspositionTable(sessionTableSize, game.ReplaceDepthPreferred)),
+			game.WithSolver(game.NewSolver(sessionSolverTableSize)))
+	}
+	return settings.NewGameManager(opts...), nil
+}
+
+// handleCreate répond à POST /games.
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	// un corps vide demande la partie par défaut
+	var req NewGame
+	if r.ContentLength != 0 && !decode(w, r, &req) {
+		return
+	}
+	gm, err := s.newGameManager(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.AIFirst && !gm.IsAI() {
+		writeError(w, http.StatusBadRequest, "aiFirst requires the ai mode")
+		return
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	sess := &session{id: hex.EncodeToString(id[:]), mode: "local", gm: gm, subs: make(map[chan Event]struct{})}
+	if gm.IsAI() {
+		sess.mode, sess.difficulty = "ai", req.Difficulty
+	}
+
+	// la place est réservée avant le premier coup de l'IA, pendant lequel
+	// les autres coups sont refusés
+	sess.thinking = req.AIFirst
+	s.mu.Lock()
+	if len(s.games) >= maxGames {
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "too many games")
+		return
+	}
+	sess.lastUsed = s.now()
+	s.games[sess.id] = sess
+	s.mu.Unlock()
+	if req.AIFirst {
+		err := s.opponentMove(gm)
+		sess.mu.Lock()
+		sess.thinking = false
+		sess.broadcast()
+		sess.mu.Unlock()
+		if err != nil {
+			s.mu.Lock()
+			delete(s.games, sess.id)
+			s.mu.Unlock()
+			sess.close()
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Location", "/games/"+sess.id)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	writeJSON(w, http.StatusCreated, sess.state())
+}
+
+// lookup renvoie la partie désignée par la requête, ou répond 404 et
+// renvoie nil si elle n'existe pas.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *session {
+	s.mu.Lock()
+	sess := s.games[r.PathValue("id")]
+	if sess != nil {
+		sess.lastUsed = s.now()
+	}
+	s.mu.Unlock()
+	if sess == nil {
+		writeError(w, http.StatusNotFound, "unknown game")
+	}
+	return sess
+}
+
+// handleState répond à GET /games/{id}.
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	writeJSON(w, http.StatusOK, sess.state())
+}
+
+// handleDelete répond à DELETE /games/{id} : la partie est supprimée et
+// ses abonnés déconnectés.
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	s.mu.Lock()
+	delete(s.games, sess.id)
+	s.mu.Unlock()
+	sess.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// close déconnecte les abonnés de la partie supprimée.
+func (sess *session) close() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for ch := range sess.subs {
+		close(ch)
+		delete(sess.subs, ch)
+	}
+}
+
+// handleMove répond à POST /games/{id}/moves. La partie n'est pas
+// verrouillée pendant la réflexion de l'IA, qui ne bloque donc pas la
+// lecture de son état ; sess.thinking refuse entre-temps les autres coups.
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	var req MoveRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	gm := sess.gm
+	if sess.thinking {
+		writeError(w, http.StatusConflict, "the AI is thinking")
+		return
+	}
+	if gm.GetState() != game.Running {
+		writeError(w, http.StatusConflict, "the game is over")
+		return
+	}
+	var err error
+	if req.Pop {
+		_, err = gm.MakePlayerPop(req.Column)
+	} else {
+		_, err = gm.MakePlayerTurn(req.Column)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sess.broadcast()
+
+	if gm.IsAI() && gm.GetState() == game.Running {
+		// la réponse de l'IA ne dépend pas de la requête : elle est jouée
+		// même si le client se déconnecte entre-temps
+		sess.thinking = true
+		sess.mu.Unlock()
+		err := s.opponentMove(gm)
+		sess.mu.Lock()
+		sess.thinking = false
+		switch {
+		case errors.Is(err, game.ErrPositionChanged):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sess.broadcast()
+	}
+	writeJSON(w, http.StatusOK, sess.state())
+}
+
+// handleEvents répond à GET /games/{id}/events en diffusant les coups de
+// la partie par WebSocket jusqu'à la déconnexion du client ou la
+// suppression de la partie.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookup(w, r)
+	if sess == nil {
+		return
+	}
+	// websocket.Server, contrairement à websocket.Handler, ne vérifie pas
+	// l'en-tête Origin : le flux est en lecture seule et les robots n'en
+	// envoient pas toujours
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		events, cancel := sess.subscribe()
+		defer cancel()
+		// les messages du client sont ignorés ; la fin de leur lecture
+		// signale la déconnexion
+		closed := make(chan struct{})
+		go func() {
+			io.Copy(io.Discard, ws)
+			close(closed)
+		}()
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, ev); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}}.ServeHTTP(w, r)
+}
+
+// subscribe abonne un client aux coups de la partie. Le premier événement
+// reçu est l'état de la partie ; cancel met fin à l'abonnement.
+func (sess *session) subscribe() (events <-chan Event, cancel func()) {
+	ch := make(chan Event, eventBuffer)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	ch <- Event{Type: "state", State: sess.state()}
+	sess.subs[ch] = struct{}{}
+	return ch, func() {
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		if _, ok := sess.subs[ch]; ok {
+			close(ch)
+			delete(sess.subs, ch)
+		}
+	}
+}
+
+// broadcast envoie le dernier coup joué aux abonnés. sess.mu doit être
+// verrouillé.
+func (sess *session) broadcast() {
+	history := sess.gm.History()
+	move := toMove(history[len(history)-1])
+	ev := Event{Type: "move", Move: &move, State: sess.state()}
+	for ch := range sess.subs {
+		select {
+		case ch <- ev:
+		default:
+			// abonné trop lent : il est déconnecté plutôt que de bloquer la
+			// partie
+			close(ch)
+			delete(sess.subs, ch)
+		}
+	}
+}
+
+// state renvoie l'état de la partie. sess.mu doit être verrouillé.
+func (sess *session) state() State {
+	gm := sess.gm
+	width, height, connect := gm.BoardSize()
+	st := State{
+		ID:         sess.id,
+		Mode:       sess.mode,
+		Difficulty: sess.difficulty,
+		Variant:    gm.Variant().String(),
+		Width:      width,
+		Height:     height,
+		Connect:    connect,
+		Moves:      []Move{},
+		Board:      strings.Split(gm.Board().String(), "\n"),
+	}
+	history := gm.History()
+	for _, m := range history {
+		st.Moves = append(st.Moves, toMove(m))
+	}
+	switch gm.GetState() {
+	case game.Running:
+		st.Status = "running"
+		st.ToMove = "X"
+		if len(history)%2 == 1 {
+			st.ToMove = "O"
+		}
+	case game.Tie:
+		st.Status = "tie"
+	default:
+		st.Status = "won"
+		if ok, rows, cols := gm.WhereConnected(); ok {
+			st.Winner = symbol(gm.GetHoleColor(rows[0], cols[0]))
+			for i := range rows {
+				st.Line = append(st.Line, [2]int{rows[i], cols[i]})
+			}
+		}
+	}
+	return st
+}
+
+// toMove convertit un coup de l'historique.
+func toMove(m game.Move) Move {
+	return Move{Column: m.Column, Pop: m.Kind == game.MovePop, Player: symbol(m.Player), AI: m.Opponent}
+}
+
+// symbol renvoie la lettre désignant un joueur, comme Board.String.
+func symbol(player string) string {
+	if player == game.PlayerOneColor {
+		return "X"
+	}
+	return "O"
+}
+
+// decode lit le corps JSON de la requête dans v, ou répond 400 et renvoie
+// false s'il est invalide.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeJSON répond avec le code et l'objet v.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError répond avec le code et le message d'erreur.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
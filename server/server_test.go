@@ -0,0 +1,343 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+	"golang.org/x/net/websocket"
+)
+
+// do envoie une requête au serveur de test et décode la réponse JSON dans
+// out (si out n'est pas nil). Elle renvoie le code HTTP.
+func do(t *testing.T, ts *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding the response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestLocalGame(t *testing.T) {
+	ts := httptest.NewServer(New(game.Settings{}))
+	defer ts.Close()
+
+	var st State
+	if code := do(t, ts, "POST", "/games", "", &st); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if st.Mode != "local" || st.Width != 7 || st.Height != 6 || st.Status != "running" || st.ToMove != "X" {
+		t.Fatalf("unexpected new game %+v", st)
+	}
+	// X aligne quatre jetons dans la colonne 0
+	for i, c := range []int{0, 1, 0, 1, 0, 1, 0} {
+		// les champs omis de la réponse ne doivent pas garder leur valeur
+		// précédente
+		var next State
+		body := `{"column": ` + string(rune('0'+c)) + `}`
+		if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", body, &next); code != http.StatusOK {
+			t.Fatalf("move %d: expected 200, got %d", i, code)
+		}
+		st = next
+	}
+	if st.Status != "won" || st.Winner != "X" || st.ToMove != "" || len(st.Moves) != 7 {
+		t.Fatalf("unexpected final state %+v", st)
+	}
+	if want := [][2]int{{2, 0}, {3, 0}, {4, 0}, {5, 0}}; !slices.Equal(sorted(st.Line), want) {
+		t.Fatalf("unexpected winning line %v", st.Line)
+	}
+	if st.Board[5] != "XO....." {
+		t.Fatalf("unexpected bottom row %q", st.Board[5])
+	}
+
+	var again State
+	if code := do(t, ts, "GET", "/games/"+st.ID, "", &again); code != http.StatusOK || again.Status != "won" {
+		t.Fatalf("GET: %d %+v", code, again)
+	}
+	var e map[string]string
+	if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 3}`, &e); code != http.StatusConflict || e["error"] == "" {
+		t.Fatalf("expected 409 with an error after the end of the game, got %d %v", code, e)
+	}
+	if code := do(t, ts, "DELETE", "/games/"+st.ID, "", nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE: expected 204, got %d", code)
+	}
+	if code := do(t, ts, "GET", "/games/"+st.ID, "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 after DELETE, got %d", code)
+	}
+}
+
+// sorted renvoie les cases triées par rangée puis par colonne.
+func sorted(cells [][2]int) [][2]int {
+	cells = slices.Clone(cells)
+	slices.SortFunc(cells, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	return cells
+}
+
+func TestAIGame(t *testing.T) {
+	ts := httptest.NewServer(New(game.Settings{Seed: 1}))
+	defer ts.Close()
+
+	var st State
+	if code := do(t, ts, "POST", "/games", `{"mode": "ai", "difficulty": 2, "aiFirst": true}`, &st); code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	if len(st.Moves) != 1 || !st.Moves[0].AI || st.Moves[0].Player != "X" || st.ToMove != "O" {
+		t.Fatalf("expected the AI to play first, got %+v", st)
+	}
+	if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 3}`, &st); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(st.Moves) != 3 || st.Moves[1] != (Move{Column: 3, Player: "O"}) || !st.Moves[2].AI {
+		t.Fatalf("expected the AI to answer the move, got %+v", st.Moves)
+	}
+}
+
+// slowEngine réfléchit jusqu'à l'échéance de sa recherche, ou jusqu'à la
+// fermeture de release, puis joue dans la première colonne.
+type slowEngine struct {
+	thinking chan struct{} // Reçoit un message au début de chaque recherche
+	release  chan struct{}
+}
+
+func (e *slowEngine) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	e.thinking <- struct{}{}
+	select {
+	case <-ctx.Done():
+	case <-e.release:
+	}
+	return game.Move{Column: 0, Player: player}, nil
+}
+
+func TestAIThinkingUnlocked(t *testing.T) {
+	e := &slowEngine{thinking: make(chan struct{}, 1), release: make(chan struct{})}
+	ts := httptest.NewServer(New(game.Settings{Opponent: e, MoveTime: time.Minute}))
+	defer ts.Close()
+	var st State
+	do(t, ts, "POST", "/games", `{"mode": "ai", "difficulty": 2}`, &st)
+
+	moved := make(chan State, 1)
+	go func() {
+		var next State
+		do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 3}`, &next)
+		moved <- next
+	}()
+	<-e.thinking
+	// pendant la réflexion de l'IA, l'état reste lisible et les coups sont refusés
+	if code := do(t, ts, "GET", "/games/"+st.ID, "", &st); code != http.StatusOK || len(st.Moves) != 1 || st.ToMove != "O" {
+		t.Fatalf("expected the state during the AI search, got %d %+v", code, st)
+	}
+	if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 2}`, nil); code != http.StatusConflict {
+		t.Fatalf("expected a move during the AI search to be refused, got %d", code)
+	}
+	close(e.release)
+	if st = <-moved; len(st.Moves) != 2 || !st.Moves[1].AI {
+		t.Fatalf("expected the AI to answer, got %+v", st.Moves)
+	}
+}
+
+func TestAIMoveTimeLimit(t *testing.T) {
+	e := &slowEngine{thinking: make(chan struct{}, 1), release: make(chan struct{})}
+	srv := New(game.Settings{Opponent: e})
+	srv.moveTime = 50 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	var st State
+	if code := do(t, ts, "POST", "/games", `{"mode": "ai", "difficulty": 2, "aiFirst": true}`, &st); code != http.StatusCreated || len(st.Moves) != 1 {
+		t.Fatalf("expected the AI to play at the end of its time, got %d %+v", code, st)
+	}
+	select {
+	case <-e.thinking:
+	default:
+		t.Fatalf("expected the AI to have searched")
+	}
+}
+
+func TestFullServerDoesNotSearch(t *testing.T) {
+	e := &slowEngine{thinking: make(chan struct{}, 1), release: make(chan struct{})}
+	srv := New(game.Settings{Opponent: e})
+	for i := range maxGames {
+		srv.games[fmt.Sprint(i)] = &session{lastUsed: time.Now()}
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	if code := do(t, ts, "POST", "/games", `{"mode": "ai", "difficulty": 2, "aiFirst": true}`, nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a full server to refuse the game, got %d", code)
+	}
+	select {
+	case <-e.thinking:
+		t.Fatalf("expected no AI search on a full server")
+	default:
+	}
+}
+
+func TestConcurrentSearches(t *testing.T) {
+	e := &slowEngine{thinking: make(chan struct{}, 2), release: make(chan struct{})}
+	srv := New(game.Settings{Opponent: e, MoveTime: time.Minute})
+	srv.searches = make(chan struct{}, 1)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	var a, b State
+	do(t, ts, "POST", "/games", `{"mode": "ai", "difficulty": 2}`, &a)
+	do(t, ts, "POST", "/games", `{"mode": "ai", "difficulty": 2}`, &b)
+
+	done := make(chan struct{}, 2)
+	for _, id := range []string{a.ID, b.ID} {
+		go func() {
+			do(t, ts, "POST", "/games/"+id+"/moves", `{"column": 3}`, nil)
+			done <- struct{}{}
+		}()
+	}
+	<-e.thinking
+	// la seconde recherche attend la fin de la première
+	select {
+	case <-e.thinking:
+		t.Fatalf("expected a single AI search at a time")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(e.release)
+	<-done
+	<-done
+}
+
+func TestEvictIdleGames(t *testing.T) {
+	srv := New(game.Settings{})
+	now := time.Unix(1000, 0)
+	srv.now = func() time.Time { return now }
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	var old, active State
+	do(t, ts, "POST", "/games", "", &old)
+	do(t, ts, "POST", "/games", "", &active)
+
+	now = now.Add(idleTimeout / 2)
+	do(t, ts, "GET", "/games/"+active.ID, "", nil)
+	now = now.Add(idleTimeout/2 + time.Second)
+	if code := do(t, ts, "GET", "/games/"+old.ID, "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected the idle game to be evicted, got %d", code)
+	}
+	if code := do(t, ts, "GET", "/games/"+active.ID, "", nil); code != http.StatusOK {
+		t.Fatalf("expected the active game to be kept, got %d", code)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := httptest.NewServer(New(game.Settings{}))
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"mode": "remote"}`,
+		`{"mode": "ai", "difficulty": 12}`,
+		`{"variant": "chess"}`,
+		`{"width": 12}`,
+		`{"aiFirst": true}`,
+		`{"colour": "red"}`,
+		`{`,
+	} {
+		var e map[string]string
+		if code := do(t, ts, "POST", "/games", body, &e); code != http.StatusBadRequest || e["error"] == "" {
+			t.Fatalf("%s: expected 400 with an error, got %d %v", body, code, e)
+		}
+	}
+
+	var st State
+	do(t, ts, "POST", "/games", `{"width": 3, "height": 2, "connect": 3}`, &st)
+	for _, body := range []string{`{"column": 3}`, `{"column": -1}`, `{"column": 0, "pop": true}`, `{"column": "a"}`} {
+		if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", body, nil); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, code)
+		}
+	}
+	do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 0}`, nil)
+	do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 0}`, nil)
+	if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 0}`, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected a full column to be refused, got %d", code)
+	}
+	if code := do(t, ts, "POST", "/games/nope/moves", `{"column": 0}`, nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown game, got %d", code)
+	}
+}
+
+func TestPopOutGame(t *testing.T) {
+	ts := httptest.NewServer(New(game.Settings{}))
+	defer ts.Close()
+
+	var st State
+	do(t, ts, "POST", "/games", `{"variant": "popout"}`, &st)
+	do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 2}`, &st)
+	do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 4}`, &st)
+	if code := do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 2, "pop": true}`, &st); code != http.StatusOK {
+		t.Fatalf("expected the pop to be accepted, got %d", code)
+	}
+	if st.Variant != "popout" || !st.Moves[2].Pop || st.Board[5] != "....O.." {
+		t.Fatalf("unexpected state after the pop %+v", st)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	ts := httptest.NewServer(New(game.Settings{}))
+	defer ts.Close()
+
+	var st State
+	do(t, ts, "POST", "/games", "", &st)
+	do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 3}`, &st)
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/games/" + st.ID + "/events"
+	ws, err := websocket.Dial(url, "", ts.URL)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer ws.Close()
+
+	var ev Event
+	if err := websocket.JSON.Receive(ws, &ev); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if ev.Type != "state" || len(ev.State.Moves) != 1 {
+		t.Fatalf("expected the current state on join, got %+v", ev)
+	}
+	do(t, ts, "POST", "/games/"+st.ID+"/moves", `{"column": 4}`, nil)
+	if err := websocket.JSON.Receive(ws, &ev); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if ev.Type != "move" || *ev.Move != (Move{Column: 4, Player: "O"}) || len(ev.State.Moves) != 2 {
+		t.Fatalf("unexpected move event %+v", ev)
+	}
+
+	// la suppression de la partie ferme le flux
+	do(t, ts, "DELETE", "/games/"+st.ID, "", nil)
+	if err := websocket.JSON.Receive(ws, &ev); err == nil {
+		t.Fatalf("expected the stream to end with the game, got %+v", ev)
+	}
+}
+
+func TestDecodeLimit(t *testing.T) {
+	ts := httptest.NewServer(New(game.Settings{}))
+	defer ts.Close()
+	body := `{"mode": "` + string(bytes.Repeat([]byte("a"), maxBodySize)) + `"}`
+	if code := do(t, ts, "POST", "/games", body, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected an oversized body to be refused, got %d", code)
+	}
+}
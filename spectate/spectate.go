@@ -0,0 +1,454 @@
+// Package spectate diffuse en direct, en lecture seule, les parties jouées
+// par une instance du jeu : des spectateurs s'y connectent (Watch) et
+// suivent la partie coup par coup.
+//
+// Le protocole échange des messages JSON, un par ligne, sur une connexion
+// TCP. Le spectateur se présente par un message "watch" portant la version
+// du protocole ; l'instance observée répond par "state" avec les noms des
+// joueurs, les règles et les coups de la partie, puis envoie chaque coup
+// dans un message "move". Une nouvelle partie, une annulation ou un
+// chargement sont diffusés par un nouveau message "state", de même qu'une
+// défaite au temps. Les messages indiquent aussi l'issue de la partie et
+// les jetons alignés par le gagnant (GameManager.WhereConnected). Les
+// messages du spectateur après "watch" sont ignorés.
+package spectate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// Version est la version du protocole. Un spectateur dont la version
+// diffère est refusé.
+const Version = 1
+
+// DefaultPort est le port utilisé lorsque l'adresse n'en précise pas.
+const DefaultPort = "4405"
+
+// handshakeTimeout borne la durée de l'échange de "watch" et "state".
+const handshakeTimeout = 10 * time.Second
+
+// writeTimeout borne l'envoi d'un message à un spectateur.
+const writeTimeout = 10 * time.Second
+
+// queueSize est le nombre de messages mis en attente pour un spectateur ;
+// un spectateur trop lent pour les lire est déconnecté.
+const queueSize = 64
+
+var (
+	// ErrVersion est renvoyée lorsque le spectateur et l'instance observée
+	// n'utilisent pas la même version du protocole.
+	ErrVersion = errors.New("spectate: protocol version mismatch")
+	// ErrClosed est renvoyée lorsque l'instance observée a arrêté la
+	// diffusion ou que la connexion est coupée.
+	ErrClosed = errors.New("spectate: feed closed")
+)
+
+// message est un message du protocole. Seuls les champs utiles à chaque
+// type sont renseignés.
+type message struct {
+	Type    string `json:"type"`              // watch, state, move ou error
+	Version int    `json:"version,omitempty"` // watch, state, error
+
+	// state : joueurs et règles de la partie
+	Names   []string `json:"names,omitempty"` // Premier joueur (X), puis second (O)
+	Width   int      `json:"width,omitempty"`
+	Height  int      `json:"height,omitempty"`
+	Connect int      `json:"connect,omitempty"`
+	Variant string   `json:"variant,omitempty"`
+	Moves   string   `json:"moves,omitempty"` // Coups joués en notation compacte
+
+	Ply    int  `json:"ply,omitempty"`    // move : numéro du coup (0 pour le premier)
+	Column int  `json:"column,omitempty"` // move : colonne, numérotée à partir de 0
+	Pop    bool `json:"pop,omitempty"`    // move : retrait plutôt que dépôt
+
+	// state, move : issue de la partie
+	Status  string   `json:"status,omitempty"`  // running, won ou tie
+	Winner  string   `json:"winner,omitempty"`  // X ou O
+	Line    [][2]int `json:"line,omitempty"`    // Cases alignées par le gagnant : [rangée, colonne], rangée 0 en haut
+	Timeout bool     `json:"timeout,omitempty"` // state : partie gagnée au temps, sans alignement
+
+	Error string `json:"error,omitempty"` // error : explication
+}
+
+// withPort ajoute DefaultPort à une adresse qui ne précise pas de port.
+func withPort(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(addr, DefaultPort)
+	}
+	return addr
+}
+
+// Feed diffuse la partie en cours aux spectateurs connectés. Elle est
+// utilisable par plusieurs goroutines à la fois.
+type Feed struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	state    message // Dernier état publié (Type "state")
+	watchers map[chan message]struct{}
+	closed   bool
+}
+
+// Listen accepte les spectateurs sur l'adresse addr (par exemple ":4405",
+// DefaultPort si elle ne précise pas de port). Tant que Publish n'a pas été
+// appelée, les spectateurs reçoivent un plateau standard vide.
+func Listen(addr string) (*Feed, error) {
+	ln, err := net.Listen("tcp", withPort(addr))
+	if err != nil {
+		return nil, err
+	}
+	f := &Feed{
+		ln:       ln,
+		state:    message{Type: "state", Version: Version, Width: game.DefaultWidth, Height: game.DefaultHeight, Connect: game.DefaultConnect, Variant: game.Classic.String(), Status: "running"},
+		watchers: make(map[chan message]struct{}),
+	}
+	go f.serve()
+	return f, nil
+}
+
+// Addr renvoie l'adresse sur laquelle les spectateurs se connectent.
+func (f *Feed) Addr() net.Addr {
+	return f.ln.Addr()
+}
+
+// Close arrête la diffusion et déconnecte les spectateurs.
+func (f *Feed) Close() error {
+	err := f.ln.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.watchers {
+		close(ch)
+		delete(f.watchers, ch)
+	}
+	return err
+}
+
+// Publish diffuse la partie gm, names étant les noms du premier et du
+// second joueur. Elle peut être appelée après chaque coup ou à chaque
+// image de l'interface : seuls les changements sont envoyés, un coup
+// ajouté à la partie par un message "move" et tout autre changement par un
+// message "state".
+func (f *Feed) Publish(gm *game.GameManager, names [2]string) {
+	state := stateOf(gm, names)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prev := f.state
+	if equalStates(prev, state) {
+		return
+	}
+	f.state = state
+
+	msg := state
+	if move, ok := nextMove(prev, state); ok {
+		msg = move
+	}
+	for ch := range f.watchers {
+		select {
+		case ch <- msg:
+		default:
+			// spectateur trop lent : il est déconnecté plutôt que de
+			// bloquer la partie
+			close(ch)
+			delete(f.watchers, ch)
+		}
+	}
+}
+
+// stateOf renvoie le message "state" décrivant la partie gm.
+func stateOf(gm *game.GameManager, names [2]string) message {
+	width, height, connect := gm.BoardSize()
+	msg := message{
+		Type:    "state",
+		Version: Version,
+		Names:   names[:],
+		Width:   width,
+		Height:  height,
+		Connect: connect,
+		Variant: gm.Variant().String(),
+		Moves:   gm.MoveString(),
+	}
+	setOutcome(&msg, gm)
+	return msg
+}
+
+// setOutcome renseigne dans msg l'issue de la partie gm.
+func setOutcome(msg *message, gm *game.GameManager) {
+	switch gm.GetState() {
+	case game.Running:
+		msg.Status = "running"
+	case game.Tie:
+		msg.Status = "tie"
+	default:
+		msg.Status = "won"
+		msg.Winner = "O"
+		if gm.Winner() == game.PlayerOneColor {
+			msg.Winner = "X"
+		}
+		msg.Timeout = gm.TimedOut()
+		if ok, rows, cols := gm.WhereConnected(); ok {
+			for i := range rows {
+				msg.Line = append(msg.Line, [2]int{rows[i], cols[i]})
+			}
+		}
+	}
+}
+
+// equalStates indique si deux messages "state" décrivent la même partie.
+func equalStates(a, b message) bool {
+	return a.Moves == b.Moves && a.Width == b.Width && a.Height == b.Height && a.Connect == b.Connect &&
+		a.Variant == b.Variant && a.Status == b.Status && slices.Equal(a.Names, b.Names)
+}
+
+// nextMove renvoie le message "move" menant de la partie prev à la partie
+// next si next ne fait que lui ajouter un coup.
+func nextMove(prev, next message) (message, bool) {
+	if !strings.HasPrefix(next.Moves, prev.Moves) || !slices.Equal(next.Names, prev.Names) ||
+		next.Width != prev.Width || next.Height != prev.Height || next.Connect != prev.Connect || next.Variant != prev.Variant {
+		return message{}, false
+	}
+	added, err := game.ParseMoveString(next.Moves[len(prev.Moves):])
+	if err != nil || len(added) != 1 {
+		return message{}, false
+	}
+	ply, _ := game.ParseMoveString(prev.Moves)
+	return message{
+		Type:   "move",
+		Ply:    len(ply),
+		Column: added[0].Column,
+		Pop:    added[0].Kind == game.MovePop,
+		Status: next.Status,
+		Winner: next.Winner,
+		Line:   next.Line,
+	}, true
+}
+
+// serve accepte les spectateurs jusqu'à Close.
+func (f *Feed) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.watch(conn)
+	}
+}
+
+// watch répond au "watch" d'un spectateur puis lui envoie la partie
+// jusqu'à sa déconnexion.
+func (f *Feed) watch(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	r := bufio.NewReader(conn)
+	hello, err := readMessage(r)
+	if err != nil {
+		return
+	}
+	if hello.Type != "watch" || hello.Version != Version {
+		writeMessage(conn, message{Type: "error", Version: Version, Error: fmt.Sprintf("expected a watch message of version %d", Version)})
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	ch := make(chan message, queueSize)
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	ch <- f.state
+	f.watchers[ch] = struct{}{}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.watchers[ch]; ok {
+			close(ch)
+			delete(f.watchers, ch)
+		}
+	}()
+
+	// les messages du spectateur sont ignorés sans être découpés en
+	// lignes, qu'il pourrait ne jamais terminer ; la fin de leur lecture
+	// signale sa déconnexion
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, r)
+		close(gone)
+	}()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if writeMessage(conn, msg) != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// writeMessage écrit un message sur la connexion.
+func writeMessage(conn net.Conn, msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(data, '\n'))
+	return err
+}
+
+// readMessage lit un message sur r.
+func readMessage(r *bufio.Reader) (message, error) {
+	var msg message
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return msg, fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return msg, fmt.Errorf("spectate: invalid message: %v", err)
+	}
+	return msg, nil
+}
+
+// Watcher suit la partie diffusée par une autre instance. Un Watcher n'est
+// pas utilisable par plusieurs goroutines à la fois.
+type Watcher struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	gm    *game.GameManager
+	names [2]string
+}
+
+// Watch se connecte à la diffusion de l'instance d'adresse addr
+// (DefaultPort si elle ne précise pas de port) et reçoit la partie en
+// cours.
+func Watch(ctx context.Context, addr string) (*Watcher, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", withPort(addr))
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := writeMessage(conn, message{Type: "watch", Version: Version}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	msg, err := readMessage(w.r)
+	if err == nil {
+		err = w.apply(msg)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return w, nil
+}
+
+// Game renvoie la partie suivie. Elle est remplacée lorsque Next signale
+// une nouvelle partie et ne doit pas être modifiée.
+func (w *Watcher) Game() *game.GameManager {
+	return w.gm
+}
+
+// Names renvoie les noms du premier et du second joueur.
+func (w *Watcher) Names() [2]string {
+	return w.names
+}
+
+// Next attend le prochain changement de la partie et l'applique. reset
+// indique que la partie a été remplacée (nouvelle partie, annulation ou
+// chargement, voir Game) ; sinon un coup lui a été ajouté. Une erreur
+// enveloppant ErrClosed signale la fin de la diffusion.
+func (w *Watcher) Next(ctx context.Context) (reset bool, err error) {
+	// l'échéance posée à la fin de ctx n'est effacée qu'une fois posée,
+	// pour ne pas s'appliquer à l'attente suivante
+	expired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(expired)
+		w.conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer func() {
+		if !stop() {
+			<-expired
+			w.conn.SetReadDeadline(time.Time{})
+		}
+	}()
+	msg, err := readMessage(w.r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, err
+	}
+	return msg.Type != "move", w.apply(msg)
+}
+
+// apply applique un message de la diffusion à la partie suivie.
+func (w *Watcher) apply(msg message) error {
+	switch msg.Type {
+	case "move":
+		if w.gm == nil || msg.Ply != len(w.gm.History()) {
+			return fmt.Errorf("spectate: unexpected move %d", msg.Ply)
+		}
+		var err error
+		if msg.Pop {
+			_, err = w.gm.MakePlayerPop(msg.Column)
+		} else {
+			_, err = w.gm.MakePlayerTurn(msg.Column)
+		}
+		return err
+	case "state":
+		if msg.Version != Version {
+			return fmt.Errorf("%w: feed uses version %d, we use %d", ErrVersion, msg.Version, Version)
+		}
+		if err := game.CheckGeometry(msg.Width, msg.Height, msg.Connect); err != nil {
+			return err
+		}
+		variant := game.Classic
+		if msg.Variant == game.PopOut.String() {
+			variant = game.PopOut
+		}
+		gm := game.NewGameManager(false, 0, game.WithBoardSize(msg.Width, msg.Height, msg.Connect), game.WithVariant(variant))
+		if err := gm.PlayMoves(msg.Moves); err != nil {
+			return err
+		}
+		if msg.Timeout {
+			// le joueur au trait a perdu au temps
+			gm.TimeOut(false)
+		}
+		w.gm = gm
+		w.names = [2]string{}
+		copy(w.names[:], msg.Names)
+		return nil
+	case "error":
+		if msg.Version != 0 && msg.Version != Version {
+			return fmt.Errorf("%w: feed uses version %d, we use %d", ErrVersion, msg.Version, Version)
+		}
+		return fmt.Errorf("spectate: %s", msg.Error)
+	}
+	return fmt.Errorf("spectate: unexpected %q message", msg.Type)
+}
+
+// Close arrête de suivre la partie.
+func (w *Watcher) Close() error {
+	return w.conn.Close()
+}
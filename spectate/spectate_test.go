@@ -0,0 +1,165 @@
+package spectate
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// listen démarre une diffusion sur une adresse locale libre.
+func listen(t *testing.T) *Feed {
+	t.Helper()
+	f, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// watch connecte un spectateur à la diffusion f.
+func watch(t *testing.T, f *Feed) *Watcher {
+	t.Helper()
+	w, err := Watch(context.Background(), f.Addr().String())
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+// next attend le prochain changement de la partie suivie par w.
+func next(t *testing.T, w *Watcher) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reset, err := w.Next(ctx)
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	return reset
+}
+
+func TestWatchGame(t *testing.T) {
+	f := listen(t)
+	names := [2]string{"alice", "bob"}
+	gm := game.NewGameManager(false, 0)
+	for _, c := range []int{0, 1, 0} {
+		gm.MakePlayerTurn(c)
+	}
+	f.Publish(gm, names)
+
+	// un spectateur arrivant en cours de partie reçoit tous les coups joués
+	w := watch(t, f)
+	if w.Names() != names || w.Game().MoveString() != "121" {
+		t.Fatalf("unexpected game on join: %v %q", w.Names(), w.Game().MoveString())
+	}
+
+	// les coups suivants arrivent un par un ; une publication sans
+	// changement n'envoie rien
+	for _, c := range []int{1, 0, 1, 0} {
+		gm.MakePlayerTurn(c)
+		f.Publish(gm, names)
+		f.Publish(gm, names)
+		if next(t, w) {
+			t.Fatalf("expected a move, got a new game")
+		}
+		if w.Game().MoveString() != gm.MoveString() {
+			t.Fatalf("expected %q, got %q", gm.MoveString(), w.Game().MoveString())
+		}
+	}
+	if w.Game().GetState() == game.Running {
+		t.Fatalf("expected the watched game to be over")
+	}
+	ok, rows, cols := w.Game().WhereConnected()
+	if !ok || len(rows) != 4 || cols[0] != 0 {
+		t.Fatalf("unexpected winning line %v %v", rows, cols)
+	}
+
+	// une annulation est diffusée comme une nouvelle partie
+	gm.Undo()
+	f.Publish(gm, names)
+	if !next(t, w) || w.Game().MoveString() != "121212" || w.Game().GetState() != game.Running {
+		t.Fatalf("expected the undone game, got %q", w.Game().MoveString())
+	}
+
+	// une défaite au temps est diffusée avec son gagnant
+	gm.TimeOut(false)
+	f.Publish(gm, names)
+	if !next(t, w) || !w.Game().TimedOut() || w.Game().Winner() != game.PlayerTwoColor {
+		t.Fatalf("expected a loss on time, got %v won by %q", w.Game().GetState(), w.Game().Winner())
+	}
+}
+
+func TestNextAgainAfterCancel(t *testing.T) {
+	f := listen(t)
+	gm := game.NewGameManager(false, 0)
+	f.Publish(gm, [2]string{"alice", "bob"})
+	w := watch(t, f)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := w.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end Next, got %v", err)
+	}
+	// le délai de la première attente ne s'applique pas à la suivante
+	gm.MakePlayerTurn(3)
+	f.Publish(gm, [2]string{"alice", "bob"})
+	if next(t, w) || w.Game().MoveString() != "4" {
+		t.Fatalf("expected the move after a cancelled wait, got %q", w.Game().MoveString())
+	}
+}
+
+func TestWatchPopOutAndNewGame(t *testing.T) {
+	f := listen(t)
+	w := watch(t, f)
+	if w.Game().MoveString() != "" {
+		t.Fatalf("expected an empty board before the first publication")
+	}
+
+	gm := game.NewGameManager(false, 0, game.WithVariant(game.PopOut), game.WithBoardSize(5, 4, 4))
+	gm.MakePlayerTurn(2)
+	f.Publish(gm, [2]string{"Player", "AI"})
+	if !next(t, w) {
+		t.Fatalf("expected a new game")
+	}
+	gm.MakePlayerTurn(3)
+	gm.MakePlayerPop(2)
+	f.Publish(gm, [2]string{"Player", "AI"})
+	// deux coups publiés à la fois sont diffusés comme une nouvelle partie
+	if !next(t, w) || w.Game().Variant() != game.PopOut || w.Game().MoveString() != "34p3" {
+		t.Fatalf("unexpected game %q", w.Game().MoveString())
+	}
+	if width, height, _ := w.Game().BoardSize(); width != 5 || height != 4 {
+		t.Fatalf("unexpected board size %dx%d", width, height)
+	}
+}
+
+func TestWatchRefusedVersionAndClose(t *testing.T) {
+	f := listen(t)
+	conn, err := net.Dial("tcp", f.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	writeMessage(conn, message{Type: "watch", Version: Version + 1})
+	msg, err := readMessage(bufio.NewReader(conn))
+	if err != nil || msg.Type != "error" {
+		t.Fatalf("expected an error message, got %+v (%v)", msg, err)
+	}
+
+	w := watch(t, f)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := w.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Next to honour its context, got %v", err)
+	}
+	f.Close()
+	if _, err := w.Next(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after Close, got %v", err)
+	}
+}
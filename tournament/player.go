@@ -0,0 +1,238 @@
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbassHammed/c4/engine"
+	"github.com/AbassHammed/c4/game"
+)
+
+// Player est un participant au tournoi. Chaque partie dispose de ses
+// propres moteurs : les parties jouées en parallèle ne partagent ni table
+// de transposition ni processus externe.
+type Player struct {
+	Name string
+	// New crée le moteur d'une partie, dont les coups aléatoires dépendent
+	// de seed ; close, si elle n'est pas nil, est appelée à la fin de la
+	// partie.
+	New func(seed int64) (e game.Engine, close func() error, err error)
+}
+
+// ParsePlayer crée un joueur à partir de sa description :
+//
+//	ab[:niveau][,option=valeur...]    alpha-bêta au niveau 0 à 9 (5 par défaut)
+//	mcts[:simulations][,option=valeur...]
+//	                                  MCTS (game.DefaultMCTSIterations simulations par défaut)
+//	ext:commande                      moteur externe lancé par la commande (voir le paquet engine)
+//
+// Les options d'alpha-bêta sont depth (profondeur, à la place de celle du
+// niveau), time (temps par coup, par exemple 200ms), workers, eval (positional
+// ou zero) et les poids de l'évaluation positionnelle three, two, center et
+// parity ; celles de MCTS sont time, c (constante d'exploration) et rollout
+// (random ou heuristic). L'option name renomme le joueur. moveTime est le
+// temps par coup des joueurs sans option time (0 : profondeur ou nombre de
+// simulations fixe, engine.DefaultMoveTime pour un moteur externe).
+func ParsePlayer(spec string, moveTime time.Duration) (Player, error) {
+	if command, ok := strings.CutPrefix(spec, "ext:"); ok {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return Player{}, fmt.Errorf("player %q: missing engine command", spec)
+		}
+		return Player{Name: spec, New: func(int64) (game.Engine, func() error, error) {
+			e, err := engine.Start(args[0], args[1:]...)
+			if err != nil {
+				return nil, nil, err
+			}
+			e.MoveTime = moveTime
+			return e, e.Close, nil
+		}}, nil
+	}
+
+	head, list, _ := strings.Cut(spec, ",")
+	kind, arg, _ := strings.Cut(head, ":")
+	opts := options{}
+	if list != "" {
+		for _, opt := range strings.Split(list, ",") {
+			key, value, ok := strings.Cut(opt, "=")
+			if !ok || value == "" {
+				return Player{}, fmt.Errorf("player %q: invalid option %q", spec, opt)
+			}
+			opts[key] = value
+		}
+	}
+	p := Player{Name: spec}
+	if name, ok := opts["name"]; ok {
+		p.Name = name
+		delete(opts, "name")
+	}
+	var err error
+	switch kind {
+	case "ab":
+		p.New, err = alphaBetaPlayer(arg, opts, moveTime)
+	case "mcts":
+		p.New, err = mctsPlayer(arg, opts, moveTime)
+	default:
+		err = fmt.Errorf("unknown engine %q (ab, mcts or ext)", kind)
+	}
+	if err != nil {
+		return Player{}, fmt.Errorf("player %q: %w", spec, err)
+	}
+	return p, nil
+}
+
+// options lit les options numériques d'un joueur et signale celles qui
+// restent inconnues.
+type options map[string]string
+
+func (o options) int(key string, v *int) error {
+	s, ok := o[key]
+	if !ok {
+		return nil
+	}
+	delete(o, key)
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid %s %q", key, s)
+	}
+	*v = n
+	return nil
+}
+
+func (o options) duration(key string, v *time.Duration) error {
+	s, ok := o[key]
+	if !ok {
+		return nil
+	}
+	delete(o, key)
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid %s %q", key, s)
+	}
+	*v = d
+	return nil
+}
+
+func (o options) unknown() error {
+	for key := range o {
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return nil
+}
+
+// alphaBetaPlayer crée les moteurs alpha-bêta d'un joueur "ab".
+func alphaBetaPlayer(level string, opts options, moveTime time.Duration) (func(int64) (game.Engine, func() error, error), error) {
+	s := game.Settings{Difficulty: 5, MoveTime: moveTime}
+	if level != "" {
+		n, err := strconv.Atoi(level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %q", level)
+		}
+		s.Difficulty = n
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	depth, workers, weights := 0, 1, game.DefaultWeights
+	var evaluator game.Evaluator
+	switch opts["eval"] {
+	case "", "positional":
+	case "zero":
+		evaluator = game.ZeroEvaluator{}
+	default:
+		return nil, fmt.Errorf("unknown evaluator %q (positional or zero)", opts["eval"])
+	}
+	delete(opts, "eval")
+	for key, v := range map[string]*int{
+		"depth": &depth, "workers": &workers,
+		"three": &weights.OpenThree, "two": &weights.OpenTwo, "center": &weights.Center, "parity": &weights.ParityThree,
+	} {
+		if err := opts.int(key, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := opts.duration("time", &s.MoveTime); err != nil {
+		return nil, err
+	}
+	if err := opts.unknown(); err != nil {
+		return nil, err
+	}
+	if evaluator == nil && weights != game.DefaultWeights {
+		evaluator = game.NewPositionalEvaluator(weights)
+	}
+	return func(seed int64) (game.Engine, func() error, error) {
+		a := s.Engine()
+		if depth > 0 {
+			a.Depth = depth
+		}
+		a.Workers = workers
+		a.Evaluator = evaluator
+		a.Rand = rand.New(rand.NewSource(seed))
+		return a, nil, nil
+	}, nil
+}
+
+// mctsPlayer crée les moteurs MCTS d'un joueur "mcts".
+func mctsPlayer(iterations string, opts options, moveTime time.Duration) (func(int64) (game.Engine, func() error, error), error) {
+	m := game.MCTS{Rollout: game.RolloutHeuristic}
+	if iterations != "" {
+		n, err := strconv.Atoi(iterations)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid number of simulations %q", iterations)
+		}
+		m.Iterations = n
+	}
+	if c, ok := opts["c"]; ok {
+		v, err := strconv.ParseFloat(c, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid exploration constant %q", c)
+		}
+		m.Exploration = v
+		delete(opts, "c")
+	}
+	switch opts["rollout"] {
+	case "", "heuristic":
+	case "random":
+		m.Rollout = game.RolloutRandom
+	default:
+		return nil, fmt.Errorf("unknown rollout policy %q (random or heuristic)", opts["rollout"])
+	}
+	delete(opts, "rollout")
+	timed := moveTime
+	if err := opts.duration("time", &timed); err != nil {
+		return nil, err
+	}
+	if err := opts.unknown(); err != nil {
+		return nil, err
+	}
+	if timed > 0 && iterations == "" {
+		// le temps seul limite la recherche
+		m.Iterations = math.MaxInt
+	}
+	return func(seed int64) (game.Engine, func() error, error) {
+		e := m
+		e.Rand = rand.New(rand.NewSource(seed))
+		if timed > 0 {
+			return timedEngine{&e, timed}, nil, nil
+		}
+		return &e, nil, nil
+	}, nil
+}
+
+// timedEngine limite à d le temps de réflexion de chaque coup d'un moteur
+// qui n'a pas d'autre limite que son contexte.
+type timedEngine struct {
+	game.Engine
+	d time.Duration
+}
+
+func (t timedEngine) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.d)
+	defer cancel()
+	return t.Engine.BestMove(ctx, b, player)
+}
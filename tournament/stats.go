@@ -0,0 +1,158 @@
+package tournament
+
+import (
+	"fmt"
+	"math"
+)
+
+// Score compte les résultats d'un joueur, contre un adversaire ou contre
+// l'ensemble des autres joueurs.
+type Score struct {
+	Wins, Draws, Losses int
+}
+
+// Games renvoie le nombre de parties jouées.
+func (s Score) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Points renvoie le nombre de points marqués : un par victoire, un demi par
+// partie nulle.
+func (s Score) Points() float64 {
+	return float64(s.Wins) + float64(s.Draws)/2
+}
+
+// add ajoute les résultats de o à s.
+func (s Score) add(o Score) Score {
+	return Score{s.Wins + o.Wins, s.Draws + o.Draws, s.Losses + o.Losses}
+}
+
+// String écrit le score sous la forme victoires-nulles-défaites.
+func (s Score) String() string {
+	return fmt.Sprintf("%d-%d-%d", s.Wins, s.Draws, s.Losses)
+}
+
+// mean renvoie le score moyen par partie (entre 0 et 1) et sa variance par
+// partie.
+func (s Score) mean() (mean, variance float64) {
+	return meanOf(float64(s.Wins), float64(s.Draws), float64(s.Losses))
+}
+
+// meanOf est mean pour des nombres de victoires, de nulles et de défaites
+// pas forcément entiers.
+func meanOf(wins, draws, losses float64) (mean, variance float64) {
+	n := wins + draws + losses
+	if n == 0 {
+		return 0.5, 0
+	}
+	w, d, l := wins/n, draws/n, losses/n
+	mean = w + d/2
+	variance = w*(1-mean)*(1-mean) + d*(0.5-mean)*(0.5-mean) + l*mean*mean
+	return mean, variance
+}
+
+// eloOf renvoie la différence d'Elo correspondant au score moyen p selon
+// le modèle logistique (infinie pour 0 et 1).
+func eloOf(p float64) float64 {
+	switch {
+	case p <= 0:
+		return math.Inf(-1)
+	case p >= 1:
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/p-1)
+}
+
+// scoreOf renvoie le score moyen attendu avec une différence d'Elo elo.
+func scoreOf(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// Elo estime la différence d'Elo entre le joueur et ses adversaires, avec
+// la demi-largeur de son intervalle de confiance à 95 %. Un score parfait
+// (ou nul) donne une différence infinie.
+func (s Score) Elo() (elo, margin float64) {
+	mean, variance := s.mean()
+	if s.Games() == 0 {
+		return 0, math.Inf(1)
+	}
+	dev := 1.96 * math.Sqrt(variance/float64(s.Games()))
+	return eloOf(mean), (eloOf(mean+dev) - eloOf(mean-dev)) / 2
+}
+
+// SPRT est un test séquentiel du rapport de vraisemblance : il décide, au
+// fil des parties, entre l'hypothèse H0 selon laquelle un joueur n'a pas
+// plus de Elo0 points d'Elo d'avance sur ses adversaires et l'hypothèse H1
+// selon laquelle il en a au moins Elo1, avec des risques d'erreur Alpha
+// (accepter H1 à tort) et Beta (accepter H0 à tort). Par exemple, Elo0 = 0
+// et Elo1 = 10 testent si une modification apporte une amélioration.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// Decision est l'issue d'un SPRT.
+type Decision int
+
+const (
+	Continue Decision = iota // Pas encore assez de parties pour décider
+	AcceptH0                 // H0 retenue : le joueur n'a pas plus de Elo0 points d'avance
+	AcceptH1                 // H1 retenue : le joueur a au moins Elo1 points d'avance
+)
+
+// String renvoie le nom de la décision.
+func (d Decision) String() string {
+	switch d {
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	}
+	return "inconclusive"
+}
+
+// LLR renvoie le logarithme du rapport de vraisemblance de H1 contre H0
+// pour les résultats s, par l'approximation normale du GSPRT.
+func (t SPRT) LLR(s Score) float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	mean, variance := s.mean()
+	if variance == 0 {
+		// résultats tous identiques : un quart de victoire et un quart de
+		// défaite fictifs évitent une variance nulle, qui ferait conclure
+		// dès la première partie
+		mean, variance = meanOf(float64(s.Wins)+0.25, float64(s.Draws), float64(s.Losses)+0.25)
+	}
+	s0, s1 := scoreOf(t.Elo0), scoreOf(t.Elo1)
+	return float64(s.Games()) * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// Bounds renvoie les bornes du LLR au-delà desquelles le test conclut.
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// Decide renvoie la décision du test pour les résultats s.
+func (t SPRT) Decide(s Score) Decision {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return AcceptH1
+	case llr <= lower:
+		return AcceptH0
+	}
+	return Continue
+}
+
+// Check vérifie les paramètres du test.
+func (t SPRT) Check() error {
+	if t.Elo1 <= t.Elo0 {
+		return fmt.Errorf("SPRT: elo1 (%v) must be greater than elo0 (%v)", t.Elo1, t.Elo0)
+	}
+	if t.Alpha <= 0 || t.Alpha >= 1 || t.Beta <= 0 || t.Beta >= 1 {
+		return fmt.Errorf("SPRT: alpha and beta must be between 0 and 1")
+	}
+	return nil
+}
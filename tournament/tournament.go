@@ -0,0 +1,379 @@
+// Package tournament fait s'affronter des configurations de l'IA (niveaux,
+// évaluations, alpha-bêta contre MCTS, moteurs externes) et estime leur
+// différence d'Elo. Les parties sont jouées en parallèle, par paires où
+// chaque joueur commence une fois ; un SPRT peut arrêter le tournoi dès
+// qu'il est possible de décider si le premier joueur est meilleur que les
+// autres.
+package tournament
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// Format est l'organisation des rencontres d'un tournoi.
+type Format int
+
+const (
+	RoundRobin Format = iota // Chaque joueur rencontre tous les autres
+	Gauntlet                 // Le premier joueur rencontre tous les autres, qui ne se rencontrent pas
+)
+
+// ParseFormat renvoie le format de nom name (roundrobin ou gauntlet).
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "roundrobin":
+		return RoundRobin, nil
+	case "gauntlet":
+		return Gauntlet, nil
+	}
+	return 0, fmt.Errorf("unknown tournament format %q (roundrobin or gauntlet)", name)
+}
+
+// Outcome est le résultat d'une partie.
+type Outcome int
+
+const (
+	Draw       Outcome = iota // Partie nulle
+	FirstWins                 // Victoire du joueur ayant commencé
+	SecondWins                // Victoire de l'autre joueur
+)
+
+// Game est une partie terminée du tournoi.
+type Game struct {
+	Number  int     // Numéro de la partie dans l'ordre du calendrier, à partir de 1
+	First   int     // Indice du joueur ayant commencé
+	Second  int     // Indice de l'autre joueur
+	Outcome Outcome // Résultat
+	Reason  string  // Cause d'une fin anormale, par exemple un coup illégal ("" sinon)
+	Moves   string  // Coups en notation compacte, ouverture comprise
+}
+
+// Config décrit un tournoi.
+type Config struct {
+	Players     []Player
+	Format      Format
+	Games       int           // Parties par rencontre, arrondi au nombre pair supérieur
+	Concurrency int           // Parties jouées en même temps (≤ 0 : nombre de cœurs)
+	Openings    int           // Demi-coups tirés au hasard avant que les moteurs jouent (les mêmes pour les deux parties d'une paire)
+	Seed        int64         // Graine des ouvertures et des moteurs
+	Options     []game.Option // Plateau et variante des parties
+	SPRT        *SPRT         // Test arrêtant le tournoi sur les résultats du premier joueur (peut être nil)
+	OnGame      func(Game)    // Appelée après chaque partie, dans l'ordre où elles se terminent (peut être nil)
+}
+
+// Report est le bilan d'un tournoi.
+type Report struct {
+	Players  []string
+	Scores   [][]Score // Scores[i][j] : résultats de i contre j
+	Games    []Game    // Parties, dans l'ordre où elles se sont terminées
+	Decision Decision  // Décision du SPRT (Continue sans test ou s'il n'a pas conclu)
+	LLR      float64   // Logarithme du rapport de vraisemblance du SPRT
+}
+
+// Total renvoie les résultats du joueur i contre tous ses adversaires.
+func (r *Report) Total(i int) Score {
+	var total Score
+	for _, s := range r.Scores[i] {
+		total = total.add(s)
+	}
+	return total
+}
+
+// pairing est une partie prévue au calendrier.
+type pairing struct {
+	number        int
+	first, second int
+	seed          int64
+}
+
+// schedule renvoie le calendrier du tournoi : les rencontres se succèdent
+// paire par paire, pour que chaque rencontre progresse au même rythme.
+func schedule(cfg Config) []pairing {
+	var matches [][2]int
+	for i := range cfg.Players {
+		for j := i + 1; j < len(cfg.Players); j++ {
+			if cfg.Format == Gauntlet && i > 0 {
+				break
+			}
+			matches = append(matches, [2]int{i, j})
+		}
+	}
+	var games []pairing
+	for round := 0; round < (cfg.Games+1)/2; round++ {
+		for _, m := range matches {
+			// les deux parties d'une paire partagent leur graine : seul le
+			// joueur qui commence change
+			seed := cfg.Seed + int64(len(games)/2)
+			games = append(games,
+				pairing{len(games) + 1, m[0], m[1], seed},
+				pairing{len(games) + 2, m[1], m[0], seed})
+		}
+	}
+	return games
+}
+
+// Run joue le tournoi décrit par cfg. Il s'arrête à la fin du calendrier,
+// quand le SPRT conclut (les parties en cours sont alors terminées et
+// comptées) ou à l'échéance de ctx (les parties en cours sont abandonnées).
+// Une erreur d'un moteur arrête le tournoi.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if len(cfg.Players) < 2 {
+		return nil, errors.New("a tournament needs at least two players")
+	}
+	if cfg.Games <= 0 {
+		return nil, fmt.Errorf("invalid number of games per match %d", cfg.Games)
+	}
+	if cfg.SPRT != nil {
+		if err := cfg.SPRT.Check(); err != nil {
+			return nil, err
+		}
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	r := &Report{Scores: make([][]Score, len(cfg.Players))}
+	for i, p := range cfg.Players {
+		r.Players = append(r.Players, p.Name)
+		r.Scores[i] = make([]Score, len(cfg.Players))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		errMu  sync.Mutex
+		runErr error // Première erreur, qui arrête le tournoi
+	)
+	fail := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		if runErr == nil {
+			runErr = err
+			cancel()
+		}
+	}
+
+	jobs, stop := make(chan pairing), make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for _, p := range schedule(cfg) {
+			select {
+			case jobs <- p:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	results := make(chan Game)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				g, err := playGame(ctx, cfg, p)
+				if err != nil {
+					fail(err)
+					return
+				}
+				results <- g
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for g := range results {
+		r.record(g)
+		if cfg.OnGame != nil {
+			cfg.OnGame(g)
+		}
+		if cfg.SPRT != nil && r.Decision == Continue {
+			total := r.Total(0)
+			r.LLR = cfg.SPRT.LLR(total)
+			if r.Decision = cfg.SPRT.Decide(total); r.Decision != Continue {
+				close(stop)
+			}
+		}
+	}
+	return r, runErr
+}
+
+// record ajoute la partie g au bilan.
+func (r *Report) record(g Game) {
+	r.Games = append(r.Games, g)
+	first, second := &r.Scores[g.First][g.Second], &r.Scores[g.Second][g.First]
+	switch g.Outcome {
+	case FirstWins:
+		first.Wins++
+		second.Losses++
+	case SecondWins:
+		first.Losses++
+		second.Wins++
+	default:
+		first.Draws++
+		second.Draws++
+	}
+}
+
+// playGame joue la partie p.
+func playGame(ctx context.Context, cfg Config, p pairing) (Game, error) {
+	g := Game{Number: p.number, First: p.first, Second: p.second}
+	gm := game.NewGameManager(false, 0, cfg.Options...)
+
+	// ouverture aléatoire
+	rng := rand.New(rand.NewSource(p.seed))
+	width, _, _ := gm.BoardSize()
+	for len(gm.History()) < cfg.Openings && gm.GetState() == game.Running {
+		gm.MakePlayerTurn(rng.Intn(width))
+	}
+
+	players := [2]int{p.first, p.second}
+	var engines [2]game.Engine
+	for i, player := range players {
+		e, closeEngine, err := cfg.Players[player].New(p.seed)
+		if err != nil {
+			return g, fmt.Errorf("%s: %w", cfg.Players[player].Name, err)
+		}
+		if closeEngine != nil {
+			defer closeEngine()
+		}
+		engines[i] = e
+	}
+
+	for gm.GetState() == game.Running {
+		if err := ctx.Err(); err != nil {
+			return g, err
+		}
+		turn := len(gm.History()) % 2
+		player := game.PlayerOneColor
+		if turn == 1 {
+			player = game.PlayerTwoColor
+		}
+		var move game.Move
+		var err error
+		if he, ok := engines[turn].(game.HistoryEngine); ok {
+			move, err = he.BestMoveAfter(ctx, gm.Board(), gm.History(), player)
+		} else {
+			move, err = engines[turn].BestMove(ctx, gm.Board(), player)
+		}
+		if err != nil {
+			return g, fmt.Errorf("%s: %w", cfg.Players[players[turn]].Name, err)
+		}
+		if move.Kind == game.MovePop {
+			_, err = gm.MakePlayerPop(move.Column)
+		} else {
+			_, err = gm.MakePlayerTurn(move.Column)
+		}
+		if err != nil {
+			// un coup illégal perd la partie
+			g.Outcome = []Outcome{SecondWins, FirstWins}[turn]
+			g.Reason = fmt.Sprintf("illegal move %s", game.FormatMoves([]game.Move{move}))
+			g.Moves = gm.MoveString()
+			return g, nil
+		}
+	}
+
+	g.Moves = gm.MoveString()
+	if state := gm.GetState(); state != game.Tie {
+		// en partie locale, Win signifie que l'auteur du dernier coup a gagné
+		winner := len(gm.History()) - 1
+		if state == game.Lose {
+			winner++
+		}
+		g.Outcome = []Outcome{FirstWins, SecondWins}[winner%2]
+	}
+	return g, nil
+}
+
+// WriteTable écrit le classement du tournoi et le tableau croisé des
+// résultats (victoires-nulles-défaites de chaque ligne contre chaque
+// colonne), du joueur ayant marqué la plus forte proportion de points au
+// plus faible.
+func (r *Report) WriteTable(w io.Writer) error {
+	order := make([]int, len(r.Players))
+	for i := range order {
+		order[i] = i
+	}
+	ratio := func(i int) float64 {
+		t := r.Total(i)
+		if t.Games() == 0 {
+			return 0
+		}
+		return t.Points() / float64(t.Games())
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch ra, rb := ratio(a), ratio(b); {
+		case ra > rb:
+			return -1
+		case ra < rb:
+			return 1
+		}
+		return 0
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := []string{"#", "Player", "Elo", "±", "Games", "Score", "Draws"}
+	for rank := range order {
+		header = append(header, fmt.Sprint(rank+1))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+	for rank, i := range order {
+		t := r.Total(i)
+		elo, margin := t.Elo()
+		draws := 0.0
+		if t.Games() > 0 {
+			draws = 100 * float64(t.Draws) / float64(t.Games())
+		}
+		row := []string{fmt.Sprint(rank + 1), r.Players[i], formatElo(elo), formatMargin(margin),
+			fmt.Sprint(t.Games()), fmt.Sprintf("%.1f", t.Points()), fmt.Sprintf("%.0f%%", draws)}
+		for _, j := range order {
+			switch s := r.Scores[i][j]; {
+			case i == j:
+				row = append(row, "-")
+			case s.Games() == 0:
+				row = append(row, ".")
+			default:
+				row = append(row, s.String())
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+// formatElo écrit une différence d'Elo, éventuellement infinie.
+func formatElo(elo float64) string {
+	if math.IsInf(elo, 0) {
+		if elo > 0 {
+			return "+inf"
+		}
+		return "-inf"
+	}
+	return fmt.Sprintf("%+.0f", elo)
+}
+
+// formatMargin écrit la marge d'erreur d'une différence d'Elo.
+func formatMargin(margin float64) string {
+	if math.IsInf(margin, 0) || math.IsNaN(margin) {
+		return "inf"
+	}
+	return fmt.Sprintf("%.0f", margin)
+}
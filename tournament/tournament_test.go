@@ -0,0 +1,197 @@
+package tournament
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/AbassHammed/c4/game"
+)
+
+// players crée les joueurs décrits par specs.
+func players(t *testing.T, specs ...string) []Player {
+	t.Helper()
+	var ps []Player
+	for _, spec := range specs {
+		p, err := ParsePlayer(spec, 0)
+		if err != nil {
+			t.Fatalf("ParsePlayer(%q): %v", spec, err)
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+func TestParsePlayer(t *testing.T) {
+	for _, spec := range []string{
+		"ab", "ab:0", "ab:3,depth=2,eval=zero", "ab:5,three=40,center=6,name=wide", "ab,time=10ms,workers=2",
+		"mcts", "mcts:500,c=0.8,rollout=random", "mcts,time=20ms", "ext:c4 engine",
+	} {
+		if _, err := ParsePlayer(spec, 0); err != nil {
+			t.Fatalf("ParsePlayer(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{
+		"", "minimax", "ab:10", "ab:x", "ab,depth=-1", "ab,eval=neural", "ab,colour=red", "ab,depth",
+		"mcts:0", "mcts,rollout=smart", "mcts,time=0", "ext:",
+	} {
+		if _, err := ParsePlayer(spec, 0); err == nil {
+			t.Fatalf("expected ParsePlayer(%q) to fail", spec)
+		}
+	}
+	if p, _ := ParsePlayer("ab:5,name=wide", 0); p.Name != "wide" {
+		t.Fatalf("expected the name option to rename the player, got %q", p.Name)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	cfg := Config{Players: make([]Player, 4), Games: 3}
+	games := schedule(cfg)
+	if len(games) != 6*4 {
+		t.Fatalf("expected 4 games for each of the 6 round-robin matches, got %d", len(games))
+	}
+	first := map[[2]int]int{}
+	for i, g := range games {
+		if g.number != i+1 {
+			t.Fatalf("unexpected game number %d at %d", g.number, i)
+		}
+		first[[2]int{g.first, g.second}]++
+		if i%2 == 1 && (g.first != games[i-1].second || g.seed != games[i-1].seed) {
+			t.Fatalf("expected games %d and %d to swap colours with the same seed", i, i+1)
+		}
+	}
+	for pair, n := range first {
+		if n != 2 {
+			t.Fatalf("expected %v to start 2 games, got %d", pair, n)
+		}
+	}
+
+	cfg.Format = Gauntlet
+	for _, g := range schedule(cfg) {
+		if g.first != 0 && g.second != 0 {
+			t.Fatalf("gauntlet game without the first player: %+v", g)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var played int
+	r, err := Run(context.Background(), Config{
+		Players:     players(t, "ab:1,depth=6", "ab:1,depth=1", "mcts:200"),
+		Games:       4,
+		Concurrency: 3,
+		Openings:    2,
+		Seed:        1,
+		OnGame:      func(Game) { played++ },
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if played != 12 || len(r.Games) != 12 {
+		t.Fatalf("expected 12 games, got %d (%d reported)", len(r.Games), played)
+	}
+	for i := range r.Players {
+		if r.Total(i).Games() != 8 {
+			t.Fatalf("expected each player to play 8 games, got %v", r.Total(i))
+		}
+		for j := range r.Players {
+			if a, b := r.Scores[i][j], r.Scores[j][i]; a.Wins != b.Losses || a.Draws != b.Draws {
+				t.Fatalf("inconsistent scores %v and %v", a, b)
+			}
+		}
+	}
+	if strong, weak := r.Total(0), r.Total(1); strong.Points() <= weak.Points() {
+		t.Fatalf("expected the deeper search to score more: %v against %v", strong, weak)
+	}
+	// les deux parties d'une paire commencent par la même ouverture
+	moves := map[int]string{}
+	for _, g := range r.Games {
+		moves[g.Number] = g.Moves
+	}
+	for n := 1; n < 12; n += 2 {
+		if moves[n][:2] != moves[n+1][:2] {
+			t.Fatalf("games %d and %d have different openings: %q and %q", n, n+1, moves[n], moves[n+1])
+		}
+	}
+
+	var table strings.Builder
+	r.WriteTable(&table)
+	for _, want := range []string{"Player", "Elo", "ab:1,depth=6", "mcts:200"} {
+		if !strings.Contains(table.String(), want) {
+			t.Fatalf("expected %q in the table:\n%s", want, table.String())
+		}
+	}
+}
+
+// illegalEngine joue toujours dans une colonne inexistante.
+type illegalEngine struct{}
+
+func (illegalEngine) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	return game.Move{Column: b.Width(), Player: player}, nil
+}
+
+func TestIllegalMove(t *testing.T) {
+	cheat := Player{Name: "cheat", New: func(int64) (game.Engine, func() error, error) {
+		return illegalEngine{}, nil, nil
+	}}
+	r, err := Run(context.Background(), Config{Players: append(players(t, "ab:1"), cheat), Games: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if s := r.Total(0); s.Wins != 2 {
+		t.Fatalf("expected illegal moves to lose, got %v", s)
+	}
+	for _, g := range r.Games {
+		if g.Reason != "illegal move 8" {
+			t.Fatalf("unexpected reason %q", g.Reason)
+		}
+	}
+}
+
+func TestEloAndSPRT(t *testing.T) {
+	elo, margin := Score{Wins: 60, Draws: 30, Losses: 10}.Elo()
+	if math.Abs(elo-190.8) > 0.1 || margin < 40 || margin > 100 {
+		t.Fatalf("unexpected Elo %v ± %v for a 75%% score", elo, margin)
+	}
+	if elo, _ := (Score{Wins: 3}).Elo(); !math.IsInf(elo, 1) {
+		t.Fatalf("expected a perfect score to give an infinite Elo, got %v", elo)
+	}
+
+	sprt := SPRT{Elo0: 0, Elo1: 20, Alpha: 0.05, Beta: 0.05}
+	for _, c := range []struct {
+		score Score
+		want  Decision
+	}{
+		{Score{}, Continue},
+		{Score{Wins: 1}, Continue},
+		{Score{Wins: 12}, AcceptH1},
+		{Score{Wins: 550, Draws: 200, Losses: 450}, AcceptH1},
+		{Score{Wins: 450, Draws: 200, Losses: 550}, AcceptH0},
+		{Score{Draws: 200}, AcceptH0},
+		{Score{Wins: 10, Draws: 5, Losses: 10}, Continue},
+	} {
+		if got := sprt.Decide(c.score); got != c.want {
+			t.Fatalf("SPRT on %v: expected %v, got %v (LLR %.2f)", c.score, c.want, got, sprt.LLR(c.score))
+		}
+	}
+	if err := (SPRT{Elo0: 5, Elo1: 5, Alpha: 0.05, Beta: 0.05}).Check(); err == nil {
+		t.Fatalf("expected equal hypotheses to be refused")
+	}
+
+	// le SPRT arrête le tournoi bien avant la fin du calendrier
+	r, err := Run(context.Background(), Config{
+		Players:     players(t, "ab:1,depth=5", "ab:1,depth=1,eval=zero"),
+		Games:       200,
+		Concurrency: 2,
+		Openings:    2,
+		Seed:        1,
+		SPRT:        &SPRT{Elo0: 0, Elo1: 50, Alpha: 0.05, Beta: 0.05},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.Decision != AcceptH1 || len(r.Games) >= 100 {
+		t.Fatalf("expected the SPRT to accept H1 early, got %v after %d games", r.Decision, len(r.Games))
+	}
+}
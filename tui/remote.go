@@ -0,0 +1,153 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/netplay"
+)
+
+// reconnectTimeout borne l'attente du retour du joueur distant après une
+// coupure.
+const reconnectTimeout = 2 * time.Minute
+
+// startRemote commence la partie en réseau c.
+func (s *session) startRemote(c *netplay.Conn) {
+	s.remote = c
+	s.gm = c.Game()
+	s.state = playing
+	width, _, _ := s.gm.BoardSize()
+	s.cursor = width / 2
+	s.endIfOver()
+}
+
+// background exécute l'opération réseau op dans une goroutine ; then est
+// appelée par Run avec son résultat. Les touches restent lues pendant
+// l'opération, qui est abandonnée si Run se termine.
+func (s *session) background(op func(ctx context.Context) error, then func(error)) {
+	s.busy = true
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		go func() {
+			select {
+			case <-s.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := op(ctx)
+		select {
+		case s.events <- event{err: err, done: then}:
+		case <-s.stop:
+		}
+	}()
+}
+
+// receiveRemote attend le coup du joueur distant lorsque c'est son tour.
+func (s *session) receiveRemote() {
+	if s.remote == nil || s.busy || s.state != playing || s.gm.GetState() != game.Running || s.remote.MyTurn() {
+		return
+	}
+	s.background(func(ctx context.Context) error {
+		_, err := s.remote.Receive(ctx)
+		return err
+	}, func(err error) {
+		s.remoteDone(err)
+	})
+}
+
+// remoteDone traite le résultat d'une opération réseau : en cas de
+// coupure, la connexion est rétablie ; une autre erreur met fin à la
+// partie.
+func (s *session) remoteDone(err error) {
+	switch {
+	case err == nil:
+		s.message = ""
+		s.endIfOver()
+	case errors.Is(err, netplay.ErrDisconnected):
+		s.message = "connection lost, reconnecting..."
+		s.background(func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+			defer cancel()
+			return s.remote.Reconnect(ctx)
+		}, func(err error) {
+			if err != nil {
+				s.message = "could not reconnect: " + err.Error()
+				s.state = gameOver
+				return
+			}
+			s.message = "reconnected"
+			s.endIfOver()
+		})
+	case errors.Is(err, netplay.ErrPeerLeft):
+		s.message = s.remote.PeerName() + " left the game"
+		s.state = gameOver
+	default:
+		s.message = err.Error()
+		s.state = gameOver
+	}
+}
+
+// handleRemote applique une touche pendant une partie en réseau : seuls
+// le choix de la colonne et les coups du joueur local sont possibles.
+func (s *session) handleRemote(k rune) {
+	width, _, _ := s.gm.BoardSize()
+	kind := game.MoveDrop
+	switch {
+	case k == keyLeft:
+		s.cursor = (s.cursor + width - 1) % width
+		return
+	case k == keyRight:
+		s.cursor = (s.cursor + 1) % width
+		return
+	case k >= '1' && k < '1'+rune(width):
+		s.cursor = int(k - '1')
+		return
+	case k == keyEnter || k == ' ':
+	case k == 'p' || k == 'P':
+		kind = game.MovePop
+	default:
+		return
+	}
+	if s.busy || !s.remote.MyTurn() {
+		s.message = "waiting for " + s.remote.PeerName()
+		return
+	}
+	err := s.remote.Play(kind, s.cursor)
+	if errors.Is(err, netplay.ErrDisconnected) {
+		// le coup est joué et sera transmis à la reconnexion
+		s.remoteDone(err)
+		return
+	}
+	if err != nil {
+		s.message = err.Error()
+		return
+	}
+	s.endIfOver()
+}
+
+// endIfOver passe à l'écran de fin de partie si la partie est terminée.
+func (s *session) endIfOver() {
+	if s.gm.GetState() != game.Running {
+		s.state = gameOver
+	}
+}
+
+// remoteStatus renvoie le message d'état d'une partie en réseau.
+func (s *session) remoteStatus() string {
+	switch s.gm.GetState() {
+	case game.Win:
+		return "You win!"
+	case game.Lose:
+		return "You lost."
+	case game.Tie:
+		return "Tie."
+	}
+	if s.remote.MyTurn() {
+		return "Your turn"
+	}
+	return s.remote.PeerName() + " to move"
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+// makeCbreak n'est pas pris en charge sur ce système : les touches sont
+// lues ligne par ligne.
+func makeCbreak(f *os.File) (func(), error) {
+	return nil, errors.New("terminal mode not supported on this system")
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// makeCbreak passe la console f en mode caractère : les touches sont lues
+// une à une, sans écho, et les flèches sont transmises comme des séquences
+// ANSI. La sortie standard interprète aussi les séquences ANSI. La fonction
+// renvoyée rétablit les modes précédents.
+func makeCbreak(f *os.File) (func(), error) {
+	in := windows.Handle(f.Fd())
+	var oldIn uint32
+	if err := windows.GetConsoleMode(in, &oldIn); err != nil {
+		return nil, err
+	}
+	mode := oldIn&^(windows.ENABLE_ECHO_INPUT|windows.ENABLE_LINE_INPUT|windows.ENABLE_PROCESSED_INPUT) | windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(in, mode); err != nil {
+		return nil, err
+	}
+	out := windows.Handle(os.Stdout.Fd())
+	var oldOut uint32
+	outErr := windows.GetConsoleMode(out, &oldOut)
+	if outErr == nil {
+		windows.SetConsoleMode(out, oldOut|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+	return func() {
+		windows.SetConsoleMode(in, oldIn)
+		if outErr == nil {
+			windows.SetConsoleMode(out, oldOut)
+		}
+	}, nil
+}
@@ -0,0 +1,471 @@
+// Package tui permet de jouer au Puissance 4 dans un terminal (par exemple
+// à travers SSH), avec le même gestionnaire de partie que l'interface
+// graphique.
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/netplay"
+)
+
+// Séquences ANSI utilisées pour l'affichage.
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+	green       = "\x1b[32m"
+	red         = "\x1b[31m"
+	bold        = "\x1b[1m"
+	reverse     = "\x1b[7m"
+	reset       = "\x1b[0m"
+)
+
+// Touches spéciales renvoyées par readKey, en dehors des caractères
+// imprimables.
+const (
+	keyLeft rune = -1 - iota
+	keyRight
+	keyEnter
+	keyQuit
+)
+
+// state est l'écran affiché par le terminal.
+type state int
+
+const (
+	menu state = iota
+	enterAIdifficulty
+	playing
+	gameOver
+)
+
+// dimensions proposées dans le menu (largeur, hauteur, jetons à aligner),
+// comme dans l'interface graphique
+var boardPresets = [][3]int{{7, 6, 4}, {8, 7, 4}, {9, 7, 4}, {9, 7, 5}}
+
+// Config préconfigure le jeu au lancement (voir Run). Les réglages de
+// Settings autres que le mode et la difficulté (graine, temps de réflexion,
+// bibliothèque d'ouvertures) s'appliquent aussi aux parties lancées depuis
+// le menu.
+type Config struct {
+	game.Settings
+	Start   bool // true : la partie décrite par Settings commence sans passer par le menu
+	AIFirst bool // true : l'IA joue le premier coup de la partie lancée par Start
+
+	// Remote est une partie en réseau déjà établie, jouée à la place du
+	// menu et de la partie décrite par Settings (nil : pas de partie en
+	// réseau).
+	Remote *netplay.Conn
+}
+
+// session est une suite de parties jouées dans le terminal.
+type session struct {
+	out     io.Writer
+	config  Config
+	state   state
+	gm      *game.GameManager
+	preset  int          // Indice des dimensions choisies dans boardPresets
+	variant game.Variant // Règles choisies dans le menu
+	cursor  int          // Colonne sélectionnée
+	aiFirst bool         // true si l'IA a joué le premier coup de la partie en cours
+	message string       // Message d'erreur affiché sous le plateau
+
+	remote *netplay.Conn // Partie en réseau (nil : partie locale ou contre l'IA)
+	events chan event    // Touches et fins des opérations réseau, traitées par Run
+	busy   bool          // true pendant une opération réseau (attente d'un coup, reconnexion)
+	stop   chan struct{} // Fermé à la fin de Run, pour abandonner les opérations en cours
+}
+
+// event est une touche lue ou la fin d'une opération réseau.
+type event struct {
+	key  rune
+	err  error
+	done func(error) // Suite de l'opération réseau terminée (nil pour une touche)
+}
+
+// StartTuiGame lance le jeu dans le terminal : l'entrée standard est
+// passée en mode caractère (sans écho ni tampon de ligne) le temps de la
+// session lorsqu'il s'agit d'un terminal.
+func StartTuiGame(cfg Config) error {
+	if restore, err := makeCbreak(os.Stdin); err == nil {
+		defer restore()
+	}
+	fmt.Print(hideCursor)
+	defer fmt.Print(showCursor)
+	return Run(os.Stdin, os.Stdout, cfg)
+}
+
+// Run joue dans le terminal : in fournit les touches tapées et out reçoit
+// l'affichage, fait de séquences ANSI. Run se termine lorsque le joueur
+// quitte (Q, Ctrl-C ou Ctrl-D) ou à la fin de in. Avec cfg.Start, la partie
+// décrite par cfg.Settings commence sans passer par le menu.
+//
+// Avec cfg.Remote, la partie en réseau est jouée directement ; les coups du
+// joueur distant sont attendus pendant que les touches restent lues.
+func Run(in io.Reader, out io.Writer, cfg Config) error {
+	s := &session{out: out, config: cfg, events: make(chan event), stop: make(chan struct{})}
+	defer close(s.stop)
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			k, err := readKey(r)
+			select {
+			case s.events <- event{key: k, err: err}:
+			case <-s.stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	switch {
+	case cfg.Remote != nil:
+		s.startRemote(cfg.Remote)
+	case cfg.Start:
+		s.start(cfg.NewGameManager(s.options()...), cfg.AIFirst)
+	}
+	for {
+		s.receiveRemote()
+		if err := s.render(); err != nil {
+			return err
+		}
+		e := <-s.events
+		if e.done != nil {
+			s.busy = false
+			e.done(e.err)
+			continue
+		}
+		if errors.Is(e.err, io.EOF) {
+			return nil
+		}
+		if e.err != nil {
+			return e.err
+		}
+		if e.key == keyQuit || ((e.key == 'q' || e.key == 'Q') && s.state != enterAIdifficulty) {
+			return nil
+		}
+		s.handle(e.key)
+	}
+}
+
+// readKey lit une touche : un caractère, une flèche gauche ou droite
+// (séquences ESC [ D et ESC [ C), Entrée ou une demande d'arrêt.
+func readKey(r *bufio.Reader) (rune, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch c {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 3, 4: // Ctrl-C, Ctrl-D
+		return keyQuit, nil
+	case 0x1b:
+		if next, err := r.Peek(2); err == nil && next[0] == '[' {
+			r.Discard(2)
+			switch next[1] {
+			case 'D':
+				return keyLeft, nil
+			case 'C':
+				return keyRight, nil
+			}
+		}
+		return 0, nil
+	}
+	return c, nil
+}
+
+// handle applique une touche à l'écran en cours.
+func (s *session) handle(k rune) {
+	s.message = ""
+	switch s.state {
+	case menu:
+		switch k {
+		case 'a', 'A':
+			s.state = enterAIdifficulty
+		case 'p', 'P':
+			settings := s.config.Settings
+			settings.AI = false
+			s.start(settings.NewGameManager(s.options()...), false)
+		case 'g', 'G':
+			s.preset = (s.preset + 1) % len(boardPresets)
+		case 'o', 'O':
+			if s.variant == game.PopOut {
+				s.variant = game.Classic
+			} else {
+				s.variant = game.PopOut
+			}
+		}
+	case enterAIdifficulty:
+		difficulty, err := strconv.Atoi(string(k))
+		if err != nil {
+			s.state = menu
+			return
+		}
+		// 0 : difficulté 10, l'IA joue parfaitement (sur le plateau standard)
+		settings := s.config.Settings
+		settings.AI, settings.Difficulty = true, difficulty
+		s.start(settings.NewGameManager(s.options()...), false)
+	case playing:
+		s.handlePlaying(k)
+	case gameOver:
+		if s.remote != nil {
+			// pas de revanche en réseau : seul Q (géré par Run) reste possible
+			return
+		}
+		switch k {
+		case keyEnter, 'y', 'Y':
+			// comme dans l'interface graphique, l'IA commence la partie
+			// suivante lorsque le joueur a gagné
+			s.aiFirst = s.gm.IsAI() && s.gm.GetState() == game.Win
+			s.gm.ResetGame()
+			s.state = playing
+			s.opponentMoves()
+		case 'u', 'U':
+			if s.gm.Undo() {
+				s.state = playing
+			}
+		case 'm', 'M':
+			s.gm = nil
+			s.state = menu
+		}
+	}
+}
+
+// options renvoie les options de création d'une partie choisies dans le
+// menu.
+func (s *session) options() []game.Option {
+	preset := boardPresets[s.preset]
+	return []game.Option{game.WithBoardSize(preset[0], preset[1], preset[2]), game.WithVariant(s.variant)}
+}
+
+// start commence la partie gm, le joueur ayant le trait sauf si aiFirst
+// est vrai : l'IA joue alors le premier coup.
+func (s *session) start(gm *game.GameManager, aiFirst bool) {
+	s.gm = gm
+	s.state = playing
+	s.aiFirst = aiFirst && gm.IsAI()
+	width, _, _ := gm.BoardSize()
+	s.cursor = width / 2
+	s.opponentMoves()
+}
+
+// handlePlaying applique une touche pendant une partie : choix de la
+// colonne (flèches ou chiffres), dépôt, retrait, annulation ou coup rejoué.
+func (s *session) handlePlaying(k rune) {
+	if s.remote != nil {
+		s.handleRemote(k)
+		return
+	}
+	width, _, _ := s.gm.BoardSize()
+	var ok bool
+	var err error
+	switch {
+	case k == keyLeft:
+		s.cursor = (s.cursor + width - 1) % width
+		return
+	case k == keyRight:
+		s.cursor = (s.cursor + 1) % width
+		return
+	case k >= '1' && k < '1'+rune(width):
+		// les chiffres choisissent la colonne sans jouer : en entrée non
+		// interactive, « 4 » suivi d'Entrée ne joue ainsi qu'un coup
+		s.cursor = int(k - '1')
+		return
+	case k == keyEnter || k == ' ':
+		ok, err = s.gm.MakePlayerTurn(s.cursor)
+	case k == 'p' || k == 'P':
+		ok, err = s.gm.MakePlayerPop(s.cursor)
+	case k == 'u' || k == 'U':
+		ok = s.gm.Undo()
+	case k == 'r' || k == 'R':
+		ok = s.gm.Redo()
+	default:
+		return
+	}
+	if !ok {
+		if err != nil {
+			s.message = err.Error()
+		}
+		return
+	}
+	s.opponentMoves()
+}
+
+// opponentMoves fait jouer l'IA si c'est son tour, puis passe à l'écran de
+// fin de partie si la partie est terminée. Si le moteur de l'IA échoue, la
+// partie est abandonnée et l'erreur affichée au menu, plutôt que de laisser
+// le joueur jouer à la place de l'IA.
+func (s *session) opponentMoves() {
+	if s.gm.GetState() == game.Running && s.gm.IsAI() && s.aiToMove() {
+		s.message = "thinking..."
+		s.render()
+		s.message = ""
+		if _, err := s.gm.MakeOpponentTurn(-1); err != nil {
+			s.message = err.Error()
+			s.gm = nil
+			s.state = menu
+			return
+		}
+	}
+	if s.gm.GetState() != game.Running {
+		s.state = gameOver
+	}
+}
+
+// aiToMove indique si c'est à l'IA de jouer.
+func (s *session) aiToMove() bool {
+	return (len(s.gm.History())%2 == 0) == s.aiFirst
+}
+
+// render affiche l'écran en cours.
+func (s *session) render() error {
+	var b bytes.Buffer
+	b.WriteString(clearScreen)
+	b.WriteString(bold + "Connect four" + reset + "\n\n")
+	switch s.state {
+	case menu:
+		preset := boardPresets[s.preset]
+		b.WriteString("[A] - play against AI\n")
+		b.WriteString("[P] - play local (2 players)\n")
+		fmt.Fprintf(&b, "[G] - board %dx%d, connect %d\n", preset[0], preset[1], preset[2])
+		fmt.Fprintf(&b, "[O] - rules: %s\n", s.variant)
+		b.WriteString("[Q] - quit\n")
+		if s.message != "" {
+			b.WriteString("\n" + s.message + "\n")
+		}
+	case enterAIdifficulty:
+		b.WriteString("Enter difficulty (1-9, 0 = perfect)\n")
+	default:
+		s.renderGame(&b)
+	}
+	_, err := s.out.Write(b.Bytes())
+	return err
+}
+
+// renderGame affiche le score, le plateau et le message d'état d'une
+// partie.
+func (s *session) renderGame(b *bytes.Buffer) {
+	gm := s.gm
+	width, height, connect := gm.BoardSize()
+	fmt.Fprintf(b, "W  %d:%d  L    (%dx%d, connect %d, %s)\n\n", gm.GetWonGames(), gm.GetLostGames(), width, height, connect, gm.Variant())
+
+	winning := make(map[[2]int]bool)
+	if won, rows, cols := gm.WhereConnected(); won {
+		for i := range rows {
+			winning[[2]int{rows[i], cols[i]}] = true
+		}
+	}
+	b.WriteString(" ")
+	for c := 0; c < width; c++ {
+		if c == s.cursor && s.state == playing {
+			b.WriteString(" ▼")
+		} else {
+			b.WriteString("  ")
+		}
+	}
+	b.WriteString("\n ")
+	for c := 0; c < width; c++ {
+		fmt.Fprintf(b, " %d", c+1)
+	}
+	b.WriteString("\n")
+	for r := 0; r < height; r++ {
+		b.WriteString(" │")
+		for c := 0; c < width; c++ {
+			b.WriteString(disc(gm.GetHoleColor(r, c), winning[[2]int{r, c}]))
+			b.WriteString("│")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(" └")
+	for c := 1; c < width; c++ {
+		b.WriteString("─┴")
+	}
+	b.WriteString("─┘\n\n")
+
+	b.WriteString(s.status() + "\n")
+	if s.message != "" {
+		b.WriteString(s.message + "\n")
+	}
+	if s.state == gameOver && s.remote != nil {
+		b.WriteString("\n[Q]uit\n")
+		return
+	}
+	if s.state == gameOver {
+		b.WriteString("\n[Enter] play again  [U]ndo  [M]enu  [Q]uit\n")
+		return
+	}
+	fmt.Fprintf(b, "\n[←/→] or [1-%d] choose  [Enter] drop", width)
+	if gm.Variant() == game.PopOut {
+		b.WriteString("  [P]op")
+	}
+	if s.remote != nil {
+		b.WriteString("  [Q]uit\n")
+		return
+	}
+	b.WriteString("  [U]ndo  [R]edo  [Q]uit\n")
+}
+
+// disc renvoie la représentation colorée d'une case, en vidéo inverse
+// pour un jeton de l'alignement gagnant.
+func disc(hole string, winning bool) string {
+	var color string
+	switch hole {
+	case game.PlayerOneColor:
+		color = green
+	case game.PlayerTwoColor:
+		color = red
+	default:
+		return " "
+	}
+	if winning {
+		color += reverse
+	}
+	return color + "●" + reset
+}
+
+// status renvoie le message d'état de la partie, du point de vue du joueur
+// contre l'IA et en nommant les joueurs en partie locale.
+func (s *session) status() string {
+	gm := s.gm
+	if s.remote != nil {
+		return s.remoteStatus()
+	}
+	switch state := gm.GetState(); {
+	case state == game.Tie:
+		return "Tie."
+	case state == game.Win && gm.IsAI():
+		return "You win!"
+	case state == game.Lose && gm.IsAI():
+		return "You lost."
+	case state != game.Running:
+		// en partie locale, les deux joueurs jouent par MakePlayerTurn : le
+		// gagnant est celui des jetons alignés
+		_, rows, cols := gm.WhereConnected()
+		return playerName(gm.GetHoleColor(rows[0], cols[0])) + " wins!"
+	case gm.IsAI():
+		return "Your turn"
+	case len(gm.History())%2 == 0:
+		return playerName(game.PlayerOneColor) + " to move"
+	}
+	return playerName(game.PlayerTwoColor) + " to move"
+}
+
+// playerName renvoie le nom d'un joueur en partie locale, avec la couleur
+// de ses jetons.
+func playerName(player string) string {
+	if player == game.PlayerOneColor {
+		return "Player 1 (" + disc(player, false) + ")"
+	}
+	return "Player 2 (" + disc(player, false) + ")"
+}
@@ -0,0 +1,235 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/netplay"
+)
+
+// feed applique les touches keys à une nouvelle session et la renvoie avec
+// l'affichage produit.
+func feed(t *testing.T, keys string) (*session, string) {
+	t.Helper()
+	var out bytes.Buffer
+	s := &session{out: &out}
+	r := bufio.NewReader(strings.NewReader(keys))
+	for {
+		k, err := readKey(r)
+		if err != nil {
+			break
+		}
+		s.handle(k)
+		if err := s.render(); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+	}
+	return s, out.String()
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("\x1b[D\x1b[C\r\n4\x03\x04"))
+	want := []rune{keyLeft, keyRight, keyEnter, keyEnter, '4', keyQuit, keyQuit}
+	for i, w := range want {
+		if k, err := readKey(r); err != nil || k != w {
+			t.Fatalf("key %d: expected %d, got %d (%v)", i, w, k, err)
+		}
+	}
+}
+
+func TestMenuOptions(t *testing.T) {
+	s, out := feed(t, "ggop")
+	if s.state != playing {
+		t.Fatalf("expected a game to start, state %v", s.state)
+	}
+	if w, h, n := s.gm.BoardSize(); w != 9 || h != 7 || n != 4 || s.gm.Variant() != game.PopOut {
+		t.Fatalf("unexpected game %dx%d connect %d %v", w, h, n, s.gm.Variant())
+	}
+	if !strings.Contains(out, "rules: popout") || !strings.Contains(out, "board 9x7, connect 4") {
+		t.Fatalf("menu does not show the chosen options:\n%s", out)
+	}
+}
+
+func TestLocalGame(t *testing.T) {
+	// le joueur 1 aligne quatre jetons dans la colonne 1, en choisissant
+	// les colonnes au clavier numérique puis aux flèches
+	s, out := feed(t, "p1\n2\n1\n2\n\x1b[D\n2 1\r")
+	if s.state != gameOver || s.gm.GetState() != game.Win {
+		t.Fatalf("expected the game to be over, got state %v", s.gm.GetState())
+	}
+	if !strings.Contains(out, "Player 1 ("+disc(game.PlayerOneColor, false)+") wins!") {
+		t.Fatalf("expected Player 1 to be announced as the winner:\n%s", out)
+	}
+	if got := s.gm.MoveString(); got != "1212121" {
+		t.Fatalf("unexpected moves %q", got)
+	}
+
+	// Entrée relance une partie, le score est conservé
+	s.handle(keyEnter)
+	if s.state != playing || len(s.gm.History()) != 0 || s.gm.GetWonGames() != 1 {
+		t.Fatalf("expected a new game after the win")
+	}
+}
+
+func TestAIGame(t *testing.T) {
+	s, out := feed(t, "a14\n4\n")
+	if got := len(s.gm.History()); got != 4 {
+		t.Fatalf("expected the AI to answer both moves, got %d moves", got)
+	}
+	if !strings.Contains(out, "thinking...") || !strings.Contains(out, "Your turn") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	// une colonne pleine est refusée avec un message
+	s, out = feed(t, "p4\n\n\n\n\n\n\n")
+	if len(s.gm.History()) != 6 || !strings.Contains(out, "is full") {
+		t.Fatalf("expected the seventh drop to be refused:\n%s", out)
+	}
+}
+
+func TestRunQuits(t *testing.T) {
+	var out bytes.Buffer
+	if err := Run(strings.NewReader("p4\nq4\n"), &out, Config{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// seul le coup joué avant q apparaît sur le dernier écran
+	screens := strings.Split(out.String(), clearScreen)
+	if n := strings.Count(screens[len(screens)-1], "●"+reset+"│"); n != 1 {
+		t.Fatalf("expected a single disc on the last screen, got %d", n)
+	}
+}
+
+func TestPreconfiguredAIFirst(t *testing.T) {
+	var out bytes.Buffer
+	cfg := Config{Settings: game.Settings{AI: true, Difficulty: 1, Seed: 3}, Start: true, AIFirst: true}
+	if err := Run(strings.NewReader(""), &out, cfg); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	screens := strings.Split(out.String(), clearScreen)
+	last := screens[len(screens)-1]
+	if strings.Contains(out.String(), "[A] - play against AI") || strings.Count(last, "●"+reset+"│") != 1 || !strings.Contains(last, "Your turn") {
+		t.Fatalf("expected the AI to open the game without the menu:\n%s", last)
+	}
+}
+
+// failingEngine échoue à chaque recherche, comme un moteur externe arrêté.
+type failingEngine struct{}
+
+func (failingEngine) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	return game.Move{Column: -1}, errors.New("engine exited")
+}
+
+func TestAIEngineFailure(t *testing.T) {
+	var out bytes.Buffer
+	s := &session{out: &out, config: Config{Settings: game.Settings{AI: true, Opponent: failingEngine{}}}}
+	s.start(s.config.NewGameManager(), false)
+	s.handle(keyEnter)
+	// le joueur ne reçoit pas le trait de l'IA
+	if s.state != menu || s.gm != nil {
+		t.Fatalf("expected a failing AI to end the game, state %v", s.state)
+	}
+	out.Reset()
+	s.render()
+	if !strings.Contains(out.String(), "engine exited") {
+		t.Fatalf("expected the error to be shown:\n%s", out.String())
+	}
+}
+
+// syncBuffer est un bytes.Buffer utilisable par plusieurs goroutines.
+type syncBuffer struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	seen int // Fin de la dernière occurrence trouvée par consume
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// consume cherche s dans ce qui a été écrit depuis la dernière occurrence
+// trouvée.
+func (b *syncBuffer) consume(s string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	i := strings.Index(b.buf.String()[b.seen:], s)
+	if i < 0 {
+		return false
+	}
+	b.seen += i + len(s)
+	return true
+}
+
+// waitFor attend que out contienne s après la dernière chaîne attendue.
+func waitFor(t *testing.T, out *syncBuffer, s string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !out.consume(s); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q:\n%s", s, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRemoteGame(t *testing.T) {
+	h, err := netplay.Listen("127.0.0.1:0", netplay.Options{Name: "host"})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer h.Close()
+	accepted := make(chan *netplay.Conn, 1)
+	go func() {
+		c, err := h.Accept(context.Background())
+		if err != nil {
+			t.Errorf("Accept: %v", err)
+		}
+		accepted <- c
+	}()
+	guest, err := netplay.Dial(context.Background(), h.Addr().String(), "bob")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+
+	in, keys := io.Pipe()
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- Run(in, &out, Config{Remote: <-accepted}) }()
+
+	// le joueur local dépose un jeton au centre, le joueur distant répond
+	keys.Write([]byte("\n"))
+	waitFor(t, &out, "bob to move")
+	if m, err := guest.Receive(context.Background()); err != nil || m.Column != 3 {
+		t.Fatalf("unexpected move %+v (%v)", m, err)
+	}
+	if err := guest.Play(game.MoveDrop, 0); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	waitFor(t, &out, "Your turn")
+	// u est sans effet en réseau
+	keys.Write([]byte("u1\n"))
+	if m, err := guest.Receive(context.Background()); err != nil || m.Column != 0 {
+		t.Fatalf("unexpected move %+v (%v)", m, err)
+	}
+	waitFor(t, &out, "bob to move")
+	guest.Close()
+	waitFor(t, &out, "bob left the game")
+	keys.Write([]byte("q"))
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
@@ -2,22 +2,26 @@ package ui
 
 import (
 	"bytes"
+	"fmt"
 	"image"
 
 	"image/color"
 	_ "image/jpeg"
 	_ "image/png"
 	"log"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/AbassHammed/c4/game"
 	"github.com/AbassHammed/c4/images"
+	"github.com/AbassHammed/c4/netplay"
+	"github.com/AbassHammed/c4/spectate"
+	"github.com/AbassHammed/c4/ui/machine"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	textv2 "github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 )
@@ -29,6 +33,7 @@ var backgroundImage,
 	ghost,
 	greenBallImage,
 	boardImage,
+	boardTemplate,
 	bats *ebiten.Image
 
 func byteSliceToEbitenImage(arr []byte) *ebiten.Image {
@@ -47,7 +52,8 @@ func init() {
 	owl = byteSliceToEbitenImage(images.Owl_png)
 	dot = byteSliceToEbitenImage(images.Dot_png)
 	bats = byteSliceToEbitenImage(images.Bats_png)
-	boardImage = byteSliceToEbitenImage(images.Board_png)
+	boardTemplate = byteSliceToEbitenImage(images.Board_png)
+	boardImage = boardTemplate
 	tt, _ := opentype.Parse(images.MPlus1pRegular_ttf)
 	mplusNormalFont, _ = opentype.NewFace(tt, &opentype.FaceOptions{
 		Size:    20,
@@ -59,229 +65,161 @@ func init() {
 	// afin d'utiliser text/v2.Draw (qui attend un text.Face).
 	// NewGoXFace enveloppe font.Face et fournit la mise en cache des glyphes.
 	tvFace = textv2.NewGoXFace(mplusNormalFont)
-	initBallYCoords()
 }
 
-type Game struct{}
-
-type GameState int
-
-func initBallYCoords() {
-	for i := 0; i < 7; i++ {
-		for j := 0; j < 6; j++ {
-			ballYcoords[i][j] = -tileHeight
-		}
-	}
+// Game relie la machine à états de l'interface à Ebiten : Update lui
+// transmet la souris et le clavier, Draw dessine l'état qu'elle expose.
+type Game struct {
+	m     *machine.Machine
+	scale float64 // Échelle de la fenêtre (0 : 1)
+	// dimensions du plateau dessiné par boardImage
+	width, height int
 }
 
 const (
-	yourTurn GameState = iota
-	opponentTurn
-	win
-	lose
-	tie
-	animation
-	opponentAnimation
-	menu
-	enterAIdifficulty
-)
-
-const (
-	batsX             = 440
-	batsY             = 200
-	secondsToMakeTurn = 59
-	fps               = 60
-	tileHeight        = 65
-	tileOffset        = 10
-	boardX            = 84
-	boardY            = 130
-	gravity           = 0.5
+	batsX      = 440
+	batsY      = 200
+	tileHeight = machine.TileSize
+	tileOffset = 10
+	boardX     = 84
+	boardY     = 130
 )
 
-// colonne choisie par l'adversaire lors du dernier coup
-var opponentLastCol int
-var frameCount int
-var gameState GameState = menu
-
-var ballYcoords [7][6]float64
-var ballFallSpeed [7][6]float64
-
 var mplusNormalFont font.Face
 var tvFace textv2.Face
 
-// messages affichés pendant une partie
-var messages [7]string = [7]string{"Your turn", "Other's turn", "You win!", "You lost.", "Tie.", "...", "..."}
-
-// gm is le gestionnaire de partie (peut être nil si pas de partie en cours)
-var gm *game.GameManager
-
-func changeGameStateBasedOnGameManagerState(gmState game.GameState) {
-	if gmState != game.Running {
-		switch gmState {
-		case game.Win:
-			gameState = win
-		case game.Lose:
-			gameState = lose
-		case game.Tie:
-			gameState = tie
-		}
-	}
+// Config préconfigure l'interface au lancement (voir StartGuiGame). Les
+// réglages de Settings autres que le mode et la difficulté (graine, temps
+// de réflexion) s'appliquent aussi aux parties lancées depuis le menu.
+type Config struct {
+	game.Settings
+	Start   bool    // true : la partie décrite par Settings commence sans passer par le menu
+	AIFirst bool    // true : l'IA joue le premier coup de la partie lancée par Start
+	Scale   float64 // Échelle de la fenêtre (0 : 1)
+	// Remote, s'il n'est pas nil, est une partie en réseau qui commence
+	// sans passer par le menu ; Settings, Start et AIFirst sont ignorés.
+	Remote *netplay.Conn
+	// Watch, s'il n'est pas nil, est la partie d'une autre instance, suivie
+	// en spectateur : les entrées sont ignorées.
+	Watch *spectate.Watcher
+	Feed  *spectate.Feed // Diffusion des parties aux spectateurs (nil : aucune)
+	Name  string         // Nom du joueur local montré aux spectateurs ("" : Player)
 }
 
-func updateBallPos() {
-	for i := 0; i < 6; i++ {
-		for j := 0; j < 7; j++ {
-			if gm == nil {
-				continue
-			}
-			if gm.GetHoleColor(i, j) == game.PlayerTwoColor ||
-				gm.GetHoleColor(i, j) == game.PlayerOneColor {
-				y, x := i, j
-				destY := float64(y) * tileHeight
-				fallY := &ballYcoords[x][y]
-				fallSpeed := &ballFallSpeed[x][y]
-
-				*fallY += *fallSpeed
-				*fallSpeed += gravity
-				if *fallY > destY {
-					*fallY = destY
-					*fallSpeed = 0
-				}
-			}
-		}
-	}
+// newGame crée l'interface décrite par cfg.
+func newGame(cfg Config) *Game {
+	g := &Game{scale: cfg.Scale, m: machine.New(machine.Config{
+		Settings: cfg.Settings,
+		Start:    cfg.Start,
+		AIFirst:  cfg.AIFirst,
+		Remote:   cfg.Remote,
+		Watch:    cfg.Watch,
+		Feed:     cfg.Feed,
+		Name:     cfg.Name,
+	})}
+	g.applyBoardSize()
+	return g
 }
 
-// logique principale du jeu : transitions d'état et démarrage d'une partie
+// logique principale du jeu : les entrées de l'image sont transmises à la
+// machine à états, puis le plateau est redimensionné s'il a changé
 func (g *Game) Update() error {
-	press := inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft)
-	// lire les caractères tapés (gère AZERTY et autres dispositions)
-	inputRunes := ebiten.AppendInputChars(nil)
-
-	if gameState == yourTurn || gameState == opponentTurn {
-		frameCount++
-	}
-
-	if gameState == animation || gameState == opponentAnimation {
-		frameCount = 0
-		updateBallPos()
-	}
-
-	if frameCount == fps*secondsToMakeTurn {
-		os.Exit(1)
-	}
-
-	// mise à jour des positions des billes (désactivée)
-
-	if (gameState == yourTurn || gameState == opponentTurn) && press {
-		mouseX, _ := ebiten.CursorPosition()
-		if gm != nil {
-			prevState := gameState
-			ok, _ := gm.MakePlayerTurn(xcoordToColumn(mouseX))
-			if ok {
-				// show animation for the drop
-				gameState = animation
-				go func(prev GameState) {
-					time.Sleep(1 * time.Second)
-					// si la partie est terminée, mettre à jour l'état final
-					gmState := gm.GetState()
-					if gmState != game.Running {
-						changeGameStateBasedOnGameManagerState(gmState)
-						return
-					}
-					// si l'adversaire est une IA, planifier son coup
-					if gm.IsAI() {
-						// après le coup du joueur, l'IA joue
-						gameState = opponentTurn
-					} else {
-						// jeu local à deux : basculer le tour
-						if prev == yourTurn {
-							gameState = opponentTurn
-						} else {
-							gameState = yourTurn
-						}
-					}
-				}(prevState)
-			}
-		}
+	mouseX, mouseY := ebiten.CursorPosition()
+	screenWidth, _ := screenSize()
+	in := machine.Input{
+		Click: inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft),
+		// clic droit : retrait d'un jeton en PopOut
+		PopClick: inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight),
+		Column:   xcoordToColumn(mouseX),
+		// zone « play again » de la fin de partie
+		Again: mouseX >= 230 && mouseX <= screenWidth-40 && mouseY >= boardBottom()-47,
+		// caractères tapés (gère AZERTY et autres dispositions)
+		Keys: ebiten.AppendInputChars(nil),
+		// flèches : coups précédent et suivant de la revue
+		Back:    inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft),
+		Forward: inpututil.IsKeyJustPressed(ebiten.KeyArrowRight),
 	}
-
-	if gameState == opponentTurn {
-		// jouer automatiquement uniquement si l'adversaire est IA ; sinon attendre l'entrée utilisateur
-		if gm != nil && gm.IsAI() {
-			gameState = animation
-			go func() {
-				col, _ := gm.MakeOpponentTurn(-1)
-				opponentLastCol = col
-				gameState = opponentAnimation
-				time.Sleep(1 * time.Second)
-				if gm != nil {
-					gmState := gm.GetState()
-					if gmState == game.Running {
-						gameState = yourTurn
-					} else {
-						changeGameStateBasedOnGameManagerState(gmState)
-					}
-				} else {
-					gameState = yourTurn
-				}
-			}()
-		}
+	g.m.Step(in)
+	if width, height := g.m.Dims(); width != g.width || height != g.height {
+		g.applyBoardSize()
 	}
+	return nil
+}
 
-	if gameState == menu {
-		for _, r := range inputRunes {
-			switch r {
-			case 'a', 'A':
-				gameState = enterAIdifficulty
-			case 'p', 'P':
-				gm = game.NewGameManager(false, 0)
-				gameState = yourTurn
-			}
-		}
+// applyBoardSize adapte l'image du plateau et la taille de la fenêtre aux
+// dimensions du plateau de la machine.
+func (g *Game) applyBoardSize() {
+	g.width, g.height = g.m.Dims()
+	boardImage = buildBoardImage(g.width, g.height)
+	setWindowSize(g.scale)
+}
+
+// setWindowSize adapte la taille de la fenêtre à celle de l'écran, à
+// l'échelle scale (0 : 1).
+func setWindowSize(scale float64) {
+	width, height := screenSize()
+	if scale <= 0 {
+		scale = 1
 	}
+	ebiten.SetWindowSize(int(float64(width)*scale), int(float64(height)*scale))
+}
 
-	if gameState == enterAIdifficulty {
-		runes := ebiten.AppendInputChars(nil)
-		if len(runes) == 1 {
-			diff := string(runes)
-			difficulty, err := strconv.Atoi(diff)
-			if err == nil {
-				gameState = yourTurn
-				gm = game.NewGameManager(true, difficulty+3)
-			}
+// buildBoardImage assemble l'image d'un plateau de width colonnes et height
+// rangées à partir de celle du plateau standard : les cases du bord sont
+// reprises avec leur bordure et une case intérieure est répétée autant de
+// fois que nécessaire.
+func buildBoardImage(width, height int) *ebiten.Image {
+	if width == game.DefaultWidth && height == game.DefaultHeight {
+		return boardTemplate
+	}
+	bounds := boardTemplate.Bounds()
+	// span renvoie la portion de l'image standard utilisée pour la case
+	// d'indice i parmi n (end étant la taille de l'image standard) et sa
+	// position dans l'image assemblée
+	span := func(i, n, standard, end int) (from, to, at int) {
+		switch i {
+		case 0:
+			return 0, tileOffset + tileHeight, 0
+		case n - 1:
+			from = tileOffset + (standard-1)*tileHeight
+			return from, end, tileOffset + i*tileHeight
 		}
+		return tileOffset + tileHeight, tileOffset + 2*tileHeight, tileOffset + i*tileHeight
 	}
-	// Partie locale à deux sur le même clavier : démarrée par 'P' (gérée ci-dessus via inputRunes)
-
-	if isGameOver() && press {
-		mouseX, mouseY := ebiten.CursorPosition()
-		/*check if mouse is in play again area
-		 */
-		if mouseX >= 230 && mouseX <= 600 && mouseY >= 500 {
-
-			gmState := gm.GetState()
-			gm.ResetGame()
-			var s [7][6]float64
-			ballFallSpeed = s
-			initBallYCoords()
-			if gmState == game.Win {
-				gameState = opponentTurn
-			} else {
-				gameState = yourTurn
-			}
+	img := ebiten.NewImage(boardPixelSize(width, height))
+	for c := 0; c < width; c++ {
+		x0, x1, x := span(c, width, game.DefaultWidth, bounds.Dx())
+		for r := 0; r < height; r++ {
+			y0, y1, y := span(r, height, game.DefaultHeight, bounds.Dy())
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(x), float64(y))
+			img.DrawImage(boardTemplate.SubImage(image.Rect(x0, y0, x1, y1)).(*ebiten.Image), op)
 		}
 	}
-	return nil
+	return img
 }
 
-// isGameOver returns whether the game is over
-func isGameOver() bool {
-	return gameState == tie || gameState == win || gameState == lose
+// boardPixelSize renvoie la taille en pixels de l'image d'un plateau de
+// width colonnes et height rangées.
+func boardPixelSize(width, height int) (int, int) {
+	bounds := boardTemplate.Bounds()
+	return bounds.Dx() + (width-game.DefaultWidth)*tileHeight, bounds.Dy() + (height-game.DefaultHeight)*tileHeight
 }
 
-// dessine l'interface en fonction de l'état de la partie
+// boardBottom renvoie l'ordonnée du bas du plateau affiché.
+func boardBottom() int {
+	return boardY + boardImage.Bounds().Dy()
+}
+
+// screenSize renvoie la taille de l'écran, agrandi si le plateau ne tient
+// pas dans la fenêtre standard de 640x640.
+func screenSize() (int, int) {
+	return max(640, 2*boardX+boardImage.Bounds().Dx()), max(640, boardBottom()+93)
+}
+
+// dessine l'interface en fonction de l'état de la machine
 func (g *Game) Draw(screen *ebiten.Image) {
+	m := g.m
 	screen.DrawImage(backgroundImage, nil)
 	op := &ebiten.DrawImageOptions{}
 
@@ -290,7 +228,7 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	op.GeoM.Reset()
 
 	op.GeoM.Translate(boardX, boardY)
-	if gameState == menu {
+	if m.State() == machine.Menu {
 		screen.DrawImage(boardImage, op)
 		// Utilise text/v2.Draw avec l'adaptateur GoXFace (tvFace). La position est définie
 		// via DrawOptions.DrawImageOptions.GeoM.Translate.
@@ -299,66 +237,112 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		textv2.Draw(screen, "[A] - play against AI", tvFace, o1)
 
 		o2 := &textv2.DrawOptions{}
-		o2.DrawImageOptions.GeoM.Translate(float64(boardX), float64(570))
+		o2.DrawImageOptions.GeoM.Translate(float64(boardX), float64(boardBottom()+23))
 		textv2.Draw(screen, "[P] - play local (2 players)", tvFace, o2)
+
+		o3 := &textv2.DrawOptions{}
+		o3.DrawImageOptions.GeoM.Translate(float64(boardX), float64(boardBottom()+53))
+		textv2.Draw(screen, "[L] - load saved game", tvFace, o3)
+
+		preset := m.Preset()
+		o4 := &textv2.DrawOptions{}
+		o4.DrawImageOptions.GeoM.Translate(float64(boardX), float64(boardY-60))
+		textv2.Draw(screen, "[G] - board "+strconv.Itoa(preset[0])+"x"+strconv.Itoa(preset[1])+", connect "+strconv.Itoa(preset[2]), tvFace, o4)
+
+		o5 := &textv2.DrawOptions{}
+		o5.DrawImageOptions.GeoM.Translate(float64(boardX), float64(boardY-90))
+		textv2.Draw(screen, "[O] - rules: "+m.Variant().String(), tvFace, o5)
 		return
 	}
 
-	if gameState == enterAIdifficulty {
+	if m.State() == machine.EnterAIDifficulty {
 		screen.DrawImage(boardImage, op)
 		o := &textv2.DrawOptions{}
 		o.DrawImageOptions.GeoM.Translate(200, 50)
-		textv2.Draw(screen, "Enter difficulty (1-9)", tvFace, o)
+		textv2.Draw(screen, "Enter difficulty (1-9, 0 = perfect)", tvFace, o)
 		return
 	}
 
-	var msg string = messages[gameState]
-	text.Draw(screen, "W  "+strconv.Itoa(gm.GetWonGames())+":"+strconv.Itoa(gm.GetLostGames())+"  L", mplusNormalFont, boardX, 50, color.White)
-	text.Draw(screen, msg, mplusNormalFont, boardX, 580, color.White)
-	text.Draw(screen, "00:"+strconv.Itoa(secondsToMakeTurn-frameCount/fps), mplusNormalFont, 500, 580, color.White)
-
-	drawOwl(screen)
-	if gameState == opponentAnimation {
-		drawGhost(screen)
+	textY := boardBottom() + 33
+	if names, ok := m.Spectating(); ok {
+		text.Draw(screen, names[0]+" (green) vs "+names[1]+" (red)", mplusNormalFont, boardX, 50, color.White)
+		text.Draw(screen, m.Message(), mplusNormalFont, boardX, textY, color.White)
+		drawBalls(screen, m)
+		screen.DrawImage(boardImage, op)
+		if m.State() == machine.Win || m.State() == machine.Lose {
+			drawWinnerDots(screen, m)
+		}
+		return
+	}
+	won, lost := m.Score()
+	text.Draw(screen, "W  "+strconv.Itoa(won)+":"+strconv.Itoa(lost)+"  L", mplusNormalFont, boardX, 50, color.White)
+	text.Draw(screen, m.Message(), mplusNormalFont, boardX, textY, color.White)
+	if left, ok := m.TimeLeft(); ok {
+		text.Draw(screen, formatTimeLeft(left), mplusNormalFont, boardX+boardImage.Bounds().Dx()-55, textY, color.White)
+	}
+	peer, remote := m.Remote()
+	switch {
+	case m.State() == machine.Review:
+		text.Draw(screen, "[B]ack [N]ext [V] leave", mplusNormalFont, 340, 50, color.White)
+	case remote:
+		text.Draw(screen, "Playing against "+peer, mplusNormalFont, 340, 50, color.White)
+	default:
+		text.Draw(screen, "[U]ndo [R]edo [S]ave [L]oad", mplusNormalFont, 340, 50, color.White)
+	}
+	switch {
+	case m.GameOver():
+		text.Draw(screen, "[V] review", mplusNormalFont, boardX, 75, color.White)
+	case !remote && m.State() != machine.Review:
+		text.Draw(screen, "[H]int [A]nalysis", mplusNormalFont, boardX, 75, color.White)
+	}
+	if m.Variant() == game.PopOut {
+		text.Draw(screen, "Right click: pop out", mplusNormalFont, 340, 75, color.White)
 	}
 
-	drawBalls(screen)
+	drawOwl(screen, m)
+	drawGhost(screen, m)
+
+	drawBalls(screen, m)
 	screen.DrawImage(boardImage, op)
+	drawHint(screen, m)
+	drawAnalysis(screen, m)
+	drawReview(screen, m)
 
-	if isGameOver() {
-		text.Draw(screen, "Click here\nto play again", mplusNormalFont, 250, 580, color.White)
-		if gameState != tie {
-			drawWinnerDots(screen)
+	if m.GameOver() || m.State() == machine.Review {
+		if m.CanReplay() {
+			text.Draw(screen, "Click here\nto play again", mplusNormalFont, 250, textY, color.White)
+		}
+		if m.State() != machine.Tie {
+			drawWinnerDots(screen, m)
 		}
 	}
 }
 
 // dessine toutes les billes à l'écran
-func drawBalls(screen *ebiten.Image) {
-	if gm == nil {
+func drawBalls(screen *ebiten.Image, m *machine.Machine) {
+	if !m.InGame() {
 		return
 	}
-	for i := 0; i < 6; i++ {
-		for j := 0; j < 7; j++ {
-			if gm.GetHoleColor(i, j) == game.PlayerTwoColor {
-				drawBall(j, i, game.PlayerTwoColor, screen)
-			} else if gm.GetHoleColor(i, j) == game.PlayerOneColor {
-				drawBall(j, i, game.PlayerOneColor, screen)
+	width, height := m.Dims()
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			if hole := m.Cell(i, j); hole == game.PlayerOneColor || hole == game.PlayerTwoColor {
+				drawBallAt(j, m.BallY(j, i), hole, screen)
 			}
 		}
 	}
+	if column, player, y, ok := m.Popped(); ok {
+		drawBallAt(column, y, player, screen)
+	}
 }
 
-// dessine les points indiquant les quatre jetons gagnants
-func drawWinnerDots(screen *ebiten.Image) {
-	if gm == nil {
-		return
-	}
-	win, dotsY, dotsX := gm.WhereConnected()
+// dessine les points indiquant les jetons gagnants
+func drawWinnerDots(screen *ebiten.Image, m *machine.Machine) {
+	dotsY, dotsX, win := m.WinningLine()
 	if !win {
 		return
 	}
-	for i := 0; i < 4; i++ {
+	for i := range dotsX {
 		op := &ebiten.DrawImageOptions{}
 		op.GeoM.Translate(boardX+tileOffset, boardY+tileOffset)
 		op.GeoM.Translate(float64(dotsX[i])*tileHeight+25, float64(dotsY[i])*tileHeight+25)
@@ -366,36 +350,131 @@ func drawWinnerDots(screen *ebiten.Image) {
 	}
 }
 
-// dessine l'image fantôme à l'écran
-func drawGhost(screen *ebiten.Image) {
-	if gm == nil || !gm.IsAI() {
+// dessine l'image fantôme à l'écran, au-dessus de la colonne jouée par
+// l'adversaire pendant l'animation de son coup
+func drawGhost(screen *ebiten.Image, m *machine.Machine) {
+	column, ok := m.Ghost()
+	if !ok {
 		return
 	}
 	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(float64(opponentLastCol)*tileHeight+boardX+10, boardY-75)
+	op.GeoM.Translate(float64(column)*tileHeight+boardX+10, boardY-75)
 	screen.DrawImage(ghost, op)
 }
 
+// dessine en surbrillance le coup conseillé par la touche H : la colonne
+// d'un dépôt, ou la case du bas pour un retrait
+func drawHint(screen *ebiten.Image, m *machine.Machine) {
+	move, ok := m.Hint()
+	if !ok {
+		return
+	}
+	_, height := m.Dims()
+	x := float32(boardX + tileOffset + move.Column*tileHeight)
+	y, h := float32(boardY+tileOffset), float32(height*tileHeight)
+	if move.Kind == game.MovePop {
+		y, h = y+float32((height-1)*tileHeight), tileHeight
+	}
+	vector.DrawFilledRect(screen, x, y, tileHeight, h, color.RGBA{0x60, 0x60, 0x00, 0x60}, false)
+}
+
+// dessine au-dessus de chaque colonne la valeur de son coup (touche A) :
+// victoire (W) ou défaite (L) en N demi-coups, nul (=) ou évaluation
+// heuristique ; en PopOut, le meilleur du dépôt et du retrait (noté p)
+func drawAnalysis(screen *ebiten.Image, m *machine.Machine) {
+	evals, ok := m.Analysis()
+	if !ok {
+		if m.Analyzing() {
+			text.Draw(screen, "analyzing...", mplusNormalFont, boardX, 100, color.White)
+		}
+		return
+	}
+	width, _ := m.Dims()
+	best := make([]*game.MoveEval, width)
+	for i := range evals {
+		if e := &evals[i]; best[e.Move.Column] == nil || e.Value() > best[e.Move.Column].Value() {
+			best[e.Move.Column] = e
+		}
+	}
+	for col, e := range best {
+		if e != nil {
+			text.Draw(screen, evalLabel(*e), mplusNormalFont, boardX+tileOffset+col*tileHeight+8, boardY-4, color.White)
+		}
+	}
+}
+
+// dessine l'analyse du coup affiché par la revue (touche V) : la valeur
+// de la position avant et après le coup pour son auteur, puis la gaffe ou
+// la victoire manquée avec le meilleur coup
+func drawReview(screen *ebiten.Image, m *machine.Machine) {
+	r, ok := m.Reviewed()
+	if !ok || r.Ply == 0 {
+		return
+	}
+	if !r.Analyzed {
+		analyzed, plies := m.ReviewProgress()
+		text.Draw(screen, "analyzing "+strconv.Itoa(analyzed)+"/"+strconv.Itoa(plies)+"...", mplusNormalFont, boardX, 75, color.White)
+		return
+	}
+	review := r.Review
+	text.Draw(screen, "before "+review.Best.String()+", after "+review.Played.String(), mplusNormalFont, boardX, 75, color.White)
+	var note string
+	switch {
+	case review.Blunder:
+		note = "Blunder!"
+	case review.MissedWin:
+		note = "Missed win!"
+	default:
+		return
+	}
+	best := game.FormatMoves([]game.Move{review.Best.Move})
+	text.Draw(screen, note+" Best: "+best+" ("+review.Best.String()+")", mplusNormalFont, boardX, 100, color.White)
+}
+
+// evalLabel renvoie la notation courte de la valeur d'un coup dessinée
+// par drawAnalysis.
+func evalLabel(e game.MoveEval) string {
+	var label string
+	switch {
+	case !e.Exact:
+		label = strconv.Itoa(e.Score)
+		if e.Score > 0 {
+			label = "+" + label
+		}
+	case e.Outcome == game.OutcomeDraw:
+		label = "="
+	case e.Outcome == game.OutcomeWin:
+		label = "W" + strconv.Itoa(e.Distance)
+	default:
+		label = "L" + strconv.Itoa(e.Distance)
+	}
+	if e.Move.Kind == game.MovePop {
+		return "p" + label
+	}
+	return label
+}
+
 // dessine le hibou à l'écran
-func drawOwl(screen *ebiten.Image) {
+func drawOwl(screen *ebiten.Image, m *machine.Machine) {
 	op := &ebiten.DrawImageOptions{}
 	mouseX, _ := ebiten.CursorPosition()
 	if mouseX < boardX {
 		mouseX = boardX
 	}
-	if mouseX > boardX+7*tileHeight {
-		mouseX = boardX + 7*tileHeight
+	width, _ := m.Dims()
+	if mouseX > boardX+width*tileHeight {
+		mouseX = boardX + width*tileHeight
 	}
 	owlX := xcoordToColumn(mouseX)*tileHeight + boardX
 	op.GeoM.Translate(float64(owlX), boardY-80)
 	screen.DrawImage(owl, op)
 }
 
-// dessine une bille à l'écran
-func drawBall(x, y int, player string, screen *ebiten.Image) {
+// dessine une bille de la colonne x à l'ordonnée fallY (relative au haut du plateau)
+func drawBallAt(x int, fallY float64, player string, screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(boardX+tileOffset, boardY+tileOffset)
-	op.GeoM.Translate(float64(x)*tileHeight, ballYcoords[x][y])
+	op.GeoM.Translate(float64(x)*tileHeight, fallY)
 
 	if player == game.PlayerTwoColor {
 		screen.DrawImage(redBallImage, op)
@@ -404,10 +483,8 @@ func drawBall(x, y int, player string, screen *ebiten.Image) {
 	}
 }
 
-// updateBallsPos supprimée : la mise à jour des positions est effectuée par updateBallPos
-
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return 640, 640
+	return screenSize()
 }
 
 // xcoordToColumn returns the column correspondidng which contains the x coordinate
@@ -415,11 +492,21 @@ func xcoordToColumn(x int) int {
 	return int(float64(x-tileOffset-boardX) / tileHeight)
 }
 
-// StartGuiGame initializes the game and the gui, this is the entry point for the whole game
-func StartGuiGame() {
-	ebiten.SetWindowSize(640, 640)
+// StartGuiGame initializes the game and the gui, this is the entry point for the whole game.
+// With cfg.Start, the game described by cfg.Settings begins without going through the menu,
+// as does the network game cfg.Remote. With cfg.Watch, the window only shows the watched game.
+// The game state itself lives in a ui/machine.Machine, stepped once per frame.
+func StartGuiGame(cfg Config) {
+	g := newGame(cfg)
 	ebiten.SetWindowTitle("Connect four")
-	if err := ebiten.RunGame(&Game{}); err != nil {
+	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// formatTimeLeft renvoie le temps restant d au format mm:ss, arrondi à la
+// seconde supérieure.
+func formatTimeLeft(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
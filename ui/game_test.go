@@ -4,64 +4,139 @@ import (
 	"testing"
 
 	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/ui/machine"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// play joue les colonnes cols à la souris, en laissant chaque coup
+// s'animer (et l'IA répondre) avant le suivant.
+func play(t *testing.T, m *machine.Machine, cols ...int) {
+	t.Helper()
+	for _, col := range cols {
+		m.Step(machine.Input{Click: true, Column: col})
+		for m.State() == machine.Animation || m.State() == machine.OpponentAnimation || m.Busy() {
+			m.Wait()
+			m.Step(machine.Input{})
+		}
+	}
+}
+
 // TestDraw_MenuAndEnterAI vérifie que Draw peut être appelé en menu et en
 // saisie de difficulté sans provoquer de panic.
 func TestDraw_MenuAndEnterAI(t *testing.T) {
-	// preserve global state
-	oldState := gameState
-	defer func() { gameState = oldState }()
-
 	screen := ebiten.NewImage(640, 640)
-	gameState = menu
-	g := &Game{}
+	g := newGame(Config{})
 	g.Draw(screen)
 
-	gameState = enterAIdifficulty
+	g.m.Step(machine.Input{Keys: []rune{'a'}})
+	if g.m.State() != machine.EnterAIDifficulty {
+		t.Fatalf("expected the difficulty prompt, got %v", g.m.State())
+	}
 	g.Draw(screen)
 }
 
 // TestDraw_GameplayAndWinAndGhost parcourt les chemins de rendu liés au jeu,
 // y compris une victoire verticale et le chemin du fantôme (IA).
 func TestDraw_GameplayAndWinAndGhost(t *testing.T) {
-	// preserve globals
-	oldGm := gm
-	oldState := gameState
-	defer func() { gm = oldGm; gameState = oldState }()
+	// Partie locale à deux : le joueur 1 aligne quatre jetons dans la colonne 0.
+	g := newGame(Config{Start: true})
+	play(t, g.m, 0, 1, 0, 1, 0, 1, 0)
+	if g.m.State() != machine.Win {
+		t.Fatalf("expected state Win but got %v", g.m.State())
+	}
 
-	// Crée une partie locale à deux et joue des coups menant à une victoire
-	// verticale du joueur 1 dans la colonne 0.
-	gm = game.NewGameManager(false, 0)
-	moves := []int{0, 1, 0, 1, 0, 1, 0} // P1:0, P2:1, P1:0, ... -> P1 gets four in column 0
-	for i, col := range moves {
-		if i%2 == 0 {
-			ok, err := gm.MakePlayerTurn(col)
-			if err != nil || !ok {
-				t.Fatalf("MakePlayerTurn failed at move %d col %d: %v", i, col, err)
-			}
-		} else {
-			_, err := gm.MakeOpponentTurn(col)
-			if err != nil {
-				t.Fatalf("MakeOpponentTurn failed at move %d col %d: %v", i, col, err)
-			}
-		}
+	// Dessine l'écran de fin de partie (exerce drawWinnerDots).
+	screen := ebiten.NewImage(640, 640)
+	g.Draw(screen)
+
+	// Teste ensuite le rendu du fantôme pour un adversaire IA.
+	g = newGame(Config{Settings: game.Settings{AI: true, Difficulty: 1}, Start: true})
+	g.m.Step(machine.Input{Click: true, Column: 3})
+	for g.m.State() != machine.OpponentAnimation {
+		g.m.Wait()
+		g.m.Step(machine.Input{})
 	}
+	if _, ok := g.m.Ghost(); !ok {
+		t.Fatalf("expected the ghost during the AI move")
+	}
+	g.Draw(screen)
+}
+
+// TestDraw_LargerBoard vérifie l'image assemblée d'un plateau 9x7 et le
+// rendu d'une victoire en connect-5.
+func TestDraw_LargerBoard(t *testing.T) {
+	oldImage := boardImage
+	defer func() { boardImage = oldImage }()
 
-	if gm.GetState() != game.Win {
-		t.Fatalf("expected game state Win but got %v", gm.GetState())
+	g := newGame(Config{})
+	for range 3 {
+		g.m.Step(machine.Input{Keys: []rune{'g'}})
+	}
+	g.m.Step(machine.Input{Keys: []rune{'p'}})
+	g.applyBoardSize()
+	w, h := boardPixelSize(9, 7)
+	if b := boardImage.Bounds(); b.Dx() != w || b.Dy() != h || w != 471+2*tileHeight || h != 417+tileHeight {
+		t.Fatalf("unexpected board image size %v (expected %dx%d)", b, w, h)
 	}
 
-	// Dessine l'écran de fin de partie (exerce drawWinnerDots).
+	play(t, g.m, 4, 4, 5, 5, 6, 6, 7, 7, 8)
+	if g.m.State() != machine.Win {
+		t.Fatalf("expected a connect-5 win, got %v", g.m.State())
+	}
+	screenWidth, screenHeight := screenSize()
+	g.Draw(ebiten.NewImage(screenWidth, screenHeight))
+}
+
+// TestDraw_HintAndAnalysis dessine le coup conseillé et la valeur des
+// coups, dont un retrait en PopOut.
+func TestDraw_HintAndAnalysis(t *testing.T) {
+	g := newGame(Config{})
+	g.m.Step(machine.Input{Keys: []rune{'o', 'p'}})
+	play(t, g.m, 0, 1, 0)
+	g.m.Step(machine.Input{Keys: []rune{'h', 'a'}})
+	g.m.Wait()
+	if _, ok := g.m.Hint(); !ok {
+		t.Fatalf("expected a hint")
+	}
+	if _, ok := g.m.Analysis(); !ok {
+		t.Fatalf("expected the analysis")
+	}
+	g.Draw(ebiten.NewImage(640, 640))
+
+	for _, c := range []struct {
+		e    game.MoveEval
+		want string
+	}{
+		{game.MoveEval{Exact: true, Outcome: game.OutcomeWin, Distance: 3}, "W3"},
+		{game.MoveEval{Exact: true, Outcome: game.OutcomeLoss, Distance: 2, Move: game.Move{Kind: game.MovePop}}, "pL2"},
+		{game.MoveEval{Exact: true, Outcome: game.OutcomeDraw}, "="},
+		{game.MoveEval{Score: 7}, "+7"},
+		{game.MoveEval{Score: -7}, "-7"},
+	} {
+		if got := evalLabel(c.e); got != c.want {
+			t.Fatalf("evalLabel(%+v) = %q, expected %q", c.e, got, c.want)
+		}
+	}
+}
+
+// TestDraw_Review dessine la revue d'une partie terminée, pendant puis
+// après l'analyse de ses coups.
+func TestDraw_Review(t *testing.T) {
+	g := newGame(Config{})
+	g.m.Step(machine.Input{Keys: []rune{'g', 'p'}})
+	play(t, g.m, 0, 1, 0, 1, 0, 2, 0)
 	screen := ebiten.NewImage(640, 640)
-	gameState = win
-	g := &Game{}
 	g.Draw(screen)
 
-	// Teste ensuite le rendu du fantôme pour un adversaire IA.
-	gm = game.NewGameManager(true, 1)
-	opponentLastCol = 3
-	gameState = opponentAnimation
+	g.m.Step(machine.Input{Keys: []rune{'v'}})
+	if g.m.State() != machine.Review {
+		t.Fatalf("expected the review, got %v", g.m.State())
+	}
+	g.Draw(screen)
+	g.m.Wait()
+	g.m.Step(machine.Input{Back: true})
+	if r, ok := g.m.Reviewed(); !ok || !r.Review.Blunder {
+		t.Fatalf("expected the sixth move to be shown as a blunder, got %+v", r)
+	}
 	g.Draw(screen)
 }
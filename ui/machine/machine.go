@@ -0,0 +1,1319 @@
+// Package machine est la machine à états de l'interface graphique, sans
+// dépendance à Ebiten : le menu, les tours des joueurs, les animations des
+// billes et la fin de partie avancent d'une image à chaque appel de
+// Machine.Step, selon les entrées fournies. Le temps est celui d'une
+// horloge virtuelle qui avance de Tick par étape, ce qui permet de dérouler
+// une partie entière dans un test, sans fenêtre.
+//
+// Les coups de l'IA, du joueur distant et de la partie suivie en
+// spectateur sont attendus en arrière-plan ; pendant ce temps, la machine
+// ne lit pas la partie et n'expose qu'une copie de son état, prise à la
+// fin de chaque étape.
+package machine
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/netplay"
+	"github.com/AbassHammed/c4/spectate"
+)
+
+// State est l'état de l'interface.
+type State int
+
+const (
+	YourTurn          State = iota // Au joueur local (au premier joueur d'une partie locale à deux)
+	OpponentTurn                   // À l'adversaire (au second joueur d'une partie locale à deux)
+	Win                            // Partie gagnée par l'auteur du dernier coup
+	Lose                           // Partie perdue par l'auteur du dernier coup
+	Tie                            // Match nul
+	Animation                      // Chute du jeton joué, ou attente du coup de l'adversaire
+	OpponentAnimation              // Chute du jeton joué par l'adversaire
+	Menu                           // Menu de lancement
+	EnterAIDifficulty              // Saisie du niveau de l'IA
+	Review                         // Revue des coups de la partie terminée
+)
+
+const (
+	FPS              = 60                // Étapes par seconde
+	Tick             = time.Second / FPS // Durée virtuelle d'une étape
+	TileSize         = 65                // Taille d'une case, en pixels
+	gravity          = 0.5               // Accélération des billes, en pixels par étape
+	animationFrames  = FPS               // Durée de l'animation d'un coup, en étapes
+	reconnectTimeout = 2 * time.Minute   // Attente maximale du retour du joueur distant après une coupure
+	saveFile         = "c4save.json"     // Fichier de sauvegarde utilisé par les touches S et L
+
+	analysisDepth     = 8               // Profondeur de la recherche de l'analyse après chaque coup
+	analysisSolveTime = 2 * time.Second // Temps laissé au solveur exact par l'analyse
+)
+
+// BoardPresets sont les dimensions proposées dans le menu (largeur,
+// hauteur, jetons à aligner).
+var BoardPresets = [][3]int{{7, 6, 4}, {8, 7, 4}, {9, 7, 4}, {9, 7, 5}}
+
+// messages affichés pendant une partie, selon l'état
+var messages = [...]string{"Your turn", "Other's turn", "You win!", "You lost.", "Tie.", "...", "..."}
+
+// Config configure la machine au lancement. Les réglages de Settings autres
+// que le mode et la difficulté (graine, temps de réflexion, bibliothèque
+// d'ouvertures, cadence) s'appliquent aussi aux parties lancées depuis le
+// menu. La cadence ne s'applique pas aux parties en réseau.
+type Config struct {
+	game.Settings
+	Start   bool // true : la partie décrite par Settings commence sans passer par le menu
+	AIFirst bool // true : l'IA joue le premier coup de la partie lancée par Start
+	// Remote, s'il n'est pas nil, est une partie en réseau qui commence
+	// sans passer par le menu ; Settings, Start et AIFirst sont ignorés.
+	Remote *netplay.Conn
+	// Watch, s'il n'est pas nil, est la partie d'une autre instance, suivie
+	// en spectateur : les entrées sont ignorées.
+	Watch *spectate.Watcher
+	Feed  *spectate.Feed // Diffusion des parties aux spectateurs (nil : aucune)
+	Name  string         // Nom du joueur local montré aux spectateurs ("" : Player)
+}
+
+// Input regroupe les entrées d'une étape.
+type Input struct {
+	Click    bool   // Clic gauche relâché : dépôt d'un jeton, ou nouvelle partie dans la zone Again
+	PopClick bool   // Clic droit relâché : retrait d'un jeton en PopOut
+	Column   int    // Colonne sous le curseur (éventuellement hors du plateau)
+	Again    bool   // Le curseur est sur la zone « play again » de la fin de partie
+	Keys     []rune // Caractères tapés
+	Back     bool   // Flèche gauche : coup précédent de la revue
+	Forward  bool   // Flèche droite : coup suivant de la revue
+}
+
+// poppedBall est le jeton retiré par le dernier coup PopOut, qui tombe
+// sous le plateau pendant l'animation.
+type poppedBall struct {
+	column int
+	player string
+	y      float64
+	speed  float64
+}
+
+// Machine est l'état de l'interface. Ses méthodes doivent être appelées
+// depuis une seule goroutine.
+type Machine struct {
+	cfg     Config
+	gm      *game.GameManager // Partie en cours (nil au menu)
+	remote  *netplay.Conn     // Partie en réseau en cours (nil pour une partie locale)
+	watcher *spectate.Watcher // Partie suivie en spectateur (nil sinon)
+
+	state State
+	// état au premier coup de la partie en cours (YourTurn ou
+	// OpponentTurn), pour retrouver à qui c'est le tour après une annulation
+	firstTurn State
+	frame     int // Étapes écoulées depuis la création
+
+	clock  *game.Clock        // Pendule de la partie en cours (nil sans limite de temps)
+	cancel context.CancelFunc // Interrompt la réflexion de l'IA en cours (nil sinon)
+
+	boardPreset int          // Indice dans BoardPresets des dimensions choisies pour la prochaine partie
+	variant     game.Variant // Règles choisies dans le menu pour la prochaine partie
+
+	// positions et vitesses de chute des billes, indexées par [colonne][rangée],
+	// en pixels depuis le haut du plateau
+	ballY, ballSpeed [][]float64
+	popped           *poppedBall // Jeton en cours de chute sous le plateau (nil s'il n'y en a pas)
+	opponentLastCol  int         // Colonne choisie par l'adversaire lors du dernier coup
+
+	wait int    // Étapes restantes de l'animation en cours
+	then func() // Suite de l'animation en cours (nil sans animation)
+
+	running bool        // Un travail de fond est en cours : la partie ne doit pas être lue
+	done    chan func() // Suite d'un travail de fond terminé
+
+	view       view // Copie de l'état de la partie, lue pendant les travaux de fond
+	watchEnded bool // Fin de la diffusion suivie en spectateur
+
+	// analyse des coups du joueur au trait (touches H et A), calculée en
+	// arrière-plan indépendamment des autres travaux de fond
+	analyzer     *game.Analyzer     // Créé à la première analyse
+	analysis     analysis           // Dernière analyse terminée
+	analyzing    bool               // Une analyse est en cours
+	analyzingKey uint64             // Position de l'analyse en cours
+	stopAnalysis context.CancelFunc // Interrompt l'analyse en cours
+	analysisDone chan analysis      // Résultat de l'analyse en cours
+	showAnalysis bool               // Affichage de la valeur de chaque coup (touche A)
+	hint         bool               // Affichage du coup conseillé dans la position hintKey (touche H)
+	hintKey      uint64
+
+	// revue de la partie terminée (touche V) : la partie est mise de côté
+	// et m.gm est une copie rejouée jusqu'au coup affiché ; les coups sont
+	// analysés en arrière-plan sur une autre copie
+	reviewed    *game.GameManager    // Partie passée en revue (nil hors de la revue)
+	reviewFrom  State                // État de fin de partie, rétabli en quittant la revue
+	reviewPly   int                  // Coups joués dans la position affichée
+	reviews     []game.MoveReview    // Coups analysés, dans l'ordre de la partie
+	reviewMoves string               // Coups de la partie analysée, en notation compacte
+	stopReview  context.CancelFunc   // Interrompt l'analyse de la partie
+	reviewDone  chan game.MoveReview // Coups analysés, fermé à la fin de l'analyse (nil ensuite)
+}
+
+// ReviewedMove est le coup affiché pendant la revue d'une partie.
+type ReviewedMove struct {
+	Ply      int             // Coups joués dans la position affichée (0 : plateau vide)
+	Plies    int             // Coups de la partie
+	Move     game.Move       // Dernier coup joué, si Ply est positif
+	Review   game.MoveReview // Analyse du dernier coup, si Analyzed
+	Analyzed bool            // L'analyse du dernier coup est terminée
+}
+
+// analysis est la valeur des coups du joueur au trait dans une position.
+type analysis struct {
+	key   uint64          // Clé de la position (GameManager.PositionKey)
+	evals []game.MoveEval // Valeur de chaque coup (voir game.Analyzer)
+	done  bool            // L'analyse est allée à son terme
+}
+
+// view est la copie de l'état de la partie prise à la fin de chaque étape.
+type view struct {
+	width, height int
+	variant       game.Variant
+	cells         [][]string // Contenu des cases, indexé par [rangée][colonne]
+	won, lost     int
+	line          bool
+	rows, cols    []int     // Jetons alignés
+	winner        string    // Symbole du gagnant ("" sans gagnant)
+	timedOut      bool      // Partie terminée au temps
+	ai            bool      // Adversaire IA
+	peer          string    // Nom du joueur distant
+	peerToMove    bool      // Au joueur distant de jouer
+	names         [2]string // Joueurs de la partie suivie en spectateur
+	key           uint64    // Clé de la position (GameManager.PositionKey)
+}
+
+// New crée la machine décrite par cfg : au menu, ou dans la partie que
+// cfg fait commencer (partie en réseau, en spectateur ou lancée par Start).
+func New(cfg Config) *Machine {
+	m := &Machine{cfg: cfg, state: Menu, firstTurn: YourTurn, done: make(chan func(), 1), analysisDone: make(chan analysis, 1)}
+	m.resetBalls()
+	switch {
+	case cfg.Watch != nil:
+		m.startWatching(cfg.Watch)
+	case cfg.Remote != nil:
+		m.startRemote(cfg.Remote)
+	case cfg.Start:
+		m.startGame(cfg.NewGameManager(m.boardSizeOption(), game.WithVariant(m.variant)), cfg.AIFirst)
+	}
+	m.refresh()
+	return m
+}
+
+// Now renvoie le temps écoulé sur l'horloge virtuelle. Il est calculé à
+// partir du nombre d'étapes, Tick n'étant pas un nombre entier de
+// nanosecondes : FPS étapes font exactement une seconde.
+func (m *Machine) Now() time.Duration {
+	return time.Duration(m.frame) * time.Second / FPS
+}
+
+// Step fait avancer l'interface d'une image avec les entrées in.
+func (m *Machine) Step(in Input) {
+	m.frame++
+	m.collect()
+	m.collectAnalysis()
+	m.collectReview()
+	defer m.refresh()
+
+	// en spectateur, les entrées sont ignorées : seules les billes bougent
+	if m.watcher != nil {
+		m.animate()
+		return
+	}
+	if m.cfg.Feed != nil && m.gm != nil && !m.running {
+		m.cfg.Feed.Publish(m.played(), m.PlayerNames())
+	}
+
+	m.tickClock()
+	if m.animating() {
+		m.animate()
+	}
+
+	// annuler / rejouer : pendant le tour du joueur (ou d'un joueur local) et en fin de partie,
+	// sauf en réseau où la partie est partagée avec le joueur distant
+	if m.gm != nil && m.remote == nil && (m.state == YourTurn || (m.state == OpponentTurn && !m.gm.IsAI()) || m.GameOver()) {
+		for _, r := range in.Keys {
+			switch r {
+			case 'u', 'U':
+				if m.gm.Undo() {
+					m.syncWithHistory()
+				}
+			case 'r', 'R':
+				if m.gm.Redo() {
+					m.syncWithHistory()
+				}
+			case 's', 'S':
+				m.saveGame()
+			case 'l', 'L':
+				m.loadGame()
+			case 'h', 'H':
+				m.hint, m.hintKey = true, m.gm.PositionKey()
+			case 'a', 'A':
+				m.showAnalysis = !m.showAnalysis
+			}
+		}
+	}
+
+	// revue de la partie terminée, y compris en réseau
+	if m.state == Review {
+		m.stepReview(in)
+	} else if m.GameOver() && !m.running && slices.ContainsFunc(in.Keys, func(r rune) bool { return r == 'v' || r == 'V' }) {
+		m.startReview()
+	}
+
+	// en réseau, seuls les coups du joueur local se jouent à la souris
+	if (m.state == YourTurn || (m.state == OpponentTurn && m.remote == nil)) && (in.Click || in.PopClick) && m.gm != nil {
+		m.playColumn(in.Click, in.Column)
+	}
+
+	if m.state == OpponentTurn {
+		// jouer automatiquement uniquement si l'adversaire est IA ; sinon attendre l'entrée utilisateur
+		if m.gm != nil && m.remote != nil {
+			m.state = Animation
+			m.start(m.receiveRemote)
+		} else if m.gm != nil && m.gm.IsAI() {
+			m.state = Animation
+			m.startOpponentMove()
+		}
+	}
+
+	if m.state == Menu {
+		for _, r := range in.Keys {
+			switch r {
+			case 'a', 'A':
+				m.state = EnterAIDifficulty
+			case 'p', 'P':
+				settings := m.cfg.Settings
+				settings.AI = false
+				m.startGame(settings.NewGameManager(m.boardSizeOption(), game.WithVariant(m.variant)), false)
+			case 'g', 'G':
+				m.boardPreset = (m.boardPreset + 1) % len(BoardPresets)
+				m.resetBalls()
+			case 'o', 'O':
+				if m.variant == game.PopOut {
+					m.variant = game.Classic
+				} else {
+					m.variant = game.PopOut
+				}
+			case 'l', 'L':
+				m.loadGame()
+			}
+		}
+	}
+
+	if m.state == EnterAIDifficulty && len(in.Keys) == 1 {
+		if difficulty, err := strconv.Atoi(string(in.Keys)); err == nil {
+			// 0 : l'IA joue parfaitement (sur le plateau standard)
+			settings := m.cfg.Settings
+			settings.AI, settings.Difficulty = true, difficulty
+			m.startGame(settings.NewGameManager(m.boardSizeOption(), game.WithVariant(m.variant)), false)
+		}
+	}
+
+	if m.GameOver() && in.Click && in.Again && m.remote == nil {
+		gmState := m.gm.GetState()
+		m.gm.ResetGame()
+		m.resetBalls()
+		if m.clock != nil {
+			m.clock.Reset()
+		}
+		if gmState == game.Win {
+			m.state = OpponentTurn
+		} else {
+			m.state = YourTurn
+		}
+		m.firstTurn = m.state
+	}
+	m.updateAnalysis()
+}
+
+// playColumn joue le coup du joueur au trait dans la colonne : un dépôt si
+// drop est vrai, un retrait sinon.
+func (m *Machine) playColumn(drop bool, column int) {
+	prev := m.state
+	var ok bool
+	if m.remote != nil {
+		ok = m.playRemote(drop, column)
+	} else if drop {
+		ok, _ = m.gm.MakePlayerTurn(column)
+	} else if m.gm.Variant() == game.PopOut {
+		if ok, _ = m.gm.MakePlayerPop(column); ok {
+			m.startPopAnimation(column)
+		}
+	}
+	if !ok {
+		return
+	}
+	if m.clock != nil {
+		m.clock.Press()
+	}
+	m.animateThen(Animation, func() {
+		// si la partie est terminée, mettre à jour l'état final
+		if m.setFinalState() {
+			return
+		}
+		switch {
+		case m.gm.IsAI() || m.remote != nil:
+			// après le coup du joueur, l'adversaire joue
+			m.state = OpponentTurn
+		case prev == YourTurn:
+			// jeu local à deux : basculer le tour
+			m.state = OpponentTurn
+		default:
+			m.state = YourTurn
+		}
+	})
+}
+
+// startOpponentMove lance la réflexion de l'IA en arrière-plan, dans un
+// contexte que timeOut annule si l'IA perd au temps.
+func (m *Machine) startOpponentMove() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.start(func() func() {
+		return m.opponentMove(ctx)
+	})
+}
+
+// opponentMove joue le coup de l'IA (travail de fond). Si l'IA a perdu au
+// temps pendant sa réflexion, son coup n'est pas joué et la fin de partie
+// est affichée. Si le moteur de l'IA échoue, la partie est abandonnée et
+// l'interface revient au menu, plutôt que de laisser le joueur jouer à la
+// place de l'IA.
+func (m *Machine) opponentMove(ctx context.Context) func() {
+	col, err := m.gm.MakeOpponentTurnContext(ctx, -1)
+	if err != nil {
+		if errors.Is(err, game.ErrPositionChanged) || m.gm.GetState() != game.Running {
+			return func() {
+				m.stopSearch()
+				m.setFinalState()
+			}
+		}
+		log.Printf("ai: %v", err)
+		return func() {
+			m.stopSearch()
+			m.leaveGame()
+		}
+	}
+	history := m.gm.History()
+	pop := history[len(history)-1].Kind == game.MovePop
+	return func() {
+		m.stopSearch()
+		if m.clock != nil {
+			m.clock.Press()
+		}
+		m.opponentLastCol = col
+		if pop {
+			m.startPopAnimation(col)
+		}
+		m.animateThen(OpponentAnimation, m.afterOpponent)
+	}
+}
+
+// stopSearch libère le contexte de la réflexion de l'IA terminée.
+func (m *Machine) stopSearch() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+// tickClock décompte une étape au joueur au trait, réflexion de l'IA
+// comprise ; la pendule est arrêtée pendant les animations (voir
+// animateThen). À l'échéance, le joueur au trait perd au temps.
+func (m *Machine) tickClock() {
+	if m.clock == nil || m.gm == nil || m.GameOver() {
+		return
+	}
+	if m.state != YourTurn && m.state != OpponentTurn && !m.running {
+		return
+	}
+	if m.clock.Advance(m.Now() - time.Duration(m.frame-1)*time.Second/FPS) {
+		m.timeOut()
+	}
+}
+
+// timeOut fait perdre au temps le joueur au trait : l'IA si elle réfléchit
+// encore (sa réflexion est interrompue et la fin de partie attend son
+// retour), sinon le joueur local, ou celui des deux joueurs d'une partie
+// locale dont c'est le tour.
+func (m *Machine) timeOut() {
+	opponent := m.running || (m.state == OpponentTurn && m.gm.IsAI())
+	m.gm.TimeOut(opponent)
+	if m.running {
+		m.cancel()
+		return
+	}
+	m.setFinalState()
+}
+
+// afterOpponent rend la main au joueur local après l'animation du coup de
+// l'adversaire, sauf si la partie est terminée.
+func (m *Machine) afterOpponent() {
+	if !m.setFinalState() {
+		m.state = YourTurn
+	}
+}
+
+// setFinalState passe à l'état de fin de partie si la partie est terminée
+// et indique si c'est le cas.
+func (m *Machine) setFinalState() bool {
+	switch m.gm.GetState() {
+	case game.Win:
+		m.state = Win
+	case game.Lose:
+		m.state = Lose
+	case game.Tie:
+		m.state = Tie
+	default:
+		return false
+	}
+	return true
+}
+
+// playRemote joue en réseau le coup du joueur local dans la colonne : un
+// dépôt si drop est vrai, un retrait sinon. Un coup joué pendant une
+// coupure est transmis à la reconnexion, que receiveRemote déclenche.
+func (m *Machine) playRemote(drop bool, column int) bool {
+	kind := game.MoveDrop
+	if !drop {
+		if m.gm.Variant() != game.PopOut {
+			return false
+		}
+		kind = game.MovePop
+	}
+	err := m.remote.Play(kind, column)
+	if err != nil && !errors.Is(err, netplay.ErrDisconnected) {
+		return false
+	}
+	if kind == game.MovePop {
+		m.startPopAnimation(column)
+	}
+	return true
+}
+
+// receiveRemote attend le coup du joueur distant (travail de fond) et
+// l'anime comme celui de l'IA. Une coupure est suivie d'une reconnexion,
+// qui peut avoir transmis le coup ; une autre erreur ramène au menu.
+func (m *Machine) receiveRemote() func() {
+	move, err := m.remote.Receive(context.Background())
+	for errors.Is(err, netplay.ErrDisconnected) {
+		log.Printf("network: %v, reconnecting", err)
+		ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+		err = m.remote.Reconnect(ctx)
+		cancel()
+		if err == nil && m.gm.GetState() == game.Running && !m.remote.MyTurn() {
+			move, err = m.remote.Receive(context.Background())
+		} else if err == nil {
+			// le coup a été transmis à la reconnexion
+			return m.syncWithHistory
+		}
+	}
+	if err != nil {
+		log.Printf("network: %v", err)
+		return m.leaveRemote
+	}
+	return func() {
+		m.opponentLastCol = move.Column
+		if move.Kind == game.MovePop {
+			m.startPopAnimation(move.Column)
+		}
+		m.animateThen(OpponentAnimation, m.afterOpponent)
+	}
+}
+
+// leaveRemote met fin à la partie en réseau et revient au menu.
+func (m *Machine) leaveRemote() {
+	m.remote.Close()
+	m.remote = nil
+	m.leaveGame()
+}
+
+// leaveGame abandonne la partie en cours et revient au menu.
+func (m *Machine) leaveGame() {
+	m.gm = nil
+	m.clock = nil
+	m.state = Menu
+	m.resetBalls()
+}
+
+// startRemote commence la partie en réseau c, éventuellement déjà
+// entamée : les billes sont posées et le tour est celui de la partie.
+func (m *Machine) startRemote(c *netplay.Conn) {
+	m.remote = c
+	m.gm = c.Game()
+	// firstTurn est déduit du joueur au trait et du nombre de coups joués
+	m.firstTurn = YourTurn
+	if c.MyTurn() != (len(m.gm.History())%2 == 0) {
+		m.firstTurn = OpponentTurn
+	}
+	m.syncWithHistory()
+}
+
+// PlayerNames renvoie les noms du premier et du second joueur de la
+// partie en cours, tels qu'ils sont montrés aux spectateurs.
+func (m *Machine) PlayerNames() [2]string {
+	me := m.cfg.Name
+	if me == "" {
+		me = "Player"
+	}
+	var other string
+	switch {
+	case m.remote != nil:
+		other = m.remote.PeerName()
+	case m.played().IsAI():
+		other = "AI"
+	default:
+		return [2]string{"Player 1", "Player 2"}
+	}
+	if m.firstTurn == OpponentTurn {
+		return [2]string{other, me}
+	}
+	return [2]string{me, other}
+}
+
+// startWatching suit en spectateur la partie diffusée à w. YourTurn et
+// OpponentTurn désignent alors le tour du premier et du second joueur.
+func (m *Machine) startWatching(w *spectate.Watcher) {
+	m.watcher = w
+	m.gm = w.Game()
+	m.firstTurn = YourTurn
+	m.syncWithHistory()
+	m.start(m.watchNext)
+}
+
+// watchNext attend le prochain changement de la partie suivie (travail de
+// fond), puis l'anime avant d'attendre le suivant, jusqu'à la fin de sa
+// diffusion.
+func (m *Machine) watchNext() func() {
+	reset, err := m.watcher.Next(context.Background())
+	if err != nil {
+		return func() {
+			log.Printf("spectate: %v", err)
+			m.watchEnded = true
+		}
+	}
+	if reset {
+		return func() {
+			m.gm = m.watcher.Game()
+			m.syncWithHistory()
+			m.start(m.watchNext)
+		}
+	}
+	return func() {
+		history := m.gm.History()
+		if last := history[len(history)-1]; last.Kind == game.MovePop {
+			m.startPopAnimation(last.Column)
+		}
+		m.animateThen(Animation, func() {
+			m.syncWithHistory()
+			m.start(m.watchNext)
+		})
+	}
+}
+
+// startGame commence la partie g, le premier joueur ayant le trait sauf si
+// aiFirst est vrai : l'IA joue alors le premier coup.
+func (m *Machine) startGame(g *game.GameManager, aiFirst bool) {
+	m.gm = g
+	m.state = YourTurn
+	if aiFirst && g.IsAI() {
+		m.state = OpponentTurn
+	}
+	m.firstTurn = m.state
+	m.startClock()
+	m.resetBalls()
+}
+
+// startClock met en marche la pendule de la partie en cours, à la cadence
+// de la configuration. Les parties en réseau et celles suivies en
+// spectateur n'ont pas de pendule.
+func (m *Machine) startClock() {
+	m.clock = nil
+	if tc := m.cfg.Clock; !tc.Unlimited() && m.remote == nil && m.watcher == nil {
+		m.clock = game.NewClock(tc)
+	}
+}
+
+// boardSizeOption renvoie l'option de création d'une partie aux dimensions
+// choisies dans le menu.
+func (m *Machine) boardSizeOption() game.Option {
+	preset := BoardPresets[m.boardPreset]
+	return game.WithBoardSize(preset[0], preset[1], preset[2])
+}
+
+// syncWithHistory met l'interface en accord avec la partie après une
+// annulation ou un coup rejoué : les billes sont posées directement à leur
+// place et le tour est déduit du nombre de coups joués.
+func (m *Machine) syncWithHistory() {
+	if m.clock != nil {
+		m.clock.SetToMove(len(m.gm.History()) % 2)
+	}
+	m.placeBalls()
+	if m.setFinalState() {
+		return
+	}
+	other := OpponentTurn
+	if m.firstTurn == OpponentTurn {
+		other = YourTurn
+	}
+	if len(m.gm.History())%2 == 0 {
+		m.state = m.firstTurn
+	} else {
+		m.state = other
+	}
+}
+
+// placeBalls pose directement les billes de la partie en cours à leur
+// place, sans animation.
+func (m *Machine) placeBalls() {
+	m.resetBalls()
+	width, height := m.Dims()
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			if hole := m.gm.GetHoleColor(i, j); hole == game.PlayerOneColor || hole == game.PlayerTwoColor {
+				m.ballY[j][i] = float64(i) * TileSize
+			}
+		}
+	}
+}
+
+// startReview passe en revue la partie terminée, depuis sa dernière
+// position, et lance l'analyse de ses coups si elle n'a pas déjà été
+// faite.
+func (m *Machine) startReview() {
+	m.reviewed, m.reviewFrom = m.gm, m.state
+	m.state = Review
+	history := m.reviewed.History()
+	if moves := game.FormatMoves(history); moves != m.reviewMoves {
+		m.startReviewAnalysis(moves, len(history))
+	}
+	m.showPly(len(history))
+}
+
+// startReviewAnalysis analyse en arrière-plan les plies coups moves de la
+// partie passée en revue, sur une copie de la partie.
+func (m *Machine) startReviewAnalysis(moves string, plies int) {
+	if m.stopReview != nil {
+		m.stopReview()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	// le canal peut recevoir tous les coups : une analyse interrompue ne
+	// reste jamais bloquée
+	done := make(chan game.MoveReview, plies)
+	m.reviews, m.reviewMoves, m.stopReview, m.reviewDone = nil, moves, cancel, done
+	g := m.replay(plies)
+	go func() {
+		defer close(done)
+		defer cancel()
+		a := game.NewAnalyzer(analysisDepth)
+		a.SolveTime = analysisSolveTime
+		_, err := a.Review(ctx, g, func(r game.MoveReview) {
+			done <- r
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("review: %v", err)
+		}
+	}()
+}
+
+// collectReview conserve les coups analysés depuis l'étape précédente.
+func (m *Machine) collectReview() {
+	for m.reviewDone != nil {
+		select {
+		case r, ok := <-m.reviewDone:
+			if !ok {
+				m.reviewDone = nil
+				return
+			}
+			m.reviews = append(m.reviews, r)
+		default:
+			return
+		}
+	}
+}
+
+// stepReview applique les entrées de la revue : N et B (ou les flèches)
+// affichent le coup suivant et le précédent, V et Q quittent la revue.
+func (m *Machine) stepReview(in Input) {
+	ply := m.reviewPly
+	if in.Forward {
+		ply++
+	}
+	if in.Back {
+		ply--
+	}
+	for _, r := range in.Keys {
+		switch r {
+		case 'n', 'N':
+			ply++
+		case 'b', 'B':
+			ply--
+		case 'v', 'V', 'q', 'Q':
+			m.leaveReview()
+			return
+		}
+	}
+	if ply = max(0, min(ply, len(m.reviewed.History()))); ply != m.reviewPly {
+		m.showPly(ply)
+	}
+}
+
+// showPly affiche la position de la partie passée en revue après ses ply
+// premiers coups.
+func (m *Machine) showPly(ply int) {
+	m.reviewPly = ply
+	m.gm = m.replay(ply)
+	m.placeBalls()
+}
+
+// replay renvoie une copie de la partie passée en revue, sans IA, dans
+// laquelle ses ply premiers coups sont joués.
+func (m *Machine) replay(ply int) *game.GameManager {
+	width, height, connectN := m.reviewed.BoardSize()
+	g := game.NewGameManager(false, 0, game.WithBoardSize(width, height, connectN), game.WithVariant(m.reviewed.Variant()))
+	if err := g.PlayMoves(game.FormatMoves(m.reviewed.History()[:ply])); err != nil {
+		log.Printf("review: %v", err)
+	}
+	return g
+}
+
+// leaveReview revient à la fin de la partie passée en revue. Une analyse
+// inachevée est interrompue : elle reprendra à la prochaine revue.
+func (m *Machine) leaveReview() {
+	if m.reviewDone != nil {
+		m.stopReview()
+		m.reviewDone, m.reviewMoves = nil, ""
+	}
+	m.gm, m.reviewed = m.reviewed, nil
+	m.state = m.reviewFrom
+	m.placeBalls()
+}
+
+// played renvoie la partie jouée : pendant la revue, celle qui est passée
+// en revue plutôt que sa copie affichée.
+func (m *Machine) played() *game.GameManager {
+	if m.reviewed != nil {
+		return m.reviewed
+	}
+	return m.gm
+}
+
+// saveGame écrit la partie en cours dans saveFile.
+func (m *Machine) saveGame() {
+	f, err := os.Create(saveFile)
+	if err != nil {
+		log.Printf("save: %v", err)
+		return
+	}
+	if err := m.gm.Save(f); err != nil {
+		log.Printf("save: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Printf("save: %v", err)
+	}
+}
+
+// loadGame reprend la partie sauvegardée dans saveFile, avec la graine et
+// le nombre de goroutines de la recherche de son IA (voir game.Load), et
+// la bibliothèque d'ouvertures de la configuration.
+func (m *Machine) loadGame() {
+	f, err := os.Open(saveFile)
+	if err != nil {
+		log.Printf("load: %v", err)
+		return
+	}
+	defer f.Close()
+	var opts []game.Option
+	if m.cfg.Book != nil {
+		opts = append(opts, game.WithOpeningBook(m.cfg.Book))
+	}
+	loaded, err := game.Load(f, opts...)
+	if err != nil {
+		log.Printf("load: %v", err)
+		return
+	}
+	m.gm = loaded
+	m.firstTurn = YourTurn
+	if history := m.gm.History(); len(history) > 0 && history[0].Opponent {
+		m.firstTurn = OpponentTurn
+	}
+	m.startClock()
+	m.syncWithHistory()
+}
+
+// start lance le travail de fond job hors de la goroutine de l'interface.
+// Jusqu'à ce qu'il se termine, la partie n'est plus lue (les accesseurs
+// renvoient l'état copié juste avant son lancement) ; la suite qu'il
+// renvoie est appliquée par l'étape suivant sa fin (ou par Wait).
+func (m *Machine) start(job func() func()) {
+	m.refresh()
+	m.running = true
+	go func() {
+		m.done <- job()
+	}()
+}
+
+// collect applique la suite du travail de fond s'il est terminé.
+func (m *Machine) collect() {
+	if !m.running {
+		return
+	}
+	select {
+	case next := <-m.done:
+		m.running = false
+		next()
+		m.refresh()
+	default:
+	}
+}
+
+// Wait attend la fin du travail de fond en cours (coup de l'IA ou du
+// joueur distant, changement de la partie suivie) et applique sa suite,
+// puis celle de l'analyse en cours et celle de la partie passée en revue,
+// sans faire avancer l'horloge. Elle rend les tests déterministes.
+func (m *Machine) Wait() {
+	if m.running {
+		next := <-m.done
+		m.running = false
+		next()
+		m.refresh()
+	}
+	if m.analyzing {
+		m.applyAnalysis(<-m.analysisDone)
+	}
+	if m.reviewDone != nil {
+		for r := range m.reviewDone {
+			m.reviews = append(m.reviews, r)
+		}
+		m.reviewDone = nil
+	}
+}
+
+// collectAnalysis conserve le résultat de l'analyse en cours si elle est
+// terminée.
+func (m *Machine) collectAnalysis() {
+	select {
+	case a := <-m.analysisDone:
+		m.applyAnalysis(a)
+	default:
+	}
+}
+
+// applyAnalysis conserve le résultat a de l'analyse en cours, sauf si
+// elle a été interrompue.
+func (m *Machine) applyAnalysis(a analysis) {
+	m.analyzing = false
+	if a.done {
+		m.analysis = a
+	}
+}
+
+// canAnalyze indique si les coups de la partie peuvent être analysés :
+// pendant le tour du joueur local (de l'un ou l'autre joueur d'une partie
+// locale), hors des parties en réseau.
+func (m *Machine) canAnalyze() bool {
+	return m.gm != nil && !m.running && m.remote == nil && m.watcher == nil &&
+		(m.state == YourTurn || (m.state == OpponentTurn && !m.gm.IsAI()))
+}
+
+// updateAnalysis lance l'analyse de la position si le coup conseillé ou
+// la valeur des coups sont demandés, et interrompt celle d'une position
+// qui n'est plus d'actualité ; l'analyse suivante est lancée à son retour.
+// Le coup conseillé n'est demandé que pour la position où H a été tapée.
+func (m *Machine) updateAnalysis() {
+	if !m.canAnalyze() {
+		if m.analyzing {
+			m.stopAnalysis()
+		}
+		return
+	}
+	key := m.gm.PositionKey()
+	if m.hint && m.hintKey != key {
+		m.hint = false
+	}
+	switch {
+	case !m.hint && !m.showAnalysis, m.analysis.key == key && m.analysis.done:
+	case m.analyzing:
+		if m.analyzingKey != key {
+			m.stopAnalysis()
+		}
+	default:
+		m.startAnalysis(key)
+	}
+}
+
+// startAnalysis analyse en arrière-plan les coups du joueur au trait dans
+// la position de clé key.
+func (m *Machine) startAnalysis(key uint64) {
+	if m.analyzer == nil {
+		m.analyzer = game.NewAnalyzer(analysisDepth)
+		m.analyzer.SolveTime = analysisSolveTime
+	}
+	player := game.PlayerOneColor
+	if len(m.gm.History())%2 == 1 {
+		player = game.PlayerTwoColor
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.analyzing, m.analyzingKey, m.stopAnalysis = true, key, cancel
+	a, b := m.analyzer, m.gm.Board()
+	go func() {
+		defer cancel()
+		evals, err := a.Analyze(ctx, b, player)
+		if err != nil {
+			log.Printf("analysis: %v", err)
+		}
+		m.analysisDone <- analysis{key: key, evals: evals, done: err == nil && ctx.Err() == nil}
+	}()
+}
+
+// Busy indique si un travail de fond est en cours.
+func (m *Machine) Busy() bool {
+	return m.running
+}
+
+// refresh copie l'état de la partie lu par les accesseurs, sauf pendant
+// un travail de fond.
+func (m *Machine) refresh() {
+	if m.running {
+		return
+	}
+	m.view = view{}
+	if m.gm == nil {
+		return
+	}
+	width, height := m.Dims()
+	m.view.width, m.view.height = width, height
+	m.view.variant = m.gm.Variant()
+	m.view.ai = m.played().IsAI()
+	m.view.cells = make([][]string, height)
+	for i := range m.view.cells {
+		m.view.cells[i] = make([]string, width)
+		for j := range m.view.cells[i] {
+			m.view.cells[i][j] = m.gm.GetHoleColor(i, j)
+		}
+	}
+	m.view.won, m.view.lost = m.played().GetWonGames(), m.played().GetLostGames()
+	m.view.line, m.view.rows, m.view.cols = m.gm.WhereConnected()
+	m.view.winner, m.view.timedOut = m.gm.Winner(), m.gm.TimedOut()
+	m.view.key = m.gm.PositionKey()
+	if m.remote != nil {
+		m.view.peer = m.remote.PeerName()
+		m.view.peerToMove = !m.remote.MyTurn()
+	}
+	if m.watcher != nil {
+		m.view.names = m.watcher.Names()
+	}
+}
+
+// resetBalls place toutes les billes au-dessus du plateau, sans vitesse,
+// en dimensionnant les tableaux selon le plateau en cours.
+func (m *Machine) resetBalls() {
+	m.popped = nil
+	width, height := m.Dims()
+	m.ballY = make([][]float64, width)
+	m.ballSpeed = make([][]float64, width)
+	for i := range m.ballY {
+		m.ballY[i] = make([]float64, height)
+		m.ballSpeed[i] = make([]float64, height)
+		for j := range m.ballY[i] {
+			m.ballY[i][j] = -TileSize
+		}
+	}
+}
+
+// startPopAnimation anime le retrait du jeton du bas de la colonne qui
+// vient d'être joué : le jeton retiré tombe sous le plateau et ceux du
+// dessus descendent d'une case depuis leur ancienne position.
+func (m *Machine) startPopAnimation(column int) {
+	history := m.gm.History()
+	_, height := m.Dims()
+	m.popped = &poppedBall{column: column, player: history[len(history)-1].Player, y: float64(height-1) * TileSize}
+	for i := 0; i < height; i++ {
+		m.ballSpeed[column][i] = 0
+		if hole := m.gm.GetHoleColor(i, column); hole == game.PlayerOneColor || hole == game.PlayerTwoColor {
+			m.ballY[column][i] = float64(i-1) * TileSize
+		}
+	}
+}
+
+// animateThen passe à l'état d'animation state pendant animationFrames
+// étapes, puis appelle then. La pendule est arrêtée pendant l'animation.
+func (m *Machine) animateThen(state State, then func()) {
+	m.state = state
+	m.wait = animationFrames
+	m.then = then
+	if m.clock != nil {
+		m.clock.Pause()
+	}
+}
+
+// animating indique si des billes sont en mouvement.
+func (m *Machine) animating() bool {
+	return m.state == Animation || m.state == OpponentAnimation
+}
+
+// animate fait tomber les billes d'une étape et termine l'animation en
+// cours quand elle a duré animationFrames étapes.
+func (m *Machine) animate() {
+	for row, cells := range m.view.cells {
+		for col, hole := range cells {
+			if hole != game.PlayerOneColor && hole != game.PlayerTwoColor {
+				continue
+			}
+			destY := float64(row) * TileSize
+			fallY, fallSpeed := &m.ballY[col][row], &m.ballSpeed[col][row]
+			*fallY += *fallSpeed
+			*fallSpeed += gravity
+			if *fallY > destY {
+				*fallY = destY
+				*fallSpeed = 0
+			}
+		}
+	}
+	if m.popped != nil {
+		m.popped.y += m.popped.speed
+		m.popped.speed += gravity
+		// le jeton disparaît deux cases sous le plateau, hors de l'écran
+		if _, height := m.Dims(); m.popped.y > float64(height+2)*TileSize {
+			m.popped = nil
+		}
+	}
+	if m.then != nil {
+		if m.wait--; m.wait <= 0 {
+			then := m.then
+			m.then = nil
+			if m.clock != nil {
+				m.clock.Resume()
+			}
+			then()
+		}
+	}
+}
+
+// State renvoie l'état de l'interface.
+func (m *Machine) State() State {
+	return m.state
+}
+
+// GameOver indique si la partie affichée est terminée.
+func (m *Machine) GameOver() bool {
+	return m.state == Tie || m.state == Win || m.state == Lose
+}
+
+// Dims renvoie les dimensions du plateau de la partie en cours, ou celles
+// choisies dans le menu s'il n'y a pas de partie.
+func (m *Machine) Dims() (width, height int) {
+	switch {
+	case m.gm == nil:
+		preset := BoardPresets[m.boardPreset]
+		return preset[0], preset[1]
+	case m.running:
+		return m.view.width, m.view.height
+	}
+	width, height, _ = m.gm.BoardSize()
+	return width, height
+}
+
+// Preset renvoie les dimensions choisies dans le menu (largeur, hauteur,
+// jetons à aligner).
+func (m *Machine) Preset() [3]int {
+	return BoardPresets[m.boardPreset]
+}
+
+// Variant renvoie les règles de la partie en cours, ou celles choisies
+// dans le menu s'il n'y a pas de partie.
+func (m *Machine) Variant() game.Variant {
+	if m.gm == nil {
+		return m.variant
+	}
+	return m.view.variant
+}
+
+// InGame indique si une partie est en cours (ou suivie en spectateur).
+func (m *Machine) InGame() bool {
+	return m.gm != nil
+}
+
+// Cell renvoie le contenu de la case (rangée row, colonne col).
+func (m *Machine) Cell(row, col int) string {
+	return m.view.cells[row][col]
+}
+
+// BallY renvoie l'ordonnée de la bille de la case (rangée row, colonne
+// col), en pixels depuis le haut du plateau.
+func (m *Machine) BallY(col, row int) float64 {
+	return m.ballY[col][row]
+}
+
+// Popped renvoie le jeton retiré en train de tomber sous le plateau : sa
+// colonne, son joueur et son ordonnée. ok est faux s'il n'y en a pas.
+func (m *Machine) Popped() (column int, player string, y float64, ok bool) {
+	if m.popped == nil {
+		return 0, "", 0, false
+	}
+	return m.popped.column, m.popped.player, m.popped.y, true
+}
+
+// Score renvoie le nombre de parties gagnées et perdues.
+func (m *Machine) Score() (won, lost int) {
+	return m.view.won, m.view.lost
+}
+
+// WinningLine renvoie les rangées et les colonnes des jetons alignés. ok
+// est faux si aucun alignement n'est complet.
+func (m *Machine) WinningLine() (rows, cols []int, ok bool) {
+	return m.view.rows, m.view.cols, m.view.line
+}
+
+// Ghost renvoie la colonne jouée par l'IA ou par le joueur distant, et
+// indique si elle doit être montrée (pendant l'animation de son coup).
+func (m *Machine) Ghost() (column int, ok bool) {
+	return m.opponentLastCol, m.state == OpponentAnimation && (m.view.ai || m.remote != nil)
+}
+
+// Remote indique si la partie en cours se joue en réseau, et renvoie le
+// nom du joueur distant.
+func (m *Machine) Remote() (peer string, ok bool) {
+	if m.remote == nil {
+		return "", false
+	}
+	return m.view.peer, true
+}
+
+// Spectating indique si la partie affichée est suivie en spectateur, et
+// renvoie les noms de ses deux joueurs.
+func (m *Machine) Spectating() (names [2]string, ok bool) {
+	return m.view.names, m.watcher != nil
+}
+
+// TimeLeft renvoie le temps restant au joueur au trait (voir
+// game.Clock.Left). ok est faux si la partie n'a pas de limite de temps.
+func (m *Machine) TimeLeft() (left time.Duration, ok bool) {
+	if m.clock == nil {
+		return 0, false
+	}
+	return m.clock.Left(m.clock.ToMove()), true
+}
+
+// analysisReady indique si l'analyse terminée est celle de la position
+// affichée, pendant le tour d'un joueur local.
+func (m *Machine) analysisReady() bool {
+	return m.analysis.done && m.analysis.key == m.view.key && !m.running && m.remote == nil && m.watcher == nil &&
+		(m.state == YourTurn || (m.state == OpponentTurn && !m.view.ai))
+}
+
+// Hint renvoie le coup conseillé au joueur au trait après la touche H,
+// une fois l'analyse de la position terminée. ok est faux sinon, et dès
+// que la position change.
+func (m *Machine) Hint() (move game.Move, ok bool) {
+	if !m.hint || m.hintKey != m.view.key || !m.analysisReady() {
+		return game.Move{}, false
+	}
+	best := game.BestEval(m.analysis.evals)
+	if best < 0 {
+		return game.Move{}, false
+	}
+	return m.analysis.evals[best].Move, true
+}
+
+// Analysis renvoie la valeur de chacun des coups du joueur au trait quand
+// son affichage est activé (touche A) et que l'analyse de la position est
+// terminée.
+func (m *Machine) Analysis() (evals []game.MoveEval, ok bool) {
+	if !m.showAnalysis || !m.analysisReady() {
+		return nil, false
+	}
+	return m.analysis.evals, true
+}
+
+// Analyzing indique si le coup conseillé ou la valeur des coups sont en
+// cours de calcul.
+func (m *Machine) Analyzing() bool {
+	return m.analyzing && (m.hint || m.showAnalysis)
+}
+
+// Reviewed renvoie le coup affiché pendant la revue de la partie terminée
+// (touche V). ok est faux hors de la revue.
+func (m *Machine) Reviewed() (move ReviewedMove, ok bool) {
+	if m.state != Review {
+		return ReviewedMove{}, false
+	}
+	history := m.reviewed.History()
+	move = ReviewedMove{Ply: m.reviewPly, Plies: len(history)}
+	if m.reviewPly > 0 {
+		move.Move = history[m.reviewPly-1]
+		if m.reviewPly <= len(m.reviews) {
+			move.Review, move.Analyzed = m.reviews[m.reviewPly-1], true
+		}
+	}
+	return move, true
+}
+
+// ReviewProgress renvoie le nombre de coups analysés de la partie passée
+// en revue et le nombre de coups de la partie.
+func (m *Machine) ReviewProgress() (analyzed, plies int) {
+	if m.state != Review {
+		return 0, 0
+	}
+	return len(m.reviews), len(m.reviewed.History())
+}
+
+// CanReplay indique si un clic sur la zone Again commence une nouvelle
+// partie.
+func (m *Machine) CanReplay() bool {
+	return m.GameOver() && m.remote == nil
+}
+
+// Message renvoie le message affiché sous le plateau pendant une partie.
+func (m *Machine) Message() string {
+	if m.watcher != nil {
+		return m.spectatorMessage()
+	}
+	if m.state == Review {
+		return "Move " + strconv.Itoa(m.reviewPly) + "/" + strconv.Itoa(len(m.reviewed.History()))
+	}
+	if m.remote != nil && !m.GameOver() && m.view.peerToMove {
+		return m.view.peer + " to move"
+	}
+	if m.view.timedOut {
+		if m.state == Win {
+			return "You win on time!"
+		}
+		return "Time is up."
+	}
+	return messages[m.state]
+}
+
+// spectatorMessage renvoie le message affiché au spectateur.
+func (m *Machine) spectatorMessage() string {
+	names := m.view.names
+	switch {
+	case m.watchEnded:
+		return "The game feed ended."
+	case m.state == Tie:
+		return "Tie."
+	case m.GameOver():
+		name := names[1]
+		if m.view.winner == game.PlayerOneColor {
+			name = names[0]
+		}
+		if m.view.timedOut {
+			return name + " wins on time!"
+		}
+		return name + " wins!"
+	case m.state == YourTurn:
+		return names[0] + " to move"
+	case m.state == OpponentTurn:
+		return names[1] + " to move"
+	}
+	return "..."
+}
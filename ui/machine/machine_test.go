@@ -0,0 +1,380 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AbassHammed/c4/game"
+	"github.com/AbassHammed/c4/spectate"
+)
+
+// steps fait avancer m de n étapes sans entrée.
+func steps(m *Machine, n int) {
+	for range n {
+		m.Step(Input{})
+	}
+}
+
+// click joue la colonne col et laisse le coup s'animer.
+func click(t *testing.T, m *Machine, col int) {
+	t.Helper()
+	m.Step(Input{Click: true, Column: col})
+	if m.State() != Animation {
+		t.Fatalf("expected the move in column %d to be animated, got %v", col, m.State())
+	}
+	steps(m, animationFrames)
+}
+
+func TestMenuToWinAndReplay(t *testing.T) {
+	m := New(Config{})
+	if m.State() != Menu || m.InGame() {
+		t.Fatalf("expected the menu, got %v", m.State())
+	}
+	m.Step(Input{Keys: []rune{'o', 'p'}})
+	if m.State() != YourTurn || m.Variant() != game.PopOut {
+		t.Fatalf("expected a local PopOut game, got %v (%v)", m.State(), m.Variant())
+	}
+
+	for i, col := range []int{0, 1, 0, 1, 0, 1} {
+		click(t, m, col)
+		want := []State{OpponentTurn, YourTurn}[i%2]
+		if m.State() != want || m.Message() != messages[want] {
+			t.Fatalf("expected %v after move %d, got %v (%q)", want, i+1, m.State(), m.Message())
+		}
+		if y := m.BallY(col, 5-i/2); y != float64(5-i/2)*TileSize {
+			t.Fatalf("ball of move %d still falling at %v", i+1, y)
+		}
+	}
+	click(t, m, 0)
+	if m.State() != Win || m.Message() != "You win!" || !m.CanReplay() {
+		t.Fatalf("expected a win, got %v (%q)", m.State(), m.Message())
+	}
+	if rows, cols, ok := m.WinningLine(); !ok || len(rows) != 4 || cols[0] != 0 {
+		t.Fatalf("unexpected winning line %v %v", rows, cols)
+	}
+	if m.Now() != time.Duration(7*(animationFrames+1)+1)*time.Second/FPS {
+		t.Fatalf("unexpected virtual time %v", m.Now())
+	}
+
+	// un clic hors de la zone Again ne recommence pas la partie
+	m.Step(Input{Click: true, Column: 3})
+	if m.State() != Win {
+		t.Fatalf("expected the game to stay over, got %v", m.State())
+	}
+	m.Step(Input{Click: true, Again: true})
+	if m.State() != OpponentTurn || m.Cell(5, 0) == game.PlayerOneColor || m.BallY(0, 5) != -TileSize {
+		t.Fatalf("expected a new game with the loser to move, got %v", m.State())
+	}
+	if won, lost := m.Score(); won != 1 || lost != 0 {
+		t.Fatalf("unexpected score %d:%d", won, lost)
+	}
+}
+
+func TestAIGame(t *testing.T) {
+	m := New(Config{})
+	m.Step(Input{Keys: []rune{'a'}})
+	m.Step(Input{Keys: []rune{'1'}})
+	if m.State() != YourTurn {
+		t.Fatalf("expected a game against the AI, got %v", m.State())
+	}
+	// l'IA commence à réfléchir dès la fin de l'animation du coup du joueur
+	click(t, m, 3)
+	if m.State() != Animation || !m.Busy() {
+		t.Fatalf("expected the AI to think in the background, got %v", m.State())
+	}
+	// les accesseurs restent utilisables pendant la réflexion
+	if m.Cell(5, 3) != game.PlayerOneColor {
+		t.Fatalf("expected the player's disc to stay visible")
+	}
+	m.Wait()
+	col, ok := m.Ghost()
+	if m.State() != OpponentAnimation || !ok || m.Cell(5, col) != game.PlayerTwoColor && m.Cell(4, col) != game.PlayerTwoColor {
+		t.Fatalf("expected the AI move to be animated, got %v in column %d", m.State(), col)
+	}
+	steps(m, animationFrames)
+	if m.State() != YourTurn {
+		t.Fatalf("expected the player to move again, got %v", m.State())
+	}
+	if names := m.PlayerNames(); names != [2]string{"Player", "AI"} {
+		t.Fatalf("unexpected names %v", names)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	// sans cadence, le temps n'est pas limité
+	m := New(Config{Start: true})
+	steps(m, 120*FPS)
+	if _, ok := m.TimeLeft(); ok || m.State() != YourTurn {
+		t.Fatalf("expected an unlimited game, got %v", m.State())
+	}
+
+	m = New(Config{Settings: game.Settings{Clock: game.TimeControl{PerMove: 10 * time.Second}}, Start: true})
+	steps(m, 9*FPS)
+	if left, ok := m.TimeLeft(); !ok || left != time.Second {
+		t.Fatalf("expected one second left, got %v", left)
+	}
+	// le temps ne court pas pendant l'animation d'un coup
+	click(t, m, 3)
+	if left, _ := m.TimeLeft(); left != 10*time.Second || m.State() != OpponentTurn {
+		t.Fatalf("expected the second player's clock to start after the animation, got %v", left)
+	}
+	steps(m, 10*FPS)
+	if m.State() != Lose || m.Message() != "Time is up." || !m.CanReplay() {
+		t.Fatalf("expected a loss on time, got %v (%q)", m.State(), m.Message())
+	}
+	if won, lost := m.Score(); won != 0 || lost != 1 {
+		t.Fatalf("unexpected score %d:%d", won, lost)
+	}
+	m.Step(Input{Click: true, Again: true})
+	if left, _ := m.TimeLeft(); m.State() != YourTurn || left != 10*time.Second {
+		t.Fatalf("expected a new game with a reset clock, got %v (%v)", m.State(), left)
+	}
+}
+
+// stallingEngine réfléchit jusqu'à l'annulation de sa recherche.
+type stallingEngine struct{}
+
+func (stallingEngine) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	<-ctx.Done()
+	return game.Move{Column: 0, Player: player}, nil
+}
+
+func TestAITimeout(t *testing.T) {
+	m := New(Config{Settings: game.Settings{AI: true, Opponent: stallingEngine{}, Clock: game.TimeControl{Base: time.Second}}, Start: true})
+	click(t, m, 3)
+	if !m.Busy() {
+		t.Fatalf("expected the AI to think")
+	}
+	// la pendule de l'IA tourne pendant sa réflexion
+	steps(m, FPS)
+	m.Wait()
+	if m.State() != Win || m.Message() != "You win on time!" {
+		t.Fatalf("expected the AI to lose on time, got %v (%q)", m.State(), m.Message())
+	}
+	if won, _ := m.Score(); won != 1 || m.Cell(5, 0) == game.PlayerTwoColor {
+		t.Fatalf("expected the AI's move to be dropped and the game to count as won")
+	}
+}
+
+// failingEngine échoue à chaque recherche, comme un moteur externe arrêté.
+type failingEngine struct{}
+
+func (failingEngine) BestMove(ctx context.Context, b *game.Board, player string) (game.Move, error) {
+	return game.Move{Column: -1}, errors.New("engine exited")
+}
+
+func TestAIEngineFailure(t *testing.T) {
+	m := New(Config{Settings: game.Settings{AI: true, Opponent: failingEngine{}}, Start: true})
+	click(t, m, 3)
+	m.Wait()
+	steps(m, 2)
+	// le joueur ne reçoit pas le trait de l'IA
+	if m.State() != Menu {
+		t.Fatalf("expected a failing AI to end the game, got %v", m.State())
+	}
+}
+
+func TestHintAndAnalysis(t *testing.T) {
+	m := New(Config{})
+	// plateau 8x7 : l'analyse passe par la recherche, sans le solveur
+	m.Step(Input{Keys: []rune{'g', 'p'}})
+	for _, col := range []int{0, 1, 0, 1, 0} {
+		click(t, m, col)
+	}
+	if _, ok := m.Hint(); ok {
+		t.Fatalf("expected no hint before H")
+	}
+	m.Step(Input{Keys: []rune{'h'}})
+	if !m.Analyzing() {
+		t.Fatalf("expected the hint to be computed in the background")
+	}
+	m.Wait()
+	if move, ok := m.Hint(); !ok || move.Column != 0 || move.Kind != game.MoveDrop || move.Player != game.PlayerTwoColor {
+		t.Fatalf("expected the second player to be told to block column 1, got %+v", move)
+	}
+	if _, ok := m.Analysis(); ok {
+		t.Fatalf("expected the analysis to stay hidden before A")
+	}
+
+	// la position est déjà analysée : A l'affiche aussitôt
+	m.Step(Input{Keys: []rune{'a'}})
+	evals, ok := m.Analysis()
+	if !ok || len(evals) != 8 || evals[1].String() != "loss in 2" {
+		t.Fatalf("unexpected analysis %v", evals)
+	}
+
+	click(t, m, 0)
+	if _, ok := m.Hint(); ok {
+		t.Fatalf("expected the hint to disappear once played")
+	}
+	m.Wait()
+	if evals, ok := m.Analysis(); !ok || evals[0].Move.Player != game.PlayerOneColor {
+		t.Fatalf("expected the new position to be analyzed, got %v", evals)
+	}
+	m.Step(Input{Keys: []rune{'a'}})
+	if _, ok := m.Analysis(); ok {
+		t.Fatalf("expected A to hide the analysis")
+	}
+}
+
+func TestReview(t *testing.T) {
+	m := New(Config{})
+	m.Step(Input{Keys: []rune{'g', 'p'}})
+	// le second joueur ne bloque pas la colonne 1 au sixième coup
+	for _, col := range []int{0, 1, 0, 1, 0, 2, 0} {
+		click(t, m, col)
+	}
+	if m.State() != Win {
+		t.Fatalf("expected a win, got %v", m.State())
+	}
+	if _, ok := m.Reviewed(); ok {
+		t.Fatalf("expected no review before V")
+	}
+
+	m.Step(Input{Keys: []rune{'v'}})
+	m.Wait()
+	r, ok := m.Reviewed()
+	if !ok || m.State() != Review || r.Ply != 7 || r.Plies != 7 || m.Message() != "Move 7/7" || m.Cell(3, 0) != game.PlayerOneColor {
+		t.Fatalf("expected the review of the last move, got %+v (%v, %q)", r, m.State(), m.Message())
+	}
+	if analyzed, plies := m.ReviewProgress(); analyzed != 7 || plies != 7 || !r.Analyzed || r.Review.Played.String() != "win in 1" {
+		t.Fatalf("expected the 7 moves to be analyzed, got %d/%d %+v", analyzed, plies, r.Review)
+	}
+
+	m.Step(Input{Back: true})
+	r, _ = m.Reviewed()
+	if r.Ply != 6 || r.Move.Column != 2 || !r.Review.Blunder || r.Review.Best.Move.Column != 0 || m.Cell(3, 0) == game.PlayerOneColor {
+		t.Fatalf("expected the sixth move to be a blunder, got %+v", r)
+	}
+	if _, _, ok := m.WinningLine(); ok {
+		t.Fatalf("expected no winning line before the last move")
+	}
+	for range 8 {
+		m.Step(Input{Keys: []rune{'b'}})
+	}
+	if r, _ = m.Reviewed(); r.Ply != 0 || m.Cell(6, 0) == game.PlayerOneColor {
+		t.Fatalf("expected the empty board, got %+v", r)
+	}
+	m.Step(Input{Forward: true, Keys: []rune{'n'}})
+	if r, _ = m.Reviewed(); r.Ply != 2 || r.Move.Column != 1 || r.Review.Blunder {
+		t.Fatalf("expected the second move, got %+v", r)
+	}
+
+	// V ramène à la fin de partie, la partie jouée intacte
+	m.Step(Input{Keys: []rune{'v'}})
+	if m.State() != Win || !m.CanReplay() || m.Cell(3, 0) != game.PlayerOneColor || m.BallY(0, 3) != 3*TileSize {
+		t.Fatalf("expected the end of the game, got %v", m.State())
+	}
+	if won, lost := m.Score(); won != 1 || lost != 0 {
+		t.Fatalf("unexpected score %d:%d", won, lost)
+	}
+	m.Step(Input{Keys: []rune{'v'}})
+	if analyzed, _ := m.ReviewProgress(); analyzed != 7 || m.reviewDone != nil {
+		t.Fatalf("expected the analysis to be kept, got %d moves", analyzed)
+	}
+}
+
+// TestSyncWithHistory vérifie le tour affiché et la position des billes
+// après une annulation puis un coup rejoué.
+func TestSyncWithHistory(t *testing.T) {
+	m := New(Config{Start: true})
+	click(t, m, 3)
+	click(t, m, 3)
+
+	m.Step(Input{Keys: []rune{'u'}})
+	if m.State() != OpponentTurn {
+		t.Fatalf("expected OpponentTurn after undoing the second move, got %v", m.State())
+	}
+	if m.BallY(3, 4) != -TileSize || m.BallY(3, 5) != 5*TileSize {
+		t.Fatalf("unexpected ball positions: %v", m.ballY[3])
+	}
+
+	m.Step(Input{Keys: []rune{'r'}})
+	if m.State() != YourTurn || m.BallY(3, 4) != 4*TileSize {
+		t.Fatalf("expected YourTurn with the ball back in place, got %v (%v)", m.State(), m.ballY[3])
+	}
+}
+
+// TestSaveAndLoadGame vérifie que la partie sauvegardée par la touche S est
+// reprise par la touche L avec le bon tour.
+func TestSaveAndLoadGame(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	m := New(Config{Start: true})
+	click(t, m, 3)
+	m.Step(Input{Keys: []rune{'s'}})
+
+	m = New(Config{})
+	m.Step(Input{Keys: []rune{'l'}})
+	if m.gm.MoveString() != "4" || m.State() != OpponentTurn || m.Cell(5, 3) != game.PlayerOneColor {
+		t.Fatalf("expected the saved game with the second player to move, got %q (%v)", m.gm.MoveString(), m.State())
+	}
+}
+
+// TestPopAnimation vérifie que le jeton retiré tombe sous le plateau et
+// que ceux du dessus descendent d'une case.
+func TestPopAnimation(t *testing.T) {
+	m := New(Config{})
+	m.Step(Input{Keys: []rune{'o', 'p'}})
+	click(t, m, 2)
+	click(t, m, 2)
+	m.Step(Input{PopClick: true, Column: 2})
+	if column, player, _, ok := m.Popped(); !ok || column != 2 || player != game.PlayerOneColor || m.BallY(2, 5) >= 5*TileSize {
+		t.Fatalf("unexpected animation start: popped=%v balls=%v", ok, m.ballY[2])
+	}
+	steps(m, 2*FPS)
+	if _, _, _, ok := m.Popped(); ok || m.BallY(2, 5) != 5*TileSize {
+		t.Fatalf("animation did not end: popped=%v balls=%v", ok, m.ballY[2])
+	}
+	if m.State() != OpponentTurn {
+		t.Fatalf("expected the second player to move after the pop, got %v", m.State())
+	}
+}
+
+// TestSpectator vérifie les noms diffusés aux spectateurs et l'affichage
+// de la partie suivie, dont les entrées sont ignorées.
+func TestSpectator(t *testing.T) {
+	feed, err := spectate.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer feed.Close()
+	m := New(Config{Settings: game.Settings{AI: true, Difficulty: 1}, Start: true, AIFirst: true, Name: "alice", Feed: feed})
+	if names := m.PlayerNames(); names != [2]string{"AI", "alice"} {
+		t.Fatalf("unexpected names %v", names)
+	}
+	for _, col := range []int{0, 1, 0, 1, 0, 1, 0} {
+		m.gm.MakePlayerTurn(col)
+	}
+	feed.Publish(m.gm, m.PlayerNames())
+
+	w, err := spectate.Watch(context.Background(), feed.Addr().String())
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer w.Close()
+	s := New(Config{Watch: w})
+	if s.State() != Win || s.Message() != "AI wins!" {
+		t.Fatalf("unexpected spectator view %v %q", s.State(), s.Message())
+	}
+	if s.Step(Input{Click: true, Again: true, Keys: []rune{'u'}}); s.State() != Win {
+		t.Fatalf("expected Step to ignore the spectator's input")
+	}
+	if names, ok := s.Spectating(); !ok || names != [2]string{"AI", "alice"} {
+		t.Fatalf("unexpected spectated names %v", names)
+	}
+
+	// la partie recommence : le spectateur la suit
+	m.gm.ResetGame()
+	m.gm.MakePlayerTurn(3)
+	feed.Publish(m.gm, m.PlayerNames())
+	for s.Cell(5, 3) != game.PlayerOneColor || s.State() != OpponentTurn {
+		s.Wait()
+		steps(s, 1)
+	}
+	if s.Message() != "alice to move" {
+		t.Fatalf("unexpected message %q", s.Message())
+	}
+}